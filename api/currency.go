@@ -0,0 +1,271 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"pricemap-go/database"
+	"pricemap-go/models"
+	"pricemap-go/services"
+	"pricemap-go/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// defaultCurrency is the currency of prices in responses unless currency=
+// asks for another
+const defaultCurrency = utils.BaseCurrency
+
+// ratesRefreshInterval is how long the API keeps exchange rates in memory
+// before reading them from the database again, which picks up imports
+const ratesRefreshInterval = 10 * time.Minute
+
+var (
+	ratesMu       sync.Mutex
+	rates         = utils.NewCurrencyConverter()
+	ratesLoadedAt time.Time
+)
+
+// currencyRates returns the converter of the stored exchange rates. Without a
+// database only the built-in rates are known.
+func currencyRates() *utils.CurrencyConverter {
+	if database.DB == nil {
+		return rates
+	}
+
+	ratesMu.Lock()
+	defer ratesMu.Unlock()
+	if time.Since(ratesLoadedAt) < ratesRefreshInterval {
+		return rates
+	}
+	if loaded, err := services.LoadCurrencyConverter(); err != nil {
+		log.Printf("Error loading exchange rates, keeping the previous ones: %v", err)
+	} else {
+		rates = loaded
+	}
+	ratesLoadedAt = time.Now()
+	return rates
+}
+
+// ExchangeRate describes today's conversion into the currency of a response,
+// reported as current_exchange. It is not the rate prices were converted at:
+// each price is converted at the rates of the day its listing was scraped, or
+// its price observed.
+type ExchangeRate struct {
+	Currency string  `json:"currency"`
+	Base     string  `json:"base"`
+	Rate     float64 `json:"rate"` // units of Currency per unit of Base
+	Date     string  `json:"rate_date"`
+}
+
+// currentExchangeRate returns today's conversion into currency and reports it
+// in the X-Currency, X-Current-Exchange-Rate and X-Current-Exchange-Rate-Date
+// headers, so that object and binary responses carry it too
+func currentExchangeRate(c *gin.Context, currency string) ExchangeRate {
+	rate, date, _ := currencyRates().RateAt(currency, time.Now())
+	exchange := ExchangeRate{
+		Currency: currency,
+		Base:     utils.BaseCurrency,
+		Rate:     rate,
+		Date:     date.Format("2006-01-02"),
+	}
+
+	c.Header("X-Currency", exchange.Currency)
+	c.Header("X-Current-Exchange-Rate", strconv.FormatFloat(exchange.Rate, 'f', -1, 64))
+	c.Header("X-Current-Exchange-Rate-Date", exchange.Date)
+	return exchange
+}
+
+// parseCurrency upper-cases a currency code and checks that it has a rate
+func parseCurrency(value string) (string, error) {
+	currency := strings.ToUpper(strings.TrimSpace(value))
+	if currency == "" {
+		return defaultCurrency, nil
+	}
+	if _, ok := currencyRates().Rate(currency); !ok {
+		return "", fmt.Errorf("must be one of %s", strings.Join(currencyRates().Currencies(), ", "))
+	}
+	return currency, nil
+}
+
+// bindCurrency reads the currency parameter of endpoints without filters. An
+// empty currency means the response, 400 naming the currencies, has been written.
+func bindCurrency(c *gin.Context) string {
+	currency, err := parseCurrency(c.Query("currency"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currency", "fields": FilterErrors{"currency": err.Error()}})
+		return ""
+	}
+	return currency
+}
+
+// priceFactorsKey marks queries that joinPriceFactors has joined
+const priceFactorsKey = "pricemap:price_factors"
+
+// joinPriceFactors joins price_factors, the factors converting the prices of
+// properties into currency. The factors are built from exchange_rates alone,
+// one per currency and day, and hash-joined on the listing's currency and day
+// of scraping rather than looked up once per row and price expression.
+// Listings in a currency without a rate have no price in currency, so they
+// are left out of the queries that filter, sort or aggregate by price.
+// Joining again is a no-op.
+func joinPriceFactors(query *gorm.DB, currency string) *gorm.DB {
+	if _, ok := query.Get(priceFactorsKey); ok {
+		return query
+	}
+	return query.Set(priceFactorsKey, currency).
+		Joins(`LEFT JOIN (` + priceFactorsSQL(currency) + `) AS price_factors
+			ON price_factors.currency = properties.currency AND price_factors.day = ` + rateDaySQL("properties.scraped_at")).
+		Where("price_factors.factor IS NOT NULL")
+}
+
+// rateDaysStartSQL is the first day of the rate table built by
+// priceFactorsSQL: the earliest stored rate, or today when none is stored
+const rateDaysStartSQL = "(SELECT COALESCE(MIN(er.date), CURRENT_DATE) FROM exchange_rates er)"
+
+// rateDaySQL is the day of the rate table whose rates apply at timeExpr. Before
+// the first stored rate every currency has its earliest rate, so earlier days
+// share the table's first day.
+func rateDaySQL(timeExpr string) string {
+	return "LEAST(GREATEST(CAST(" + timeExpr + " AS date), " + rateDaysStartSQL + "), CURRENT_DATE)"
+}
+
+// priceFactorsSQL selects, for every currency and every day from the first
+// stored rate to today, the factor converting that currency into currency.
+// A day's rate is the one CurrencyConverter.RateAt gives: the latest stored
+// rate on or before it, else the earliest stored one, else the built-in rate.
+func priceFactorsSQL(currency string) string {
+	var defaults []string
+	converter := currencyRates()
+	for _, code := range converter.Currencies() {
+		if rate, ok := converter.DefaultRate(code); ok {
+			defaults = append(defaults, fmt.Sprintf("('%s', %s)", code, strconv.FormatFloat(rate, 'g', -1, 64)))
+		}
+	}
+
+	// Codes come from the rate table, never from the request
+	return `WITH periods AS (
+				SELECT er.currency, er.rate,
+					CASE WHEN LAG(er.date) OVER w IS NULL THEN ` + rateDaysStartSQL + ` ELSE er.date END AS valid_from,
+					COALESCE(LEAD(er.date) OVER w - 1, CURRENT_DATE) AS valid_to
+				FROM exchange_rates er
+				WINDOW w AS (PARTITION BY er.currency ORDER BY er.date)
+				UNION ALL
+				SELECT d.currency, d.rate, ` + rateDaysStartSQL + `, CURRENT_DATE
+				FROM (VALUES ` + strings.Join(defaults, ", ") + `) AS d (currency, rate)
+				WHERE NOT EXISTS (SELECT 1 FROM exchange_rates er WHERE er.currency = d.currency)
+			), rates AS (
+				SELECT p.currency, CAST(day AS date) AS day, p.rate
+				FROM periods p, generate_series(p.valid_from, p.valid_to, INTERVAL '1 day') AS day
+			)
+			SELECT r.currency, r.day, t.rate / r.rate AS factor
+			FROM rates r JOIN rates t ON t.day = r.day AND t.currency = '` + currency + `'`
+}
+
+// convertedSQL expresses a price column of properties in the currency of the
+// price factors joined by joinPriceFactors, at the rates of the day the
+// listing was scraped. Listings in a currency without a rate give NULL.
+func convertedSQL(column string) string {
+	return column + " * price_factors.factor"
+}
+
+// priceFactorSQL returns the factor that converts a price in currencyExpr
+// into currency at the rates of dateExpr
+func priceFactorSQL(currencyExpr, dateExpr, currency string) string {
+	// Codes come from the rate table, never from the request
+	return "(" + rateSQL("'"+currency+"'", dateExpr) + " / " + rateSQL(currencyExpr, dateExpr) + ")"
+}
+
+// rateSQL looks up the rate of currencyExpr at dateExpr the way
+// CurrencyConverter.RateAt does: the latest stored rate on or before the
+// date, else the earliest stored one, else the built-in rate
+func rateSQL(currencyExpr, dateExpr string) string {
+	var b strings.Builder
+	b.WriteString("COALESCE(")
+	fmt.Fprintf(&b, "(SELECT er.rate FROM exchange_rates er WHERE er.currency = %s AND er.date <= %s ORDER BY er.date DESC LIMIT 1), ", currencyExpr, dateExpr)
+	fmt.Fprintf(&b, "(SELECT er.rate FROM exchange_rates er WHERE er.currency = %s ORDER BY er.date LIMIT 1), ", currencyExpr)
+	b.WriteString("CASE " + currencyExpr)
+	converter := currencyRates()
+	for _, currency := range converter.Currencies() {
+		if rate, ok := converter.DefaultRate(currency); ok {
+			fmt.Fprintf(&b, " WHEN '%s' THEN %s", currency, strconv.FormatFloat(rate, 'g', -1, 64))
+		}
+	}
+	b.WriteString(" END)")
+	return b.String()
+}
+
+// convertPrice converts a price into currency at the rates of t, rounded to cents
+func convertPrice(price float64, from, currency string, t time.Time) (float64, bool) {
+	converted, err := currencyRates().ConvertAt(price, from, currency, t)
+	if err != nil {
+		return price, false
+	}
+	return math.Round(converted*100) / 100, true
+}
+
+// convertProperty expresses the prices of p in currency at the rates of the
+// day it was scraped, keeping the listing's own price in NativePrice and
+// NativeCurrency. Listings already in currency are left as they are, and
+// so are listings in a currency without a rate, flagged Unconverted.
+func convertProperty(p *models.Property, currency string) {
+	if p.NativeCurrency != "" || p.Currency == currency {
+		return // already in currency
+	}
+	price, ok := convertPrice(p.Price, p.Currency, currency, p.ScrapedAt)
+	if !ok {
+		p.Unconverted = true
+		return
+	}
+
+	p.NativePrice, p.NativeCurrency = p.Price, p.Currency
+	p.Price, p.Currency = price, currency
+	if p.PricePerSqm != nil {
+		perSqm, _ := convertPrice(*p.PricePerSqm, p.NativeCurrency, currency, p.ScrapedAt)
+		p.PricePerSqm = &perSqm
+	}
+}
+
+// convertObservation expresses an observed price in currency at the rates of
+// the day it was observed, keeping the observed one in NativePrice and
+// NativeCurrency
+func convertObservation(o *models.PriceObservation, currency string) {
+	if o.Currency == currency {
+		return
+	}
+	price, ok := convertPrice(o.Price, o.Currency, currency, o.ObservedAt)
+	if !ok {
+		return
+	}
+	o.NativePrice, o.NativeCurrency = o.Price, o.Currency
+	o.Price, o.Currency = price, currency
+}
+
+func convertProperties(properties []models.Property, currency string) {
+	for i := range properties {
+		convertProperty(&properties[i], currency)
+	}
+}
+
+// CurrencyExporter converts every property into currency before passing it
+// on to exporter. The export command shares it with the API.
+func CurrencyExporter(exporter services.PropertyExporter, currency string) services.PropertyExporter {
+	return &currencyExporter{PropertyExporter: exporter, currency: currency}
+}
+
+type currencyExporter struct {
+	services.PropertyExporter
+	currency string
+}
+
+func (e *currencyExporter) Write(p *models.Property) error {
+	convertProperty(p, e.currency)
+	return e.PropertyExporter.Write(p)
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"pricemap-go/models"
+	"pricemap-go/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestParseCurrency(t *testing.T) {
+	currency, err := parseCurrency(" eur ")
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", currency)
+
+	currency, err = parseCurrency("")
+	require.NoError(t, err)
+	assert.Equal(t, "USD", currency)
+
+	_, err = parseCurrency("XYZ")
+	assert.ErrorContains(t, err, "must be one of ")
+}
+
+func TestRateSQL(t *testing.T) {
+	sql := rateSQL("properties.currency", "properties.scraped_at")
+	assert.Contains(t, sql, "(SELECT er.rate FROM exchange_rates er WHERE er.currency = properties.currency "+
+		"AND er.date <= properties.scraped_at ORDER BY er.date DESC LIMIT 1)")
+	assert.Contains(t, sql, "(SELECT er.rate FROM exchange_rates er WHERE er.currency = properties.currency ORDER BY er.date LIMIT 1)")
+
+	// Currencies without published rates fall back to the built-in ones
+	assert.Contains(t, sql, "CASE properties.currency WHEN 'AED' THEN 3.67 ")
+	for _, currency := range currencyRates().Currencies() {
+		assert.Contains(t, sql, " WHEN '"+currency+"' THEN ")
+	}
+
+	assert.Equal(t, "("+rateSQL("'EUR'", "c.day")+" / "+rateSQL("c.currency", "c.day")+")", priceFactorSQL("c.currency", "c.day", "EUR"))
+}
+
+func TestJoinPriceFactors(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	require.NoError(t, err)
+
+	// Joined once however many price expressions need it
+	query := joinPriceFactors(db.Model(&models.Property{}), "EUR")
+	query = joinPriceFactors(query, "EUR")
+	sql := query.Select("AVG(" + convertedSQL("properties.price") + ")").Find(&[]struct{}{}).Statement.SQL.String()
+
+	assert.Equal(t, 1, strings.Count(sql, "AS price_factors"))
+	assert.Contains(t, sql, "LEFT JOIN ("+priceFactorsSQL("EUR")+") AS price_factors")
+	assert.Contains(t, sql, "ON price_factors.currency = properties.currency AND price_factors.day = "+
+		"LEAST(GREATEST(CAST(properties.scraped_at AS date), "+rateDaysStartSQL+"), CURRENT_DATE)")
+	assert.Contains(t, sql, "AVG(properties.price * price_factors.factor)")
+
+	// The factors come from the rate table alone, not from a scan of properties
+	assert.NotContains(t, sql, "FROM properties")
+}
+
+func TestPriceFactorsSQL(t *testing.T) {
+	sql := priceFactorsSQL("EUR")
+
+	// One rate per currency and day: the latest stored one, the earliest
+	// stored one before it, else the built-in one
+	assert.Contains(t, sql, "CASE WHEN LAG(er.date) OVER w IS NULL THEN "+rateDaysStartSQL+" ELSE er.date END AS valid_from")
+	assert.Contains(t, sql, "COALESCE(LEAD(er.date) OVER w - 1, CURRENT_DATE) AS valid_to")
+	assert.Contains(t, sql, "WINDOW w AS (PARTITION BY er.currency ORDER BY er.date)")
+	assert.Contains(t, sql, "('AED', 3.67)")
+	assert.Contains(t, sql, "WHERE NOT EXISTS (SELECT 1 FROM exchange_rates er WHERE er.currency = d.currency)")
+	assert.Contains(t, sql, "generate_series(p.valid_from, p.valid_to, INTERVAL '1 day')")
+	assert.Contains(t, sql, "SELECT r.currency, r.day, t.rate / r.rate AS factor")
+	assert.Contains(t, sql, "JOIN rates t ON t.day = r.day AND t.currency = 'EUR'")
+}
+
+func TestConvertProperty(t *testing.T) {
+	perSqm := 2000.0
+	p := &models.Property{Price: 100000, Currency: "EUR", PricePerSqm: &perSqm, ScrapedAt: time.Now()}
+	convertProperty(p, "USD")
+
+	rate, _ := currencyRates().Rate("EUR")
+	assert.Equal(t, "USD", p.Currency)
+	assert.InDelta(t, 100000/rate, p.Price, 0.01)
+	assert.InDelta(t, 2000/rate, *p.PricePerSqm, 0.01)
+	assert.Equal(t, 100000.0, p.NativePrice)
+	assert.Equal(t, "EUR", p.NativeCurrency)
+
+	// Converting twice keeps the listing's own price
+	convertProperty(p, "GBP")
+	assert.Equal(t, "USD", p.Currency)
+	assert.Equal(t, "EUR", p.NativeCurrency)
+
+	// Listings already in the currency are left alone
+	same := &models.Property{Price: 500, Currency: "USD"}
+	convertProperty(same, "USD")
+	assert.Equal(t, 500.0, same.Price)
+	assert.Empty(t, same.NativeCurrency)
+
+	// Listings in a currency without a rate keep their price, flagged
+	unknown := &models.Property{Price: 700, Currency: "XYZ"}
+	convertProperty(unknown, "EUR")
+	assert.Equal(t, 700.0, unknown.Price)
+	assert.Equal(t, "XYZ", unknown.Currency)
+	assert.True(t, unknown.Unconverted)
+	assert.False(t, p.Unconverted)
+}
+
+func TestCurrencyExporter(t *testing.T) {
+	columns, err := services.ParseExportColumns("id,price,currency,native_price,native_currency")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	exporter, err := services.NewPropertyExporter(&buf, services.ExportCSV, columns)
+	require.NoError(t, err)
+	exporter = CurrencyExporter(exporter, "EUR")
+	require.NoError(t, exporter.Write(&models.Property{ID: 1, Price: 100000, Currency: "EUR"}))
+	require.NoError(t, exporter.Write(&models.Property{ID: 2, Price: 250, Currency: "USD"}))
+	require.NoError(t, exporter.Close())
+
+	rate, _ := currencyRates().Rate("EUR")
+	price, _ := convertPrice(250, "USD", "EUR", time.Time{})
+	assert.InDelta(t, 250*rate, price, 0.01)
+	assert.Equal(t, "id,price,currency,native_price,native_currency\n"+
+		"1,100000,EUR,100000,EUR\n"+
+		"2,"+strconv.FormatFloat(price, 'f', -1, 64)+",EUR,250,USD\n", buf.String())
+}
+
+func TestHandler_InvalidCurrency(t *testing.T) {
+	router := setupTestRouter()
+
+	for _, url := range []string{
+		"/api/v1/properties?currency=XYZ",
+		"/api/v1/properties/1?currency=XYZ",
+		"/api/v1/properties/1/history?currency=XYZ",
+	} {
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+		assert.Contains(t, w.Body.String(), `"currency":"must be one of `, url)
+	}
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"net/http"
+
+	"pricemap-go/database"
+	"pricemap-go/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// uniqueListings restricts a property query to one listing per canonical
+// property, so duplicates across sources are counted once. The listing is
+// the lowest ID among those matching the query, so a property stays in the
+// results as long as any of its listings matches.
+func uniqueListings(query *gorm.DB) *gorm.DB {
+	representatives := query.Session(&gorm.Session{}).
+		Select("DISTINCT ON (COALESCE(properties.canonical_id, -properties.id)) properties.id").
+		Order("COALESCE(properties.canonical_id, -properties.id), properties.id")
+	return query.Where("properties.id IN (?)", representatives)
+}
+
+// GetPropertyDuplicates returns listings from other sources that describe the same property
+func (h *Handler) GetPropertyDuplicates(c *gin.Context) {
+	id := c.Param("id")
+	currency := bindCurrency(c)
+	if currency == "" {
+		return
+	}
+
+	var property models.Property
+	if err := database.DB.First(&property, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Property not found"})
+		return
+	}
+
+	duplicates := []models.Property{}
+	if property.CanonicalID != nil {
+		if err := database.DB.Where("canonical_id = ? AND id <> ?", *property.CanonicalID, property.ID).
+			Order("match_confidence DESC").
+			Find(&duplicates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	convertProperties(duplicates, currency)
+
+	c.JSON(http.StatusOK, gin.H{
+		"property_id":      property.ID,
+		"canonical_id":     property.CanonicalID,
+		"match_confidence": property.MatchConfidence,
+		"data":             duplicates,
+		"count":            len(duplicates),
+		"current_exchange": currentExchangeRate(c, currency),
+	})
+}
+
+// GetCanonicalProperty returns a canonical property with all of its listings
+func (h *Handler) GetCanonicalProperty(c *gin.Context) {
+	id := c.Param("id")
+	currency := bindCurrency(c)
+	if currency == "" {
+		return
+	}
+
+	var canonical models.CanonicalProperty
+	if err := database.DB.Preload("Members", func(db *gorm.DB) *gorm.DB {
+		return db.Order("match_confidence DESC")
+	}).First(&canonical, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Canonical property not found"})
+		return
+	}
+
+	convertProperties(canonical.Members, currency)
+	c.JSON(http.StatusOK, struct {
+		models.CanonicalProperty
+		CurrentExchange ExchangeRate `json:"current_exchange"`
+	}{canonical, currentExchangeRate(c, currency)})
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"pricemap-go/config"
+	"pricemap-go/database"
+	"pricemap-go/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestUniqueListings(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	require.NoError(t, err)
+
+	values, _ := url.ParseQuery("city=Moscow&rooms_min=2")
+	filter, err := ParsePropertyFilter(values)
+	require.NoError(t, err)
+
+	query := filter.Apply(db.Model(&models.Property{}).Where("is_active = ?", true))
+	stmt := uniqueListings(query).Select("COUNT(*)").Find(&[]struct{}{}).Statement
+	sql := stmt.SQL.String()
+
+	// The representative of each canonical property is picked among the
+	// listings that match the filter
+	assert.Contains(t, sql, "properties.id IN (SELECT DISTINCT ON (COALESCE(properties.canonical_id, -properties.id)) properties.id "+
+		"FROM \"properties\" WHERE is_active = $4 AND properties.city = $5 AND properties.rooms >= $6")
+	assert.Contains(t, sql, "ORDER BY COALESCE(properties.canonical_id, -properties.id), properties.id)")
+	assert.Equal(t, 2, strings.Count(sql, "properties.city = "))
+	assert.Equal(t, []interface{}{true, "Moscow", 2, true, "Moscow", 2}, stmt.Vars)
+}
+
+func TestAPI_Integration_UniqueListings(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	config.Load()
+	if err := database.Connect(); err != nil {
+		t.Skipf("no database: %v", err)
+	}
+	require.NoError(t, database.Migrate())
+
+	// Two listings of one flat; only the one with the higher ID is cheap
+	city := "Dedup Integration City"
+	canonical := models.CanonicalProperty{City: city, MemberCount: 2}
+	require.NoError(t, database.DB.Create(&canonical).Error)
+	listings := []models.Property{
+		{Source: "test", ExternalID: "dedup-1", City: city, Price: 900000, Currency: "USD", IsActive: true, CanonicalID: &canonical.ID},
+		{Source: "test", ExternalID: "dedup-2", City: city, Price: 100000, Currency: "USD", IsActive: true, CanonicalID: &canonical.ID},
+	}
+	require.NoError(t, database.DB.Create(&listings).Error)
+	t.Cleanup(func() {
+		database.DB.Unscoped().Where("city = ?", city).Delete(&models.Property{})
+		database.DB.Delete(&canonical)
+	})
+
+	count := func(query string) int64 {
+		values, _ := url.ParseQuery(query)
+		filter, err := ParsePropertyFilter(values)
+		require.NoError(t, err)
+		var n int64
+		require.NoError(t, uniqueListings(PropertiesQuery(filter)).Count(&n).Error)
+		return n
+	}
+
+	assert.Equal(t, int64(1), count("city="+url.QueryEscape(city)))
+	assert.Equal(t, int64(1), count("city="+url.QueryEscape(city)+"&price_max=200000"))
+	assert.Equal(t, int64(0), count("city="+url.QueryEscape(city)+"&price_max=50000"))
+}
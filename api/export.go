@@ -0,0 +1,62 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"pricemap-go/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportProperties streams every property matching the filters of /properties
+// as CSV (default), NDJSON or Parquet, prices in the currency of the filter.
+// columns selects and orders the columns. The filters may also be POSTed as a
+// JSON body.
+func (h *Handler) ExportProperties(c *gin.Context) {
+	filter := bindPropertyFilter(c)
+	if filter == nil {
+		return
+	}
+
+	format := c.DefaultQuery("format", services.ExportCSV)
+
+	columns, err := services.ParseExportColumns(c.Query("columns"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	exporter, err := services.NewPropertyExporter(c.Writer, format, columns)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	exporter = ScoreExporter(CurrencyExporter(exporter, filter.Currency), filter.FactorWeights())
+	currentExchangeRate(c, filter.Currency)
+
+	filename := fmt.Sprintf("properties-%s.%s", time.Now().Format("2006-01-02"), format)
+	c.Header("Content-Type", services.ExportContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	exported, err := services.ExportProperties(PropertiesQuery(filter), exporter)
+	if err == nil {
+		err = exporter.Close()
+	}
+	if err == nil {
+		return
+	}
+
+	log.Printf("Property export failed after %d rows: %v", exported, err)
+	if c.Writer.Written() {
+		// The status went out with the first rows, so the output is just cut short
+		c.Abort()
+		return
+	}
+	c.Writer.Header().Del("Content-Type")
+	c.Writer.Header().Del("Content-Disposition")
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
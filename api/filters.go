@@ -0,0 +1,392 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"pricemap-go/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxSearchLength bounds the text search, which scans several text columns
+const maxSearchLength = 200
+
+// PropertyFilter is the filter set shared by every endpoint that lists,
+// aggregates or exports properties. Fields use the query parameter names,
+// which are also the keys of a JSON body; nil or empty fields do not filter.
+type PropertyFilter struct {
+	City            string `json:"city,omitempty"`
+	Country         string `json:"country,omitempty"`
+	District        string `json:"district,omitempty"`
+	Source          string `json:"source,omitempty"`
+	ListingCurrency string `json:"listing_currency,omitempty"` // the currency a listing is priced in
+	Type            string `json:"type,omitempty"`
+	DealType        string `json:"deal_type,omitempty"`
+
+	// Currency is the currency of the price filters and of the prices in the
+	// response; listings are converted from their own currency
+	Currency string `json:"currency,omitempty"`
+
+	// Search matches address, district, city and description, case-insensitively
+	Search string `json:"q,omitempty"`
+
+	PriceMin     *float64 `json:"price_min,omitempty"`
+	PriceMax     *float64 `json:"price_max,omitempty"`
+	AreaMin      *float64 `json:"area_min,omitempty"`
+	AreaMax      *float64 `json:"area_max,omitempty"`
+	PpsqmMin     *float64 `json:"ppsqm_min,omitempty"` // price per m²; excludes unknown areas
+	PpsqmMax     *float64 `json:"ppsqm_max,omitempty"`
+	RoomsMin     *int     `json:"rooms_min,omitempty"`
+	RoomsMax     *int     `json:"rooms_max,omitempty"`
+	BedroomsMin  *int     `json:"bedrooms_min,omitempty"`
+	BedroomsMax  *int     `json:"bedrooms_max,omitempty"`
+	BathroomsMin *float64 `json:"bathrooms_min,omitempty"` // half baths
+	BathroomsMax *float64 `json:"bathrooms_max,omitempty"`
+	FloorMin     *int     `json:"floor_min,omitempty"`
+	FloorMax     *int     `json:"floor_max,omitempty"`
+	YearBuiltMin *int     `json:"year_built_min,omitempty"`
+	YearBuiltMax *int     `json:"year_built_max,omitempty"`
+
+	// Scores from PropertyFactors
+	ScoreMin          *float64 `json:"score_min,omitempty"`
+	CrimeScoreMin     *float64 `json:"crime_score_min,omitempty"`
+	TransportScoreMin *float64 `json:"transport_score_min,omitempty"`
+	EducationScoreMin *float64 `json:"education_score_min,omitempty"`
+
+	// Profile names the weighting profile, and Weights lists the
+	// factor:weight pairs, of the overall score that ScoreMin, sorting,
+	// aggregates and responses use; the stored score by default
+	Profile string `json:"profile,omitempty"`
+	Weights string `json:"weights,omitempty"`
+
+	// Bounding box
+	LatMin *float64 `json:"lat_min,omitempty"`
+	LatMax *float64 `json:"lat_max,omitempty"`
+	LngMin *float64 `json:"lng_min,omitempty"`
+	LngMax *float64 `json:"lng_max,omitempty"`
+
+	weights *models.FactorWeights // resolved from Profile or Weights
+}
+
+// FilterErrors maps filter names to what is wrong with their values
+type FilterErrors map[string]string
+
+func (e FilterErrors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field := range e {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		parts[i] = field + ": " + e[field]
+	}
+	return "invalid filters: " + strings.Join(parts, "; ")
+}
+
+// ParsePropertyFilter reads a filter from query parameters. Parameters that
+// are not filters are ignored.
+func ParsePropertyFilter(values url.Values) (*PropertyFilter, error) {
+	p := filterParser{values: values, errs: FilterErrors{}}
+	f := &PropertyFilter{
+		City:            p.text("city"),
+		Country:         p.text("country"),
+		District:        p.text("district"),
+		Source:          p.text("source"),
+		ListingCurrency: p.text("listing_currency"),
+		Type:            p.text("type"),
+		DealType:        p.text("deal_type"),
+		Currency:        p.text("currency"),
+		Search:          p.text("q"),
+
+		PriceMin:     p.float("price_min"),
+		PriceMax:     p.float("price_max"),
+		AreaMin:      p.float("area_min"),
+		AreaMax:      p.float("area_max"),
+		PpsqmMin:     p.float("ppsqm_min"),
+		PpsqmMax:     p.float("ppsqm_max"),
+		RoomsMin:     p.int("rooms_min"),
+		RoomsMax:     p.int("rooms_max"),
+		BedroomsMin:  p.int("bedrooms_min"),
+		BedroomsMax:  p.int("bedrooms_max"),
+		BathroomsMin: p.float("bathrooms_min"),
+		BathroomsMax: p.float("bathrooms_max"),
+		FloorMin:     p.int("floor_min"),
+		FloorMax:     p.int("floor_max"),
+		YearBuiltMin: p.int("year_built_min"),
+		YearBuiltMax: p.int("year_built_max"),
+
+		ScoreMin:          p.float("score_min"),
+		CrimeScoreMin:     p.float("crime_score_min"),
+		TransportScoreMin: p.float("transport_score_min"),
+		EducationScoreMin: p.float("education_score_min"),
+		Profile:           p.text("profile"),
+		Weights:           p.text("weights"),
+
+		LatMin: p.float("lat_min"),
+		LatMax: p.float("lat_max"),
+		LngMin: p.float("lng_min"),
+		LngMax: p.float("lng_max"),
+	}
+
+	f.normalize()
+	f.validate(p.errs)
+	if len(p.errs) > 0 {
+		return nil, p.errs
+	}
+	return f, nil
+}
+
+// DecodePropertyFilter reads a filter from a JSON object with the same keys as
+// the query parameters. Unknown keys are rejected.
+func DecodePropertyFilter(body io.Reader) (*PropertyFilter, error) {
+	f := &PropertyFilter{}
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(f); err != nil && !errors.Is(err, io.EOF) {
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &typeErr) && typeErr.Field != "":
+			return nil, FilterErrors{typeErr.Field: "must be a " + jsonTypeName(typeErr.Type.String())}
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			field, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+			return nil, FilterErrors{field: "is not a filter"}
+		default:
+			return nil, FilterErrors{"body": err.Error()}
+		}
+	}
+
+	errs := FilterErrors{}
+	f.normalize()
+	f.validate(errs)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return f, nil
+}
+
+// bindPropertyFilter reads the filter of a request: a JSON body for POST
+// requests with one, query parameters otherwise. A nil filter means the
+// response, 400 with the invalid fields, has been written.
+func bindPropertyFilter(c *gin.Context) *PropertyFilter {
+	var f *PropertyFilter
+	var err error
+	if c.Request.Method == http.MethodPost && strings.HasPrefix(c.ContentType(), "application/json") {
+		f, err = DecodePropertyFilter(c.Request.Body)
+	} else {
+		f, err = ParsePropertyFilter(c.Request.URL.Query())
+	}
+
+	if err != nil {
+		var fields FilterErrors
+		if errors.As(err, &fields) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid filters", "fields": fields})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return nil
+	}
+	return f
+}
+
+// FactorWeights returns the weights of the overall score, nil for the
+// default ones of the stored score
+func (f *PropertyFilter) FactorWeights() *models.FactorWeights {
+	return f.weights
+}
+
+// HasScoreFilters reports whether Apply refers to property_factors, which the
+// query must then join
+func (f *PropertyFilter) HasScoreFilters() bool {
+	return f.ScoreMin != nil || f.CrimeScoreMin != nil || f.TransportScoreMin != nil || f.EducationScoreMin != nil
+}
+
+// Apply adds the filter conditions to a query on properties
+func (f *PropertyFilter) Apply(query *gorm.DB) *gorm.DB {
+	equal := func(column, value string) {
+		if value != "" {
+			query = query.Where("properties."+column+" = ?", value)
+		}
+	}
+	equal("city", f.City)
+	equal("country", f.Country)
+	equal("district", f.District)
+	equal("source", f.Source)
+	equal("currency", f.ListingCurrency)
+	equal("type", f.Type)
+	equal("deal_type", f.DealType)
+
+	if f.Search != "" {
+		pattern := "%" + escapeLike(f.Search) + "%"
+		query = query.Where(`(properties.address ILIKE @q OR properties.district ILIKE @q
+			OR properties.city ILIKE @q OR properties.description ILIKE @q)`,
+			map[string]interface{}{"q": pattern})
+	}
+
+	// Prices are compared in the requested currency, so listings without a
+	// rate for their currency cannot take part in price filters
+	if f.PriceMin != nil || f.PriceMax != nil || f.PpsqmMin != nil || f.PpsqmMax != nil {
+		query = joinPriceFactors(query, f.Currency)
+	}
+	query = applyRange(query, convertedSQL("properties.price"), f.PriceMin, f.PriceMax)
+	query = applyRange(query, "properties.area", f.AreaMin, f.AreaMax)
+	query = applyRange(query, convertedSQL("properties.price_per_sqm"), f.PpsqmMin, f.PpsqmMax)
+	query = applyRange(query, "properties.rooms", f.RoomsMin, f.RoomsMax)
+	query = applyRange(query, "properties.bedrooms", f.BedroomsMin, f.BedroomsMax)
+	query = applyRange(query, "properties.bathrooms", f.BathroomsMin, f.BathroomsMax)
+	query = applyRange(query, "properties.floor", f.FloorMin, f.FloorMax)
+	query = applyRange(query, "properties.year_built", f.YearBuiltMin, f.YearBuiltMax)
+	query = applyRange(query, "properties.latitude", f.LatMin, f.LatMax)
+	query = applyRange(query, "properties.longitude", f.LngMin, f.LngMax)
+
+	query = applyRange[float64](query, overallScoreSQL(f.weights), f.ScoreMin, nil)
+	query = applyRange[float64](query, "property_factors.crime_score", f.CrimeScoreMin, nil)
+	query = applyRange[float64](query, "property_factors.transport_score", f.TransportScoreMin, nil)
+	query = applyRange[float64](query, "property_factors.education_score", f.EducationScoreMin, nil)
+
+	return query
+}
+
+func applyRange[T int | float64](query *gorm.DB, column string, min, max *T) *gorm.DB {
+	if min != nil {
+		query = query.Where(column+" >= ?", *min)
+	}
+	if max != nil {
+		query = query.Where(column+" <= ?", *max)
+	}
+	return query
+}
+
+// escapeLike makes user input match literally inside a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// normalize trims text filters and upper-cases currency codes, which default
+// to defaultCurrency for prices
+func (f *PropertyFilter) normalize() {
+	for _, field := range []*string{&f.City, &f.Country, &f.District, &f.Source, &f.Type, &f.DealType, &f.Search, &f.Profile, &f.Weights} {
+		*field = strings.TrimSpace(*field)
+	}
+	f.ListingCurrency = strings.ToUpper(strings.TrimSpace(f.ListingCurrency))
+	f.Currency = strings.ToUpper(strings.TrimSpace(f.Currency))
+	if f.Currency == "" {
+		f.Currency = defaultCurrency
+	}
+}
+
+// validate records values that parse but make no sense
+func (f *PropertyFilter) validate(errs FilterErrors) {
+	if len(f.Search) > maxSearchLength {
+		errs["q"] = fmt.Sprintf("must be at most %d characters", maxSearchLength)
+	}
+	if f.ListingCurrency != "" && len(f.ListingCurrency) != 3 {
+		errs["listing_currency"] = "must be a three-letter currency code"
+	}
+	if _, err := parseCurrency(f.Currency); err != nil {
+		errs["currency"] = err.Error()
+	}
+
+	checkRange(errs, "price", f.PriceMin, f.PriceMax, 0, -1)
+	checkRange(errs, "area", f.AreaMin, f.AreaMax, 0, -1)
+	checkRange(errs, "ppsqm", f.PpsqmMin, f.PpsqmMax, 0, -1)
+	checkRange(errs, "rooms", f.RoomsMin, f.RoomsMax, 0, -1)
+	checkRange(errs, "bedrooms", f.BedroomsMin, f.BedroomsMax, 0, -1)
+	checkRange(errs, "bathrooms", f.BathroomsMin, f.BathroomsMax, 0, -1)
+	checkRange(errs, "floor", f.FloorMin, f.FloorMax, -10, -1) // basements
+	checkRange(errs, "year_built", f.YearBuiltMin, f.YearBuiltMax, 1000, 3000)
+	checkRange(errs, "lat", f.LatMin, f.LatMax, -90, 90)
+	checkRange(errs, "lng", f.LngMin, f.LngMax, -180, 180)
+
+	checkRange[float64](errs, "score", f.ScoreMin, nil, 0, 100)
+	checkRange[float64](errs, "crime_score", f.CrimeScoreMin, nil, 0, 100)
+	checkRange[float64](errs, "transport_score", f.TransportScoreMin, nil, 0, 100)
+	checkRange[float64](errs, "education_score", f.EducationScoreMin, nil, 0, 100)
+
+	f.weights = resolveWeights(f.Profile, f.Weights, errs)
+}
+
+// checkRange checks name_min and name_max against the allowed bounds
+// (upper < lower means unbounded) and each other
+func checkRange[T int | float64](errs FilterErrors, name string, min, max *T, lower, upper T) {
+	for _, bound := range []struct {
+		field string
+		value *T
+	}{{name + "_min", min}, {name + "_max", max}} {
+		if bound.value == nil {
+			continue
+		}
+		if *bound.value < lower {
+			errs[bound.field] = fmt.Sprintf("must be at least %v", lower)
+		} else if upper >= lower && *bound.value > upper {
+			errs[bound.field] = fmt.Sprintf("must be at most %v", upper)
+		}
+	}
+
+	if min != nil && max != nil && *min > *max {
+		if _, reported := errs[name+"_max"]; !reported {
+			errs[name+"_max"] = fmt.Sprintf("must not be less than %s_min", name)
+		}
+	}
+}
+
+// filterParser reads typed query parameters, collecting errors by name
+type filterParser struct {
+	values url.Values
+	errs   FilterErrors
+}
+
+func (p filterParser) text(name string) string {
+	return strings.TrimSpace(p.values.Get(name))
+}
+
+func (p filterParser) float(name string) *float64 {
+	raw := strings.TrimSpace(p.values.Get(name))
+	if raw == "" {
+		return nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(value) {
+		p.errs[name] = "must be a number"
+		return nil
+	}
+	return &value
+}
+
+func (p filterParser) int(name string) *int {
+	raw := strings.TrimSpace(p.values.Get(name))
+	if raw == "" {
+		return nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		p.errs[name] = "must be a whole number"
+		return nil
+	}
+	return &value
+}
+
+// jsonTypeName describes a Go type the way the error messages of query
+// parameters do
+func jsonTypeName(goType string) string {
+	switch strings.TrimPrefix(goType, "*") {
+	case "int":
+		return "whole number"
+	case "float64":
+		return "number"
+	default:
+		return "string"
+	}
+}
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestParsePropertyFilter(t *testing.T) {
+	values, _ := url.ParseQuery("city=%20Moscow%20&currency=rub&q=Tverskaya&price_min=100000&price_max=5e6" +
+		"&rooms_min=2&floor_min=-1&year_built_max=2020&score_min=60&lat_min=55.5&lat_max=56&zoom=12&format=csv")
+
+	filter, err := ParsePropertyFilter(values)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Moscow", filter.City)
+	assert.Equal(t, "RUB", filter.Currency)
+	assert.Equal(t, "Tverskaya", filter.Search)
+	assert.Equal(t, 100000.0, *filter.PriceMin)
+	assert.Equal(t, 5e6, *filter.PriceMax)
+	assert.Equal(t, 2, *filter.RoomsMin)
+	assert.Equal(t, -1, *filter.FloorMin)
+	assert.Equal(t, 2020, *filter.YearBuiltMax)
+	assert.Equal(t, 55.5, *filter.LatMin)
+	assert.Nil(t, filter.RoomsMax)
+	assert.True(t, filter.HasScoreFilters())
+}
+
+func TestParsePropertyFilter_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  FilterErrors
+	}{
+		{
+			name:  "malformed numbers",
+			query: "price_min=cheap&rooms_max=2.5&score_min=NaN",
+			want: FilterErrors{
+				"price_min": "must be a number",
+				"rooms_max": "must be a whole number",
+				"score_min": "must be a number",
+			},
+		},
+		{
+			name:  "inverted range",
+			query: "price_min=500000&price_max=100000&year_built_min=2020&year_built_max=1990&ppsqm_min=9000&ppsqm_max=3000",
+			want: FilterErrors{
+				"price_max":      "must not be less than price_min",
+				"year_built_max": "must not be less than year_built_min",
+				"ppsqm_max":      "must not be less than ppsqm_min",
+			},
+		},
+		{
+			name:  "out of bounds",
+			query: "area_min=-5&lat_max=91&crime_score_min=120&listing_currency=rouble",
+			want: FilterErrors{
+				"area_min":         "must be at least 0",
+				"lat_max":          "must be at most 90",
+				"crime_score_min":  "must be at most 100",
+				"listing_currency": "must be a three-letter currency code",
+			},
+		},
+		{
+			name:  "currency without a rate",
+			query: "currency=XYZ",
+			want:  FilterErrors{"currency": "must be one of " + strings.Join(currencyRates().Currencies(), ", ")},
+		},
+		{
+			name:  "search too long",
+			query: "q=" + strings.Repeat("a", maxSearchLength+1),
+			want:  FilterErrors{"q": "must be at most 200 characters"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.query)
+			filter, err := ParsePropertyFilter(values)
+
+			assert.Nil(t, filter)
+			assert.Equal(t, tt.want, err)
+		})
+	}
+}
+
+func TestDecodePropertyFilter(t *testing.T) {
+	filter, err := DecodePropertyFilter(strings.NewReader(`{"city":"Madrid","currency":"eur","price_max":300000,"bedrooms_min":2}`))
+	require.NoError(t, err)
+	assert.Equal(t, "Madrid", filter.City)
+	assert.Equal(t, "EUR", filter.Currency)
+	assert.Equal(t, 300000.0, *filter.PriceMax)
+	assert.Equal(t, 2, *filter.BedroomsMin)
+
+	// An empty body filters nothing
+	filter, err = DecodePropertyFilter(strings.NewReader(""))
+	require.NoError(t, err)
+	assert.Equal(t, &PropertyFilter{Currency: defaultCurrency}, filter)
+
+	tests := []struct {
+		body string
+		want FilterErrors
+	}{
+		{`{"rooms_min":"two"}`, FilterErrors{"rooms_min": "must be a whole number"}},
+		{`{"price_min":"cheap"}`, FilterErrors{"price_min": "must be a number"}},
+		{`{"city":5}`, FilterErrors{"city": "must be a string"}},
+		{`{"bogus":1}`, FilterErrors{"bogus": "is not a filter"}},
+		{`{"price_min":10,"price_max":5}`, FilterErrors{"price_max": "must not be less than price_min"}},
+	}
+	for _, tt := range tests {
+		_, err := DecodePropertyFilter(strings.NewReader(tt.body))
+		assert.Equal(t, tt.want, err, tt.body)
+	}
+
+	_, err = DecodePropertyFilter(strings.NewReader(`{"city":`))
+	assert.Contains(t, err.(FilterErrors), "body")
+}
+
+func TestPropertyFilter_Apply(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	require.NoError(t, err)
+
+	values, _ := url.ParseQuery("city=Moscow&q=50%25_off&currency=EUR&price_max=300000&rooms_min=2&rooms_max=3&score_min=60&crime_score_min=50")
+	filter, err := ParsePropertyFilter(values)
+	require.NoError(t, err)
+
+	stmt := filter.Apply(db.Model(&struct{ ID uint }{}).Table("properties")).Find(&[]struct{ ID uint }{}).Statement
+	sql := stmt.SQL.String()
+
+	assert.Contains(t, sql, "properties.city = $1")
+	assert.Contains(t, sql, "properties.address ILIKE $2 OR properties.district ILIKE $3")
+	// Listings are priced in many currencies; price filters are in the requested
+	// one, leaving out listings without a rate
+	assert.Contains(t, sql, "price_factors.factor IS NOT NULL AND "+convertedSQL("properties.price")+" <= $6 AND properties.rooms >= $7 AND properties.rooms <= $8")
+	// Rates are looked up once per query, in the joined price factors
+	assert.Contains(t, sql, "LEFT JOIN ("+priceFactorsSQL("EUR")+") AS price_factors")
+	assert.NotContains(t, sql, "er.date <=")
+	assert.Contains(t, sql, "property_factors.overall_score >= $9 AND property_factors.crime_score >= $10")
+
+	pattern := `%50\%\_off%`
+	assert.Equal(t, []interface{}{"Moscow", pattern, pattern, pattern, pattern, 300000.0, 2, 3, 60.0, 50.0}, stmt.Vars)
+
+	// Without price filters listings in any currency are kept
+	values, _ = url.ParseQuery("city=Moscow&currency=EUR&rooms_min=2")
+	filter, err = ParsePropertyFilter(values)
+	require.NoError(t, err)
+	sql = filter.Apply(db.Model(&struct{ ID uint }{}).Table("properties")).Find(&[]struct{ ID uint }{}).Statement.SQL.String()
+	assert.NotContains(t, sql, "price_factors")
+	assert.NotContains(t, sql, "properties.currency")
+}
+
+func TestHandler_InvalidFilters(t *testing.T) {
+	router := setupTestRouter()
+
+	for _, url := range []string{
+		"/api/v1/properties?price_min=abc",
+		"/api/v1/heatmap?rooms_min=x",
+		"/api/v1/stats?year_built_min=1",
+		"/api/v1/properties.geojson?lat_min=-100",
+		"/api/v1/properties/export?score_min=200",
+	} {
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+
+		var response struct {
+			Error  string            `json:"error"`
+			Fields map[string]string `json:"fields"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), url)
+		assert.Len(t, response.Fields, 1, url)
+	}
+}
+
+func TestHandler_SearchProperties_JSONBody(t *testing.T) {
+	router := setupTestRouter()
+
+	req, _ := http.NewRequest("POST", "/api/v1/properties/search", bytes.NewBufferString(`{"rooms_min":"many"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"rooms_min":"must be a whole number"`)
+}
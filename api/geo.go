@@ -0,0 +1,303 @@
+package api
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"pricemap-go/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/mvt"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/maptile"
+	"gorm.io/gorm"
+)
+
+const (
+	geoJSONContentType = "application/geo+json"
+	mvtContentType     = "application/vnd.mapbox-vector-tile"
+
+	// A properties.geojson request returns up to maxGeoJSONFeatures properties
+	defaultGeoJSONFeatures = 1000
+	maxGeoJSONFeatures     = 10000
+
+	// Tiles aggregate into cells tileCellZoomOffset levels finer than the tile
+	// zoom, so a tile is roughly 30 cells across
+	tileCellZoomOffset = 2
+
+	// Individual properties are added to tiles from minTilePropertiesZoom on
+	minTilePropertiesZoom = 14
+	maxTileZoom           = 22
+	maxTileProperties     = 2000
+)
+
+// GetPropertiesGeoJSON returns the properties matching the filters of
+// /properties as a GeoJSON FeatureCollection of points
+func (h *Handler) GetPropertiesGeoJSON(c *gin.Context) {
+	filter := bindPropertyFilter(c)
+	if filter == nil {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultGeoJSONFeatures)))
+	if err != nil || limit < 1 {
+		limit = defaultGeoJSONFeatures
+	}
+	if limit > maxGeoJSONFeatures {
+		limit = maxGeoJSONFeatures
+	}
+
+	var properties []models.Property
+	if err := geocoded(PropertiesQuery(filter)).
+		Preload("Factors").
+		Order("properties.id").
+		Limit(limit + 1).
+		Find(&properties).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The extra row only tells whether there are more
+	truncated := len(properties) > limit
+	if truncated {
+		properties = properties[:limit]
+	}
+
+	convertProperties(properties, filter.Currency)
+	weighProperties(properties, filter.FactorWeights())
+
+	fc := geojson.NewFeatureCollection()
+	for i := range properties {
+		fc.Append(propertyFeature(&properties[i]))
+	}
+	fc.ExtraMembers = geojson.Properties{
+		"count":            len(properties),
+		"truncated":        truncated,
+		"current_exchange": currentExchangeRate(c, filter.Currency),
+	}
+
+	writeGeoJSON(c, fc)
+}
+
+// GetHeatmapGeoJSON returns the cells of /heatmap as a GeoJSON FeatureCollection,
+// as square polygons or, with geometry=point, as their center points
+func (h *Handler) GetHeatmapGeoJSON(c *gin.Context) {
+	filter := bindPropertyFilter(c)
+	if filter == nil {
+		return
+	}
+
+	metric := bindHeatmapMetric(c)
+	if metric == "" {
+		return
+	}
+
+	zoom := heatmapZoom(c.Query("zoom"))
+	gridSize := heatmapGridSize(zoom)
+	asPoints := c.Query("geometry") == "point"
+
+	cells, err := queryHeatmapCells(heatmapMetricQuery(heatmapQuery(filter), metric), gridSize, metric, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	fc := geojson.NewFeatureCollection()
+	var total, totalCells int64
+	for _, cell := range cells {
+		fc.Append(cellFeature(cell.PriceHeatmapPoint, gridSize, asPoints))
+		total, totalCells = cell.TotalCount, cell.TotalCells
+	}
+	fc.ExtraMembers = geojson.Properties{
+		"count":            total,
+		"metric":           metric,
+		"zoom":             zoom,
+		"grid_size":        gridSize,
+		"truncated":        totalCells > int64(len(cells)),
+		"current_exchange": currentExchangeRate(c, filter.Currency),
+	}
+
+	writeGeoJSON(c, fc)
+}
+
+// GetTile returns a Mapbox Vector Tile with a "cells" layer of aggregated
+// heatmap cells and, at close zoom, a "properties" layer of single listings.
+// The filters of /heatmap apply to both layers, its metric to the cells.
+func (h *Handler) GetTile(c *gin.Context) {
+	tile, ok := parseTile(c.Param("z"), c.Param("x"), c.Param("y"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tile coordinates"})
+		return
+	}
+	filter := bindPropertyFilter(c)
+	if filter == nil {
+		return
+	}
+	metric := bindHeatmapMetric(c)
+	if metric == "" {
+		return
+	}
+
+	bound := tile.Bound()
+	inTile := func(query *gorm.DB) *gorm.DB {
+		return query.Where("latitude >= ? AND latitude < ?", bound.Min.Lat(), bound.Max.Lat()).
+			Where("longitude >= ? AND longitude < ?", bound.Min.Lon(), bound.Max.Lon())
+	}
+
+	cellZoom := int(tile.Z) + tileCellZoomOffset
+	if cellZoom > heatmapMaxZoom {
+		cellZoom = heatmapMaxZoom
+	}
+	gridSize := heatmapGridSize(cellZoom)
+
+	cells, err := queryHeatmapCells(inTile(heatmapMetricQuery(heatmapQuery(filter), metric)), gridSize, metric, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	cellLayer := geojson.NewFeatureCollection()
+	for _, cell := range cells {
+		cellLayer.Append(cellFeature(cell.PriceHeatmapPoint, gridSize, false))
+	}
+
+	propertyLayer := geojson.NewFeatureCollection()
+	if tile.Z >= minTilePropertiesZoom {
+		var properties []models.Property
+		if err := inTile(heatmapQuery(filter)).
+			Preload("Factors").
+			Order("properties.id").
+			Limit(maxTileProperties).
+			Find(&properties).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		convertProperties(properties, filter.Currency)
+		weighProperties(properties, filter.FactorWeights())
+		for i := range properties {
+			propertyLayer.Append(propertyFeature(&properties[i]))
+		}
+	}
+
+	currentExchangeRate(c, filter.Currency)
+	if len(cellLayer.Features) == 0 && len(propertyLayer.Features) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	layers := mvt.NewLayers(map[string]*geojson.FeatureCollection{
+		"cells":      cellLayer,
+		"properties": propertyLayer,
+	})
+	layers.ProjectToTile(tile)
+	layers.Clip(mvt.MapboxGLDefaultExtentBound)
+
+	data, err := mvt.Marshal(layers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Data(http.StatusOK, mvtContentType, data)
+}
+
+// parseTile reads z/x/y path parameters; y carries the .mvt extension
+func parseTile(zParam, xParam, yParam string) (maptile.Tile, bool) {
+	yParam, found := strings.CutSuffix(yParam, ".mvt")
+	if !found {
+		return maptile.Tile{}, false
+	}
+
+	z, errZ := strconv.ParseUint(zParam, 10, 32)
+	x, errX := strconv.ParseUint(xParam, 10, 32)
+	y, errY := strconv.ParseUint(yParam, 10, 32)
+	if errZ != nil || errX != nil || errY != nil || z > maxTileZoom {
+		return maptile.Tile{}, false
+	}
+
+	tile := maptile.New(uint32(x), uint32(y), maptile.Zoom(z))
+	return tile, tile.Valid()
+}
+
+// geocoded restricts a property query to properties with coordinates
+func geocoded(query *gorm.DB) *gorm.DB {
+	return query.Where("latitude != 0 AND longitude != 0")
+}
+
+// propertyFeature turns a property into a point feature with flat, scalar
+// properties, as vector tiles and most GIS tools expect
+func propertyFeature(p *models.Property) *geojson.Feature {
+	feature := geojson.NewFeature(orb.Point{p.Longitude, p.Latitude})
+	feature.ID = p.ID
+	feature.Properties = geojson.Properties{
+		"id":                   p.ID,
+		"source":               p.Source,
+		"url":                  p.URL,
+		"country":              p.Country,
+		"city":                 p.City,
+		"district":             p.District,
+		"address":              p.Address,
+		"type":                 p.Type,
+		"deal_type":            p.DealType,
+		"price":                p.Price,
+		"currency":             p.Currency,
+		"area":                 p.Area,
+		"rooms":                p.Rooms,
+		"bedrooms":             p.Bedrooms,
+		"bathrooms":            p.Bathrooms,
+		"floor":                p.Floor,
+		"year_built":           p.YearBuilt,
+		"days_on_market":       p.DaysOnMarket,
+		"overall_score":        p.Factors.OverallScore,
+		"crime_score":          p.Factors.CrimeScore,
+		"transport_score":      p.Factors.TransportScore,
+		"education_score":      p.Factors.EducationScore,
+		"infrastructure_score": p.Factors.InfrastructureScore,
+	}
+	if p.PricePerSqm != nil {
+		feature.Properties["price_per_sqm"] = *p.PricePerSqm
+	}
+	return feature
+}
+
+// cellFeature turns a heatmap cell into a square polygon of gridSize degrees,
+// or into its center point
+func cellFeature(point models.PriceHeatmapPoint, gridSize float64, asPoint bool) *geojson.Feature {
+	var geometry orb.Geometry = orb.Point{point.Longitude, point.Latitude}
+	if !asPoint {
+		half := gridSize / 2
+		geometry = orb.Bound{
+			Min: orb.Point{point.Longitude - half, point.Latitude - half},
+			Max: orb.Point{point.Longitude + half, point.Latitude + half},
+		}.ToPolygon()
+	}
+
+	feature := geojson.NewFeature(geometry)
+	feature.Properties = geojson.Properties{
+		"price":         roundTo(point.Price, 2),
+		"median_price":  roundTo(point.MedianPrice, 2),
+		"price_per_sqm": roundTo(point.PricePerSqm, 2),
+		"score":         roundTo(point.Score, 2),
+		"count":         point.Count,
+		"value":         roundTo(point.Value, 2),
+	}
+	return feature
+}
+
+func roundTo(value float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
+
+func writeGeoJSON(c *gin.Context, fc *geojson.FeatureCollection) {
+	data, err := json.Marshal(fc)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, geoJSONContentType, data)
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"pricemap-go/models"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/mvt"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/maptile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTile(t *testing.T) {
+	tests := []struct {
+		name    string
+		z, x, y string
+		want    maptile.Tile
+		ok      bool
+	}{
+		{name: "valid", z: "12", x: "2476", y: "1280.mvt", want: maptile.New(2476, 1280, 12), ok: true},
+		{name: "world", z: "0", x: "0", y: "0.mvt", want: maptile.New(0, 0, 0), ok: true},
+		{name: "missing extension", z: "12", x: "2476", y: "1280"},
+		{name: "other extension", z: "12", x: "2476", y: "1280.png"},
+		{name: "x outside zoom", z: "2", x: "4", y: "0.mvt"},
+		{name: "negative", z: "3", x: "-1", y: "0.mvt"},
+		{name: "zoom too deep", z: "30", x: "0", y: "0.mvt"},
+		{name: "not a number", z: "z", x: "0", y: "0.mvt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tile, ok := parseTile(tt.z, tt.x, tt.y)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.Equal(t, tt.want, tile)
+			}
+		})
+	}
+}
+
+func TestCellFeature(t *testing.T) {
+	point := models.PriceHeatmapPoint{
+		Latitude: 55.755, Longitude: 37.615,
+		Price: 100000.456, MedianPrice: 90000, PricePerSqm: 2500, Score: 70, Count: 3, Value: 2500,
+	}
+
+	polygon := cellFeature(point, 0.01, false)
+	bound := polygon.Geometry.Bound()
+	assert.InDelta(t, 37.61, bound.Min.Lon(), 1e-9)
+	assert.InDelta(t, 55.76, bound.Max.Lat(), 1e-9)
+	assert.Equal(t, 100000.46, polygon.Properties["price"])
+	assert.Equal(t, 3, polygon.Properties["count"])
+	assert.Equal(t, 2500.0, polygon.Properties["value"])
+
+	center := cellFeature(point, 0.01, true)
+	assert.Equal(t, orb.Point{37.615, 55.755}, center.Geometry)
+}
+
+func TestPropertyFeature_GeoJSON(t *testing.T) {
+	property := models.Property{
+		ID: 7, Source: "cian", City: "Moscow", Latitude: 55.75, Longitude: 37.61, Price: 150000, Rooms: 2,
+		Factors: models.PropertyFactors{OverallScore: 81},
+	}
+
+	fc := geojson.NewFeatureCollection()
+	fc.Append(propertyFeature(&property))
+	data, err := json.Marshal(fc)
+	require.NoError(t, err)
+
+	decoded, err := geojson.UnmarshalFeatureCollection(data)
+	require.NoError(t, err)
+	require.Len(t, decoded.Features, 1)
+	feature := decoded.Features[0]
+	assert.Equal(t, orb.Point{37.61, 55.75}, feature.Geometry)
+	assert.Equal(t, float64(7), feature.ID)
+	assert.Equal(t, "cian", feature.Properties["source"])
+	assert.Equal(t, float64(81), feature.Properties["overall_score"])
+}
+
+func TestPropertyFeature_VectorTile(t *testing.T) {
+	tile := maptile.At(orb.Point{37.61, 55.75}, 15)
+	property := models.Property{ID: 7, Source: "cian", Latitude: 55.75, Longitude: 37.61, Price: 150000}
+
+	cells := geojson.NewFeatureCollection()
+	cells.Append(cellFeature(models.PriceHeatmapPoint{Latitude: 55.75, Longitude: 37.61, Price: 150000, Count: 1}, 0.0003125, false))
+	properties := geojson.NewFeatureCollection()
+	properties.Append(propertyFeature(&property))
+
+	layers := mvt.NewLayers(map[string]*geojson.FeatureCollection{"cells": cells, "properties": properties})
+	layers.ProjectToTile(tile)
+	data, err := mvt.Marshal(layers)
+	require.NoError(t, err)
+
+	decoded, err := mvt.Unmarshal(data)
+	require.NoError(t, err)
+	byName := decoded.ToFeatureCollections()
+	require.Len(t, byName["properties"].Features, 1)
+	require.Len(t, byName["cells"].Features, 1)
+	assert.Equal(t, "cian", byName["properties"].Features[0].Properties["source"])
+}
+
+func TestHandler_GetTile_InvalidCoordinates(t *testing.T) {
+	router := setupTestRouter()
+
+	for _, url := range []string{"/api/v1/tiles/2/9/0.mvt", "/api/v1/tiles/2/1/1.png"} {
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+	}
+}
+
+func TestHandler_GeoJSONEndpoints(t *testing.T) {
+	router := setupTestRouter()
+
+	for _, url := range []string{"/api/v1/properties.geojson?city=Moscow", "/api/v1/heatmap.geojson?zoom=10"} {
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		// Might fail without DB, but the routes must exist
+		assert.True(t, w.Code == http.StatusOK || w.Code == http.StatusInternalServerError, url)
+		if w.Code == http.StatusOK {
+			assert.Equal(t, geoJSONContentType, w.Header().Get("Content-Type"))
+		}
+	}
+}
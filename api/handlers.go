@@ -1,13 +1,17 @@
 package api
 
 import (
+	"math"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 
 	"pricemap-go/database"
 	"pricemap-go/models"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type Handler struct{}
@@ -16,345 +20,333 @@ func NewHandler() *Handler {
 	return &Handler{}
 }
 
-// GetHeatmapData returns data for heatmap
+// GetHeatmapData returns properties aggregated into grid cells for the heatmap.
+// The cell size follows the map zoom, and only the densest cells are returned.
 func (h *Handler) GetHeatmapData(c *gin.Context) {
-	// Request parameters
-	latMin, _ := strconv.ParseFloat(c.Query("lat_min"), 64)
-	latMax, _ := strconv.ParseFloat(c.Query("lat_max"), 64)
-	lngMin, _ := strconv.ParseFloat(c.Query("lng_min"), 64)
-	lngMax, _ := strconv.ParseFloat(c.Query("lng_max"), 64)
-
-	// Grid size for aggregation
-	gridSize := 0.01 // ~1km
-
-	var properties []models.Property
-	query := database.DB.Where("is_active = ?", true).
-		Where("latitude != 0 AND longitude != 0").
-		Where("latitude IS NOT NULL AND longitude IS NOT NULL")
-
-	// Apply bounds filter only if provided
-	if latMin != 0 || latMax != 0 || lngMin != 0 || lngMax != 0 {
-		query = query.Where("latitude >= ? AND latitude <= ?", latMin, latMax).
-			Where("longitude >= ? AND longitude <= ?", lngMin, lngMax)
-	}
-
-	// Apply property filters
-	if city := c.Query("city"); city != "" {
-		query = query.Where("city = ?", city)
-	}
-	if propertyType := c.Query("type"); propertyType != "" {
-		query = query.Where("type = ?", propertyType)
-	}
-	if priceMin := c.Query("price_min"); priceMin != "" {
-		if min, err := strconv.ParseFloat(priceMin, 64); err == nil {
-			query = query.Where("price >= ?", min)
-		}
-	}
-	if priceMax := c.Query("price_max"); priceMax != "" {
-		if max, err := strconv.ParseFloat(priceMax, 64); err == nil {
-			query = query.Where("price <= ?", max)
-		}
-	}
-	if roomsMin := c.Query("rooms_min"); roomsMin != "" {
-		if min, err := strconv.Atoi(roomsMin); err == nil {
-			query = query.Where("rooms >= ?", min)
-		}
-	}
-	if roomsMax := c.Query("rooms_max"); roomsMax != "" {
-		if max, err := strconv.Atoi(roomsMax); err == nil {
-			query = query.Where("rooms <= ?", max)
-		}
-	}
-	if bedroomsMin := c.Query("bedrooms_min"); bedroomsMin != "" {
-		if min, err := strconv.Atoi(bedroomsMin); err == nil {
-			query = query.Where("bedrooms >= ?", min)
-		}
-	}
-	if bedroomsMax := c.Query("bedrooms_max"); bedroomsMax != "" {
-		if max, err := strconv.Atoi(bedroomsMax); err == nil {
-			query = query.Where("bedrooms <= ?", max)
-		}
-	}
-	if bathroomsMin := c.Query("bathrooms_min"); bathroomsMin != "" {
-		if min, err := strconv.ParseFloat(bathroomsMin, 64); err == nil {
-			query = query.Where("bathrooms >= ?", min)
-		}
-	}
-	if bathroomsMax := c.Query("bathrooms_max"); bathroomsMax != "" {
-		if max, err := strconv.ParseFloat(bathroomsMax, 64); err == nil {
-			query = query.Where("bathrooms <= ?", max)
-		}
-	}
-	if areaMin := c.Query("area_min"); areaMin != "" {
-		if min, err := strconv.ParseFloat(areaMin, 64); err == nil {
-			query = query.Where("area >= ?", min)
-		}
-	}
-	if areaMax := c.Query("area_max"); areaMax != "" {
-		if max, err := strconv.ParseFloat(areaMax, 64); err == nil {
-			query = query.Where("area <= ?", max)
-		}
+	filter := bindPropertyFilter(c)
+	if filter == nil {
+		return
 	}
 
-	// Score filters (from PropertyFactors)
-	if scoreMin := c.Query("score_min"); scoreMin != "" {
-		if min, err := strconv.ParseFloat(scoreMin, 64); err == nil {
-			query = query.Joins("JOIN property_factors ON property_factors.property_id = properties.id").
-				Where("property_factors.overall_score >= ?", min)
-		}
-	}
-	if crimeScoreMin := c.Query("crime_score_min"); crimeScoreMin != "" {
-		if min, err := strconv.ParseFloat(crimeScoreMin, 64); err == nil {
-			query = query.Joins("JOIN property_factors ON property_factors.property_id = properties.id").
-				Where("property_factors.crime_score >= ?", min)
-		}
-	}
-	if transportScoreMin := c.Query("transport_score_min"); transportScoreMin != "" {
-		if min, err := strconv.ParseFloat(transportScoreMin, 64); err == nil {
-			query = query.Joins("JOIN property_factors ON property_factors.property_id = properties.id").
-				Where("property_factors.transport_score >= ?", min)
-		}
-	}
-	if educationScoreMin := c.Query("education_score_min"); educationScoreMin != "" {
-		if min, err := strconv.ParseFloat(educationScoreMin, 64); err == nil {
-			query = query.Joins("JOIN property_factors ON property_factors.property_id = properties.id").
-				Where("property_factors.education_score >= ?", min)
-		}
+	metric := bindHeatmapMetric(c)
+	if metric == "" {
+		return
 	}
 
-	query = query.Preload("Factors")
+	zoom := heatmapZoom(c.Query("zoom"))
+	gridSize := heatmapGridSize(zoom)
 
-	if err := query.Find(&properties).Error; err != nil {
+	cells, err := queryHeatmapCells(heatmapMetricQuery(heatmapQuery(filter), metric), gridSize, metric, filter)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Aggregate data by grid
-	heatmapData := aggregateToHeatmap(properties, gridSize)
+	heatmapData := make([]models.PriceHeatmapPoint, len(cells))
+	var total, totalCells int64
+	for i, cell := range cells {
+		heatmapData[i] = cell.PriceHeatmapPoint
+		total, totalCells = cell.TotalCount, cell.TotalCells
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"data":  heatmapData,
-		"count": len(properties),
+		"data":             heatmapData,
+		"count":            total,
+		"metric":           metric,
+		"zoom":             zoom,
+		"grid_size":        gridSize,
+		"truncated":        totalCells > int64(len(cells)),
+		"current_exchange": currentExchangeRate(c, filter.Currency),
 	})
 }
 
+// heatmapQuery selects the geocoded active properties matching the filter,
+// joined with their factors
+func heatmapQuery(filter *PropertyFilter) *gorm.DB {
+	// Factors are joined even without score filters for the mean score
+	query := database.DB.Model(&models.Property{}).
+		Joins("LEFT JOIN property_factors ON property_factors.property_id = properties.id").
+		Where("is_active = ?", true).
+		Where("latitude != 0 AND longitude != 0").
+		Where("latitude IS NOT NULL AND longitude IS NOT NULL")
+
+	// Count each physical property once even if several sources list it
+	return uniqueListings(filter.Apply(query))
+}
+
+// heatmapMetricQuery leaves out the properties that have no value for metric,
+// so that they do not cool down their cells
+func heatmapMetricQuery(query *gorm.DB, metric string) *gorm.DB {
+	if metric == "price_per_sqm" {
+		return query.Where("properties.price_per_sqm IS NOT NULL")
+	}
+	return query
+}
+
+// queryHeatmapCells aggregates the properties of a heatmapQuery into grid cells
+// of gridSize degrees, densest first, valued by metric with prices in the
+// filter currency and scores with the filter weights
+func queryHeatmapCells(query *gorm.DB, gridSize float64, metric string, filter *PropertyFilter) ([]heatmapCell, error) {
+	price := convertedSQL("properties.price")
+	perSqm := convertedSQL("properties.price_per_sqm")
+	score := overallScoreSQL(filter.FactorWeights())
+
+	var cells []heatmapCell
+	err := joinPriceFactors(query, filter.Currency).Select(`(FLOOR(latitude / ?) + 0.5) * ? AS latitude,
+			(FLOOR(longitude / ?) + 0.5) * ? AS longitude,
+			AVG(`+price+`) AS price,
+			PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY `+price+`) AS median_price,
+			COALESCE(AVG(`+perSqm+`), 0) AS price_per_sqm,
+			COALESCE(AVG(NULLIF(`+score+`, 0)), 0) AS score,
+			COUNT(*) AS count,
+			(SUM(COUNT(*)) OVER ())::bigint AS total_count,
+			COUNT(*) OVER () AS total_cells`,
+		gridSize, gridSize, gridSize, gridSize).
+		Group("1, 2").
+		Order("count DESC").
+		Limit(maxHeatmapCells).
+		Scan(&cells).Error
+
+	value := heatmapMetrics[metric]
+	for i := range cells {
+		cells[i].Value = value(cells[i].PriceHeatmapPoint)
+	}
+	return cells, err
+}
+
 // GetPropertyDetails returns detailed information about a property
 func (h *Handler) GetPropertyDetails(c *gin.Context) {
 	id := c.Param("id")
 
+	currency := bindCurrency(c)
+	if currency == "" {
+		return
+	}
+	weights, ok := bindWeights(c)
+	if !ok {
+		return
+	}
+
 	var property models.Property
 	if err := database.DB.Preload("Factors").First(&property, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Property not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, property)
+	convertProperty(&property, currency)
+	weighProperty(&property, weights)
+	c.JSON(http.StatusOK, struct {
+		models.Property
+		CurrentExchange ExchangeRate `json:"current_exchange"`
+	}{property, currentExchangeRate(c, currency)})
 }
 
-// GetProperties returns list of properties with filters
-func (h *Handler) GetProperties(c *gin.Context) {
-	var properties []models.Property
-	query := database.DB.Where("is_active = ?", true)
-
-	// Filters
-	if city := c.Query("city"); city != "" {
-		query = query.Where("city = ?", city)
-	}
-	if country := c.Query("country"); country != "" {
-		query = query.Where("country = ?", country)
-	}
-	if propertyType := c.Query("type"); propertyType != "" {
-		query = query.Where("type = ?", propertyType)
-	}
-
-	// Price
-	if priceMin := c.Query("price_min"); priceMin != "" {
-		if min, err := strconv.ParseFloat(priceMin, 64); err == nil {
-			query = query.Where("price >= ?", min)
-		}
-	}
-	if priceMax := c.Query("price_max"); priceMax != "" {
-		if max, err := strconv.ParseFloat(priceMax, 64); err == nil {
-			query = query.Where("price <= ?", max)
-		}
+// GetPropertyHistory returns the price timeline of a property
+func (h *Handler) GetPropertyHistory(c *gin.Context) {
+	id := c.Param("id")
+	currency := bindCurrency(c)
+	if currency == "" {
+		return
 	}
 
-	// Rooms
-	if roomsMin := c.Query("rooms_min"); roomsMin != "" {
-		if min, err := strconv.Atoi(roomsMin); err == nil {
-			query = query.Where("rooms >= ?", min)
-		}
-	}
-	if roomsMax := c.Query("rooms_max"); roomsMax != "" {
-		if max, err := strconv.Atoi(roomsMax); err == nil {
-			query = query.Where("rooms <= ?", max)
-		}
+	var property models.Property
+	if err := database.DB.Select("id").First(&property, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Property not found"})
+		return
 	}
 
-	// Bedrooms
-	if bedroomsMin := c.Query("bedrooms_min"); bedroomsMin != "" {
-		if min, err := strconv.Atoi(bedroomsMin); err == nil {
-			query = query.Where("bedrooms >= ?", min)
-		}
+	var history []models.PriceObservation
+	if err := database.DB.Where("property_id = ?", property.ID).
+		Order("observed_at ASC, id ASC").
+		Find(&history).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
-	if bedroomsMax := c.Query("bedrooms_max"); bedroomsMax != "" {
-		if max, err := strconv.Atoi(bedroomsMax); err == nil {
-			query = query.Where("bedrooms <= ?", max)
-		}
+	for i := range history {
+		convertObservation(&history[i], currency)
 	}
 
-	// Bathrooms
-	if bathroomsMin := c.Query("bathrooms_min"); bathroomsMin != "" {
-		if min, err := strconv.ParseFloat(bathroomsMin, 64); err == nil {
-			query = query.Where("bathrooms >= ?", min)
-		}
-	}
-	if bathroomsMax := c.Query("bathrooms_max"); bathroomsMax != "" {
-		if max, err := strconv.ParseFloat(bathroomsMax, 64); err == nil {
-			query = query.Where("bathrooms <= ?", max)
-		}
-	}
+	c.JSON(http.StatusOK, gin.H{
+		"property_id":      property.ID,
+		"data":             history,
+		"count":            len(history),
+		"current_exchange": currentExchangeRate(c, currency),
+	})
+}
 
-	// Area
-	if areaMin := c.Query("area_min"); areaMin != "" {
-		if min, err := strconv.ParseFloat(areaMin, 64); err == nil {
-			query = query.Where("area >= ?", min)
-		}
-	}
-	if areaMax := c.Query("area_max"); areaMax != "" {
-		if max, err := strconv.ParseFloat(areaMax, 64); err == nil {
-			query = query.Where("area <= ?", max)
-		}
+// GetProperties returns list of properties with filters
+func (h *Handler) GetProperties(c *gin.Context) {
+	filter := bindPropertyFilter(c)
+	if filter == nil {
+		return
 	}
 
-	// Score filters (from PropertyFactors)
-	if scoreMin := c.Query("score_min"); scoreMin != "" {
-		if min, err := strconv.ParseFloat(scoreMin, 64); err == nil {
-			query = query.Joins("JOIN property_factors ON property_factors.property_id = properties.id").
-				Where("property_factors.overall_score >= ?", min)
-		}
-	}
-	if crimeScoreMin := c.Query("crime_score_min"); crimeScoreMin != "" {
-		if min, err := strconv.ParseFloat(crimeScoreMin, 64); err == nil {
-			query = query.Joins("JOIN property_factors ON property_factors.property_id = properties.id").
-				Where("property_factors.crime_score >= ?", min)
-		}
-	}
-	if transportScoreMin := c.Query("transport_score_min"); transportScoreMin != "" {
-		if min, err := strconv.ParseFloat(transportScoreMin, 64); err == nil {
-			query = query.Joins("JOIN property_factors ON property_factors.property_id = properties.id").
-				Where("property_factors.transport_score >= ?", min)
-		}
-	}
-	if educationScoreMin := c.Query("education_score_min"); educationScoreMin != "" {
-		if min, err := strconv.ParseFloat(educationScoreMin, 64); err == nil {
-			query = query.Joins("JOIN property_factors ON property_factors.property_id = properties.id").
-				Where("property_factors.education_score >= ?", min)
-		}
+	page, errs := parsePageRequest(c.Request.URL.Query())
+	if errs != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pagination", "fields": errs})
+		return
 	}
 
-	// Pagination
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
-	offset := (page - 1) * limit
+	query := PropertiesQuery(filter)
 
 	var total int64
-	query.Model(&models.Property{}).Count(&total)
+	query.Session(&gorm.Session{}).Count(&total)
 
-	if err := query.Preload("Factors").
-		Offset(offset).
-		Limit(limit).
-		Find(&properties).Error; err != nil {
+	if page.needsFactors() && !filter.HasScoreFilters() {
+		query = query.Joins("LEFT JOIN property_factors ON property_factors.property_id = properties.id")
+	}
+
+	var properties []models.Property
+	if err := page.Apply(query, filter).Preload("Factors").Find(&properties).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	convertProperties(properties, filter.Currency)
+	weighProperties(properties, filter.FactorWeights())
 
-	c.JSON(http.StatusOK, gin.H{
-		"data":  properties,
-		"total": total,
-		"page":  page,
-		"limit": limit,
-	})
+	// Apply fetched one extra row to learn whether there is a next page
+	var nextCursor *string
+	if len(properties) > page.limit {
+		properties = properties[:page.limit]
+		cursor := page.nextCursor(&properties[page.limit-1])
+		nextCursor = &cursor
+	}
+
+	response := gin.H{
+		"data":             properties,
+		"total":            total,
+		"limit":            page.limit,
+		"next_cursor":      nextCursor,
+		"current_exchange": currentExchangeRate(c, filter.Currency),
+	}
+	if !page.cursor {
+		response["page"] = page.page
+	}
+	c.JSON(http.StatusOK, response)
 }
 
-// GetStats returns statistics
+// PropertiesQuery selects the active properties matching the filter. The
+// export command shares it, so its results match the API's.
+func PropertiesQuery(filter *PropertyFilter) *gorm.DB {
+	query := database.DB.Model(&models.Property{}).Where("is_active = ?", true)
+	if filter.HasScoreFilters() {
+		query = query.Joins("JOIN property_factors ON property_factors.property_id = properties.id")
+	}
+	return filter.Apply(query)
+}
+
+// GetStats returns statistics of the properties matching the filters
 func (h *Handler) GetStats(c *gin.Context) {
+	filter := bindPropertyFilter(c)
+	if filter == nil {
+		return
+	}
+
 	var stats struct {
-		TotalProperties int64    `json:"total_properties"`
-		AvgPrice        float64  `json:"avg_price"`
-		Countries       []string `json:"countries"`
-		Cities          []string `json:"cities"`
+		TotalProperties   int64        `json:"total_properties"`
+		AvgPrice          float64      `json:"avg_price"`
+		AvgPricePerSqm    float64      `json:"avg_price_per_sqm"` // over properties with a known area
+		MedianPricePerSqm float64      `json:"median_price_per_sqm"`
+		Countries         []string     `json:"countries"`
+		Cities            []string     `json:"cities"`
+		CurrentExchange   ExchangeRate `json:"current_exchange"`
 	}
 
-	database.DB.Model(&models.Property{}).
-		Where("is_active = ?", true).
-		Count(&stats.TotalProperties)
+	// Count each physical property once even if several sources list it
+	active := func() *gorm.DB {
+		return uniqueListings(PropertiesQuery(filter))
+	}
 
-	database.DB.Model(&models.Property{}).
-		Where("is_active = ?", true).
-		Select("AVG(price)").Scan(&stats.AvgPrice)
+	active().Count(&stats.TotalProperties)
 
-	database.DB.Model(&models.Property{}).
-		Where("is_active = ?", true).
-		Distinct("country").
+	price := convertedSQL("properties.price")
+	perSqm := convertedSQL("properties.price_per_sqm")
+
+	joinPriceFactors(active(), filter.Currency).Select("COALESCE(AVG(" + price + "), 0)").Scan(&stats.AvgPrice)
+
+	var perSqmStats struct {
+		Avg    float64
+		Median float64
+	}
+	joinPriceFactors(active(), filter.Currency).Select(`COALESCE(AVG(` + perSqm + `), 0) AS avg,
+		COALESCE(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY ` + perSqm + `), 0) AS median`).
+		Scan(&perSqmStats)
+	stats.AvgPricePerSqm, stats.MedianPricePerSqm = perSqmStats.Avg, perSqmStats.Median
+
+	active().Distinct("country").
 		Pluck("country", &stats.Countries)
 
-	database.DB.Model(&models.Property{}).
-		Where("is_active = ?", true).
-		Distinct("city").
+	active().Distinct("city").
 		Pluck("city", &stats.Cities)
 
+	stats.CurrentExchange = currentExchangeRate(c, filter.Currency)
+
 	c.JSON(http.StatusOK, stats)
 }
 
-// aggregateToHeatmap aggregates properties into points for heatmap
-func aggregateToHeatmap(properties []models.Property, gridSize float64) []models.PriceHeatmapPoint {
-	grid := make(map[string]*models.PriceHeatmapPoint)
-
-	for _, prop := range properties {
-		// Round coordinates to grid size
-		lat := roundToGrid(prop.Latitude, gridSize)
-		lng := roundToGrid(prop.Longitude, gridSize)
-
-		key := formatGridKey(lat, lng)
-
-		if point, exists := grid[key]; exists {
-			point.Price += prop.Price
-			point.Count++
-			if prop.Factors.OverallScore > 0 {
-				point.Score = (point.Score*float64(point.Count-1) + prop.Factors.OverallScore) / float64(point.Count)
-			}
-		} else {
-			score := 0.0
-			if prop.Factors.OverallScore > 0 {
-				score = prop.Factors.OverallScore
-			}
-			grid[key] = &models.PriceHeatmapPoint{
-				Latitude:  lat,
-				Longitude: lng,
-				Price:     prop.Price,
-				Score:     score,
-				Count:     1,
-			}
-		}
-	}
+// Heatmap grid: cells are heatmapBaseGridSize degrees wide at heatmapDefaultZoom
+// and halve with every zoom level
+const (
+	heatmapDefaultZoom  = 12
+	heatmapMinZoom      = 0
+	heatmapMaxZoom      = 20
+	heatmapBaseGridSize = 0.01 // ~1km
 
-	// Convert to array and calculate average prices
-	result := make([]models.PriceHeatmapPoint, 0, len(grid))
-	for _, point := range grid {
-		if point.Count > 0 {
-			point.Price = point.Price / float64(point.Count)
-		}
-		result = append(result, *point)
+	// maxHeatmapCells bounds the response however many properties match
+	maxHeatmapCells = 5000
+)
+
+// heatmapCell is one aggregated grid cell with the totals of the whole query
+type heatmapCell struct {
+	models.PriceHeatmapPoint
+	TotalCount int64
+	TotalCells int64
+}
+
+// defaultHeatmapMetric is the metric heatmap intensities follow by default
+const defaultHeatmapMetric = "price"
+
+// heatmapMetrics are the cell aggregates the metric parameter can select as
+// the value of heatmap cells
+var heatmapMetrics = map[string]func(p models.PriceHeatmapPoint) float64{
+	"price":         func(p models.PriceHeatmapPoint) float64 { return p.Price },
+	"median_price":  func(p models.PriceHeatmapPoint) float64 { return p.MedianPrice },
+	"price_per_sqm": func(p models.PriceHeatmapPoint) float64 { return p.PricePerSqm },
+	"score":         func(p models.PriceHeatmapPoint) float64 { return p.Score },
+	"count":         func(p models.PriceHeatmapPoint) float64 { return float64(p.Count) },
+}
+
+// bindHeatmapMetric reads the metric parameter. An empty metric means the
+// response, 400 naming the valid metrics, has been written.
+func bindHeatmapMetric(c *gin.Context) string {
+	metric := strings.TrimSpace(c.DefaultQuery("metric", defaultHeatmapMetric))
+	if _, ok := heatmapMetrics[metric]; ok {
+		return metric
 	}
 
-	return result
+	names := make([]string, 0, len(heatmapMetrics))
+	for name := range heatmapMetrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":  "Invalid metric",
+		"fields": FilterErrors{"metric": "must be one of " + strings.Join(names, ", ")},
+	})
+	return ""
 }
 
-func roundToGrid(value, gridSize float64) float64 {
-	return float64(int(value/gridSize)) * gridSize
+// heatmapZoom parses the zoom parameter, clamped to the supported levels.
+// Fractional zooms from smooth map zooming round down.
+func heatmapZoom(value string) int {
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(parsed) {
+		return heatmapDefaultZoom
+	}
+	return int(math.Max(math.Min(math.Floor(parsed), heatmapMaxZoom), heatmapMinZoom))
 }
 
-func formatGridKey(lat, lng float64) string {
-	return strconv.FormatFloat(lat, 'f', 6, 64) + "," + strconv.FormatFloat(lng, 'f', 6, 64)
+// heatmapGridSize returns the cell size in degrees for a map zoom level
+func heatmapGridSize(zoom int) float64 {
+	return heatmapBaseGridSize * math.Pow(2, float64(heatmapDefaultZoom-zoom))
 }
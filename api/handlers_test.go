@@ -5,10 +5,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+
+	"pricemap-go/models"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
-	"pricemap-go/models"
 )
 
 func setupTestRouter() *gin.Engine {
@@ -28,7 +30,39 @@ func TestHandler_GetStats(t *testing.T) {
 	assert.True(t, w.Code == http.StatusOK || w.Code == http.StatusInternalServerError)
 }
 
+// fakeRunJournal serves fixed scrape runs in place of the database
+type fakeRunJournal struct {
+	stats []sourceRunStats
+	runs  []models.ScrapeRun
+}
+
+func (f fakeRunJournal) SourceStats(since time.Time, source string) ([]sourceRunStats, error) {
+	var stats []sourceRunStats
+	for _, s := range f.stats {
+		if source == "" || s.Source == source {
+			stats = append(stats, s)
+		}
+	}
+	return stats, nil
+}
+
+func (f fakeRunJournal) RecentRuns(source string, limit int) ([]models.ScrapeRun, error) {
+	return f.runs, nil
+}
+
+func useRunJournal(t *testing.T, journal runJournal) {
+	t.Helper()
+	saved := scrapeRuns
+	scrapeRuns = journal
+	t.Cleanup(func() { scrapeRuns = saved })
+}
+
 func TestHandler_GetMetrics(t *testing.T) {
+	started := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	useRunJournal(t, fakeRunJournal{stats: []sourceRunStats{
+		{Source: "cian", Runs: 3, FailedRuns: 1, Parsed: 120, Saved: 100, Failed: 2},
+		{Source: "zillow", Runs: 2, Parsed: 80, Saved: 75, Failed: 1, LastRun: &models.ScrapeRun{Source: "zillow", StartedAt: started}},
+	}})
 	router := setupTestRouter()
 
 	req, _ := http.NewRequest("GET", "/api/v1/metrics", nil)
@@ -40,16 +74,64 @@ func TestHandler_GetMetrics(t *testing.T) {
 	var response map[string]interface{}
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
-	assert.Contains(t, response, "total_parsed")
-	assert.Contains(t, response, "total_saved")
-	assert.Contains(t, response, "total_errors")
-	assert.Contains(t, response, "uptime_seconds")
+	assert.Equal(t, 5.0, response["total_runs"])
+	assert.Equal(t, 1.0, response["failed_runs"])
+	assert.Equal(t, 200.0, response["total_parsed"])
+	assert.Equal(t, 175.0, response["total_saved"])
+	assert.Equal(t, 3.0, response["total_errors"])
+	assert.Equal(t, "2024-03-01T12:00:00Z", response["last_run_at"])
+	assert.Len(t, response["parser_stats"], 2)
+	assert.Greater(t, response["uptime_seconds"], 0.0)
+}
+
+func TestHandler_GetParserMetrics(t *testing.T) {
+	useRunJournal(t, fakeRunJournal{
+		stats: []sourceRunStats{{Source: "cian", Runs: 1, Parsed: 40, Saved: 40}},
+		runs:  []models.ScrapeRun{{Source: "cian", Status: models.ScrapeRunSucceeded, Parsed: 40, Saved: 40}},
+	})
+	router := setupTestRouter()
+
+	req, _ := http.NewRequest("GET", "/api/v1/metrics/parser/cian", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Stats sourceRunStats     `json:"stats"`
+		Runs  []models.ScrapeRun `json:"runs"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, int64(40), response.Stats.Parsed)
+	assert.Len(t, response.Runs, 1)
+
+	// Sources without runs are not found
+	req, _ = http.NewRequest("GET", "/api/v1/metrics/parser/zillow", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestPrometheusMetrics(t *testing.T) {
+	router := setupTestRouter()
+
+	// Record one request so the latency histogram has a series
+	req, _ := http.NewRequest("GET", "/liveness", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	req, _ = http.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, `pricemap_http_request_duration_seconds_count{method="GET",route="/liveness",status="200"}`)
+	assert.Contains(t, body, "go_goroutines")
 }
 
 func TestHandler_GetHeatmapData(t *testing.T) {
 	router := setupTestRouter()
 
-	req, _ := http.NewRequest("GET", "/api/v1/heatmap?lat_min=55&lat_max=56&lng_min=37&lng_max=38", nil)
+	req, _ := http.NewRequest("GET", "/api/v1/heatmap?lat_min=55&lat_max=56&lng_min=37&lng_max=38&zoom=10", nil)
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
@@ -106,113 +188,111 @@ func TestHandler_GetPropertyDetails(t *testing.T) {
 	assert.True(t, w.Code == http.StatusNotFound || w.Code == http.StatusInternalServerError)
 }
 
-func TestHandler_CORS(t *testing.T) {
+func TestHandler_GetPropertyHistory(t *testing.T) {
 	router := setupTestRouter()
 
-	req, _ := http.NewRequest("OPTIONS", "/api/v1/stats", nil)
+	req, _ := http.NewRequest("GET", "/api/v1/properties/999999/history", nil)
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusNoContent, w.Code)
-	assert.Contains(t, w.Header().Get("Access-Control-Allow-Origin"), "*")
+	// Should return 404 or 500 (if DB not connected)
+	assert.True(t, w.Code == http.StatusNotFound || w.Code == http.StatusInternalServerError)
 }
 
-func TestAggregateToHeatmap(t *testing.T) {
-	properties := []models.Property{
-		{
-			Latitude:  55.7558,
-			Longitude: 37.6173,
-			Price:     100000,
-			Factors: models.PropertyFactors{
-				OverallScore: 80.0,
-			},
-		},
-		{
-			Latitude:  55.7559,
-			Longitude: 37.6174,
-			Price:     150000,
-			Factors: models.PropertyFactors{
-				OverallScore: 70.0,
-			},
-		},
-		{
-			Latitude:  55.7558,
-			Longitude: 37.6173, // Same grid cell (after rounding)
-			Price:     120000,
-			Factors: models.PropertyFactors{
-				OverallScore: 75.0,
-			},
-		},
-	}
+func TestHandler_GetPropertyDuplicates(t *testing.T) {
+	router := setupTestRouter()
 
-	gridSize := 0.01
-	result := aggregateToHeatmap(properties, gridSize)
+	req, _ := http.NewRequest("GET", "/api/v1/properties/999999/duplicates", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
 
-	if len(result) == 0 {
-		t.Errorf("aggregateToHeatmap() returned empty result")
-	}
+	// Should return 404 or 500 (if DB not connected)
+	assert.True(t, w.Code == http.StatusNotFound || w.Code == http.StatusInternalServerError)
+}
 
-	// Check that we have aggregated data
-	totalCount := 0
-	for _, point := range result {
-		totalCount += point.Count
-		if point.Price <= 0 {
-			t.Errorf("aggregateToHeatmap() price should be positive, got %v", point.Price)
-		}
-		if point.Latitude == 0 || point.Longitude == 0 {
-			t.Errorf("aggregateToHeatmap() coordinates should not be zero")
-		}
-	}
+func TestHandler_ExportProperties_InvalidParameters(t *testing.T) {
+	router := setupTestRouter()
 
-	if totalCount != len(properties) {
-		t.Errorf("aggregateToHeatmap() total count = %v, want %v", totalCount, len(properties))
+	for _, url := range []string{
+		"/api/v1/properties/export?format=xlsx",
+		"/api/v1/properties/export?format=csv&columns=id,factors",
+	} {
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
 	}
 }
 
-func TestRoundToGrid(t *testing.T) {
+func TestHandler_CORS(t *testing.T) {
+	router := setupTestRouter()
+
+	req, _ := http.NewRequest("OPTIONS", "/api/v1/stats", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Origin"), "*")
+}
+
+func TestHeatmapZoom(t *testing.T) {
 	tests := []struct {
-		name     string
-		value    float64
-		gridSize float64
-		want     float64
+		name  string
+		value string
+		want  int
 	}{
-		{
-			name:     "round down",
-			value:    55.7558,
-			gridSize: 0.01,
-			want:     55.75,
-		},
-		{
-			name:     "round up",
-			value:    55.7599,
-			gridSize: 0.01,
-			want:     55.75,
-		},
+		{name: "missing", value: "", want: heatmapDefaultZoom},
+		{name: "invalid", value: "close", want: heatmapDefaultZoom},
+		{name: "whole", value: "15", want: 15},
+		{name: "fractional", value: "9.7", want: 9},
+		{name: "below range", value: "-3", want: heatmapMinZoom},
+		{name: "above range", value: "30", want: heatmapMaxZoom},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := roundToGrid(tt.value, tt.gridSize)
-			diff := got - tt.want
-			if diff < 0 {
-				diff = -diff
-			}
-			if diff > 0.001 {
-				t.Errorf("roundToGrid() = %v, want %v", got, tt.want)
-			}
+			assert.Equal(t, tt.want, heatmapZoom(tt.value))
 		})
 	}
 }
 
-func TestFormatGridKey(t *testing.T) {
-	key := formatGridKey(55.7558, 37.6173)
-	
-	if key == "" {
-		t.Errorf("formatGridKey() returned empty string")
+func TestHeatmapGridSize(t *testing.T) {
+	assert.InDelta(t, 0.01, heatmapGridSize(heatmapDefaultZoom), 1e-12)
+	assert.InDelta(t, 0.02, heatmapGridSize(heatmapDefaultZoom-1), 1e-12)
+	assert.InDelta(t, 0.005, heatmapGridSize(heatmapDefaultZoom+1), 1e-12)
+
+	// Every zoom level in range gets a usable, strictly smaller cell
+	for zoom := heatmapMinZoom; zoom < heatmapMaxZoom; zoom++ {
+		assert.Greater(t, heatmapGridSize(zoom), heatmapGridSize(zoom+1))
 	}
-	
-	// Should contain both coordinates
-	if len(key) < 10 {
-		t.Errorf("formatGridKey() returned too short key: %s", key)
+	assert.Greater(t, heatmapGridSize(heatmapMaxZoom), 0.0)
+}
+
+func TestHandler_HeatmapInvalidMetric(t *testing.T) {
+	router := setupTestRouter()
+
+	for _, url := range []string{
+		"/api/v1/heatmap?metric=rent",
+		"/api/v1/heatmap.geojson?metric=rent",
+		"/api/v1/tiles/12/2476/1280.mvt?metric=rent",
+	} {
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+		assert.Contains(t, w.Body.String(), `"metric":"must be one of count, median_price, price, price_per_sqm, score"`, url)
 	}
 }
+
+func TestHeatmapMetrics(t *testing.T) {
+	point := models.PriceHeatmapPoint{Price: 300000, MedianPrice: 250000, PricePerSqm: 5000, Score: 72, Count: 4}
+
+	assert.Equal(t, 300000.0, heatmapMetrics[defaultHeatmapMetric](point))
+	assert.Equal(t, 250000.0, heatmapMetrics["median_price"](point))
+	assert.Equal(t, 5000.0, heatmapMetrics["price_per_sqm"](point))
+	assert.Equal(t, 72.0, heatmapMetrics["score"](point))
+	assert.Equal(t, 4.0, heatmapMetrics["count"](point))
+}
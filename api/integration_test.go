@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -20,7 +22,7 @@ func TestAPI_Integration_Heatmap(t *testing.T) {
 	router := setupTestRouter()
 
 	// Test heatmap endpoint with valid bounds
-	req, _ := http.NewRequest("GET", "/api/v1/heatmap?lat_min=55&lat_max=56&lng_min=37&lng_max=38", nil)
+	req, _ := http.NewRequest("GET", "/api/v1/heatmap?lat_min=55&lat_max=56&lng_min=37&lng_max=38&zoom=10", nil)
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
@@ -31,6 +33,70 @@ func TestAPI_Integration_Heatmap(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Contains(t, response, "data")
 	assert.Contains(t, response, "count")
+	assert.Equal(t, float64(10), response["zoom"])
+	assert.InDelta(t, 0.04, response["grid_size"], 1e-9)
+	assert.Contains(t, response, "truncated")
+}
+
+func TestAPI_Integration_GeoJSON(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	router := setupTestRouter()
+
+	for _, url := range []string{
+		"/api/v1/heatmap.geojson?lat_min=55&lat_max=56&lng_min=37&lng_max=38&zoom=10",
+		"/api/v1/properties.geojson?lat_min=55&lat_max=56&lng_min=37&lng_max=38",
+	} {
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/geo+json", w.Header().Get("Content-Type"))
+
+		var response map[string]interface{}
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "FeatureCollection", response["type"])
+		assert.Contains(t, response, "features")
+	}
+}
+
+func TestAPI_Integration_Tile(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	router := setupTestRouter()
+
+	// Central Moscow at zoom 12
+	req, _ := http.NewRequest("GET", "/api/v1/tiles/12/2476/1280.mvt", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Contains(t, []int{http.StatusOK, http.StatusNoContent}, w.Code)
+	if w.Code == http.StatusOK {
+		assert.Equal(t, "application/vnd.mapbox-vector-tile", w.Header().Get("Content-Type"))
+	}
+}
+
+func TestAPI_Integration_Export(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	router := setupTestRouter()
+
+	req, _ := http.NewRequest("GET", "/api/v1/properties/export?format=csv&columns=id,city,price,overall_score&city=Moscow", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+	assert.True(t, strings.HasPrefix(w.Body.String(), "id,city,price,overall_score\n"))
 }
 
 func TestAPI_Integration_Properties_WithFilters(t *testing.T) {
@@ -111,6 +177,42 @@ func TestAPI_Integration_Pagination(t *testing.T) {
 
 	assert.Equal(t, 1, page)
 	assert.Equal(t, 10, limit)
+
+	// Follow the cursor: the next page starts after the last row
+	cursor, ok := response["next_cursor"].(string)
+	if !ok {
+		return // a single page
+	}
+	req, _ = http.NewRequest("GET", "/api/v1/properties?limit=10&cursor="+url.QueryEscape(cursor), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var next struct {
+		Data []struct {
+			ID uint `json:"id"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &next))
+	first := response["data"].([]interface{})
+	lastID := uint(first[len(first)-1].(map[string]interface{})["id"].(float64))
+	for _, property := range next.Data {
+		assert.Greater(t, property.ID, lastID)
+	}
+}
+
+func TestAPI_Integration_ParserMetrics(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	router := setupTestRouter()
+
+	req, _ := http.NewRequest("GET", "/api/v1/metrics/parser/no_such_source", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
 }
 
 func TestAPI_RateLimit(t *testing.T) {
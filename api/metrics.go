@@ -2,33 +2,174 @@ package api
 
 import (
 	"net/http"
-	
+	"strconv"
+	"time"
+
+	"pricemap-go/database"
+	"pricemap-go/models"
+
 	"github.com/gin-gonic/gin"
-	"pricemap-go/services"
 )
 
-var metricsService *services.MetricsService
+// defaultMetricsDays is the window metrics cover unless ?days= is given
+const defaultMetricsDays = 7
+
+// sourceRunStats sums up the journaled scrape runs of one source
+type sourceRunStats struct {
+	Source                 string            `json:"source"`
+	Runs                   int64             `json:"runs"`
+	FailedRuns             int64             `json:"failed_runs"`
+	Parsed                 int64             `json:"parsed"`
+	Saved                  int64             `json:"saved"`
+	Failed                 int64             `json:"failed"`
+	AverageDurationSeconds float64           `json:"average_duration_seconds"`
+	LastRun                *models.ScrapeRun `json:"last_run" gorm:"-"`
+}
+
+// runJournal reads the scrape run journal. Tests replace scrapeRuns to serve
+// metrics without a database.
+type runJournal interface {
+	// SourceStats aggregates the runs started since the given time by source,
+	// or of one source
+	SourceStats(since time.Time, source string) ([]sourceRunStats, error)
+	// RecentRuns returns the latest runs of a source, newest first
+	RecentRuns(source string, limit int) ([]models.ScrapeRun, error)
+}
+
+var scrapeRuns runJournal = dbRunJournal{}
+
+// dbRunJournal reads the journal from the scrape_runs table
+type dbRunJournal struct{}
+
+// metricsSince reads the ?days= window; 0 covers all runs
+func metricsSince(c *gin.Context) time.Time {
+	days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(defaultMetricsDays)))
+	if err != nil || days < 0 {
+		days = defaultMetricsDays
+	}
+	if days == 0 {
+		return time.Time{}
+	}
+	return time.Now().AddDate(0, 0, -days)
+}
+
+func (dbRunJournal) SourceStats(since time.Time, source string) ([]sourceRunStats, error) {
+	query := database.DB.Model(&models.ScrapeRun{}).
+		Select(`source,
+			COUNT(*) AS runs,
+			COUNT(*) FILTER (WHERE status = ?) AS failed_runs,
+			COALESCE(SUM(parsed), 0) AS parsed,
+			COALESCE(SUM(saved), 0) AS saved,
+			COALESCE(SUM(failed), 0) AS failed,
+			COALESCE(AVG(EXTRACT(EPOCH FROM finished_at - started_at)), 0) AS average_duration_seconds`,
+			models.ScrapeRunFailed).
+		Where("started_at >= ?", since).
+		Group("source").
+		Order("source")
+	if source != "" {
+		query = query.Where("source = ?", source)
+	}
+
+	stats := []sourceRunStats{}
+	if err := query.Scan(&stats).Error; err != nil {
+		return nil, err
+	}
 
-func init() {
-	metricsService = services.NewMetricsService()
+	// Attach the latest run of each source
+	var lastRuns []models.ScrapeRun
+	lastQuery := database.DB.Raw(`SELECT DISTINCT ON (source) * FROM scrape_runs
+		WHERE started_at >= ? ORDER BY source, started_at DESC`, since)
+	if err := lastQuery.Scan(&lastRuns).Error; err != nil {
+		return nil, err
+	}
+	for i := range lastRuns {
+		for j := range stats {
+			if stats[j].Source == lastRuns[i].Source {
+				stats[j].LastRun = &lastRuns[i]
+			}
+		}
+	}
+
+	return stats, nil
 }
 
-// GetMetrics returns system metrics
+func (dbRunJournal) RecentRuns(source string, limit int) ([]models.ScrapeRun, error) {
+	runs := []models.ScrapeRun{}
+	err := database.DB.Where("source = ?", source).
+		Order("started_at DESC").
+		Limit(limit).
+		Find(&runs).Error
+	return runs, err
+}
+
+// GetMetrics returns scrape totals per source from the run journal
 func (h *Handler) GetMetrics(c *gin.Context) {
-	stats := metricsService.GetStats()
-	c.JSON(http.StatusOK, stats)
+	since := metricsSince(c)
+
+	stats, err := scrapeRuns.SourceStats(since, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var runs, failedRuns, parsed, saved, failed int64
+	var lastRunAt *time.Time
+	for _, s := range stats {
+		runs += s.Runs
+		failedRuns += s.FailedRuns
+		parsed += s.Parsed
+		saved += s.Saved
+		failed += s.Failed
+		if s.LastRun != nil && (lastRunAt == nil || s.LastRun.StartedAt.After(*lastRunAt)) {
+			lastRunAt = &s.LastRun.StartedAt
+		}
+	}
+
+	var sinceValue interface{}
+	if !since.IsZero() {
+		sinceValue = since
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"since":          sinceValue,
+		"total_runs":     runs,
+		"failed_runs":    failedRuns,
+		"total_parsed":   parsed,
+		"total_saved":    saved,
+		"total_errors":   failed,
+		"last_run_at":    lastRunAt,
+		"parser_stats":   stats,
+		"uptime_seconds": time.Since(startTime).Seconds(),
+	})
 }
 
-// GetParserMetrics returns metrics for a specific parser
+// GetParserMetrics returns the totals and recent runs of one source
 func (h *Handler) GetParserMetrics(c *gin.Context) {
 	parserName := c.Param("parser")
-	stats := metricsService.GetParserStats(parserName)
-	
-	if stats == nil {
+
+	stats, err := scrapeRuns.SourceStats(metricsSince(c), parserName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(stats) == 0 {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Parser not found"})
 		return
 	}
-	
-	c.JSON(http.StatusOK, stats)
-}
 
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	runs, err := scrapeRuns.RecentRuns(parserName, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"stats": stats[0],
+		"runs":  runs,
+	})
+}
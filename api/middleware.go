@@ -2,9 +2,11 @@ package api
 
 import (
 	"fmt"
+	"strconv"
 	"time"
 	
 	"github.com/gin-gonic/gin"
+	"pricemap-go/metrics"
 )
 
 // LoggerMiddleware logs HTTP requests
@@ -24,6 +26,23 @@ func LoggerMiddleware() gin.HandlerFunc {
 	})
 }
 
+// MetricsMiddleware records request latency by route template, so
+// /properties/1 and /properties/2 share a series
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.HTTPRequestDuration.
+			WithLabelValues(route, c.Request.Method, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
 // RateLimitMiddleware implements basic rate limiting
 func RateLimitMiddleware() gin.HandlerFunc {
 	// Simple in-memory rate limiter
@@ -0,0 +1,361 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"pricemap-go/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 100
+)
+
+type sortKind int
+
+const (
+	sortInt sortKind = iota
+	sortFloat
+	sortTime
+	sortPrice // converted into the requested currency
+	sortScore // the overall score, with the requested weights
+)
+
+// sortColumn is a column the property list can be ordered and paged by. The
+// value of the last row of a page is stored in the cursor of the next one.
+type sortColumn struct {
+	expr    string
+	kind    sortKind
+	factors bool // expr refers to property_factors
+	value   func(p *models.Property) any
+}
+
+// sortColumns whitelists the sort parameter. Factor scores default to 0 for
+// properties without factors, as they do in the JSON.
+var sortColumns = map[string]sortColumn{
+	"id":              {"properties.id", sortInt, false, func(p *models.Property) any { return p.ID }},
+	"price":           {"properties.price", sortPrice, false, cursorPriceOf},
+	"area":            {"properties.area", sortFloat, false, func(p *models.Property) any { return p.Area }},
+	"rooms":           {"properties.rooms", sortInt, false, func(p *models.Property) any { return p.Rooms }},
+	"bedrooms":        {"properties.bedrooms", sortInt, false, func(p *models.Property) any { return p.Bedrooms }},
+	"bathrooms":       {"properties.bathrooms", sortInt, false, func(p *models.Property) any { return p.Bathrooms }},
+	"floor":           {"properties.floor", sortInt, false, func(p *models.Property) any { return p.Floor }},
+	"year_built":      {"properties.year_built", sortInt, false, func(p *models.Property) any { return p.YearBuilt }},
+	"created_at":      {"properties.created_at", sortTime, false, func(p *models.Property) any { return p.CreatedAt }},
+	"scraped_at":      {"properties.scraped_at", sortTime, false, func(p *models.Property) any { return p.ScrapedAt }},
+	"overall_score":   {"property_factors.overall_score", sortScore, true, func(p *models.Property) any { return p.Factors.OverallScore }},
+	"crime_score":     {"COALESCE(property_factors.crime_score, 0)", sortFloat, true, func(p *models.Property) any { return p.Factors.CrimeScore }},
+	"transport_score": {"COALESCE(property_factors.transport_score, 0)", sortFloat, true, func(p *models.Property) any { return p.Factors.TransportScore }},
+	"education_score": {"COALESCE(property_factors.education_score, 0)", sortFloat, true, func(p *models.Property) any { return p.Factors.EducationScore }},
+}
+
+// cursorPrice is the cursor value of a price: the listing's own price and the
+// day whose rates convert it, which the seek condition converts with the same
+// SQL as the column so that equal prices compare equal
+type cursorPrice struct {
+	Amount    float64   `json:"a"`
+	Currency  string    `json:"c"`
+	ScrapedAt time.Time `json:"t"`
+}
+
+func cursorPriceOf(p *models.Property) any {
+	if p.NativeCurrency != "" {
+		return cursorPrice{p.NativePrice, p.NativeCurrency, p.ScrapedAt}
+	}
+	return cursorPrice{p.Price, p.Currency, p.ScrapedAt}
+}
+
+func sortColumnNames() []string {
+	names := make([]string, 0, len(sortColumns))
+	for name := range sortColumns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type sortKey struct {
+	name string
+	desc bool
+	sortColumn
+}
+
+// pageRequest is the sort order and position of one page of properties
+type pageRequest struct {
+	keys   []sortKey
+	limit  int
+	page   int   // offset paging, used when there is no cursor
+	after  []any // sort values of the last row of the previous page
+	cursor bool
+}
+
+// parsePageRequest reads sort, limit, page and cursor. The sort always ends
+// in id, so that the order is total and a cursor names exactly one row.
+func parsePageRequest(values url.Values) (*pageRequest, FilterErrors) {
+	errs := FilterErrors{}
+	p := &pageRequest{limit: defaultPageLimit, page: 1}
+
+	if raw := strings.TrimSpace(values.Get("limit")); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		switch {
+		case err != nil:
+			errs["limit"] = "must be a whole number"
+		case limit < 1:
+			errs["limit"] = "must be at least 1"
+		case limit > maxPageLimit:
+			errs["limit"] = fmt.Sprintf("must be at most %d", maxPageLimit)
+		default:
+			p.limit = limit
+		}
+	}
+
+	if raw := strings.TrimSpace(values.Get("page")); raw != "" {
+		page, err := strconv.Atoi(raw)
+		switch {
+		case err != nil:
+			errs["page"] = "must be a whole number"
+		case page < 1:
+			errs["page"] = "must be at least 1"
+		default:
+			p.page = page
+		}
+	}
+
+	keys, err := parseSort(values.Get("sort"))
+	if err != nil {
+		errs["sort"] = err.Error()
+	}
+	p.keys = keys
+
+	if raw := strings.TrimSpace(values.Get("cursor")); raw != "" && err == nil {
+		after, err := decodeCursor(raw, keys)
+		if err != nil {
+			errs["cursor"] = err.Error()
+		}
+		p.after = after
+		p.cursor = true
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return p, nil
+}
+
+// parseSort reads a comma-separated list of columns, each descending when
+// prefixed with "-"
+func parseSort(spec string) ([]sortKey, error) {
+	var keys []sortKey
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		desc := strings.HasPrefix(field, "-")
+		name := strings.TrimPrefix(field, "-")
+
+		column, ok := sortColumns[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown sort column %q (available: %s)", name, strings.Join(sortColumnNames(), ", "))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("sorts by %q twice", name)
+		}
+		seen[name] = true
+		keys = append(keys, sortKey{name, desc, column})
+	}
+
+	if !seen["id"] {
+		keys = append(keys, sortKey{"id", false, sortColumns["id"]})
+	}
+	return keys, nil
+}
+
+// sortSpec is the canonical form of the sort, which a cursor must match
+func sortSpec(keys []sortKey) string {
+	fields := make([]string, len(keys))
+	for i, key := range keys {
+		fields[i] = key.name
+		if key.desc {
+			fields[i] = "-" + key.name
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+func (p *pageRequest) needsFactors() bool {
+	for _, key := range p.keys {
+		if key.factors {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply orders the query, with prices in the filter currency and overall
+// scores with the filter weights, and selects the page, fetching one row more
+// than the limit so that the caller can tell whether another page follows
+func (p *pageRequest) Apply(query *gorm.DB, filter *PropertyFilter) *gorm.DB {
+	for _, key := range p.keys {
+		if key.kind == sortPrice {
+			query = joinPriceFactors(query, filter.Currency)
+		}
+		if key.desc {
+			query = query.Order(key.sql(filter) + " DESC")
+		} else {
+			query = query.Order(key.sql(filter))
+		}
+	}
+
+	if p.cursor {
+		condition, args := p.seekCondition(filter)
+		query = query.Where(condition, args...)
+	} else if p.page > 1 {
+		query = query.Offset((p.page - 1) * p.limit)
+	}
+	return query.Limit(p.limit + 1)
+}
+
+// seekCondition selects the rows after p.after in the sort order. With one
+// direction throughout it is a row comparison, which an index on the sort
+// columns can serve; mixed directions need the expanded form
+// (a > ?) OR (a = ? AND b < ?) OR ...
+func (p *pageRequest) seekCondition(filter *PropertyFilter) (string, []any) {
+	uniform := true
+	for _, key := range p.keys {
+		uniform = uniform && key.desc == p.keys[0].desc
+	}
+
+	if uniform {
+		exprs := make([]string, len(p.keys))
+		marks := make([]string, len(p.keys))
+		var args []any
+		for i, key := range p.keys {
+			exprs[i] = key.sql(filter)
+			var keyArgs []any
+			marks[i], keyArgs = key.valueSQL(p.after[i], filter.Currency)
+			args = append(args, keyArgs...)
+		}
+		op := ">"
+		if p.keys[0].desc {
+			op = "<"
+		}
+		return fmt.Sprintf("(%s) %s (%s)", strings.Join(exprs, ", "), op, strings.Join(marks, ", ")), args
+	}
+
+	var terms []string
+	var args []any
+	for i, key := range p.keys {
+		var parts []string
+		for j := 0; j < i; j++ {
+			mark, keyArgs := p.keys[j].valueSQL(p.after[j], filter.Currency)
+			parts = append(parts, p.keys[j].sql(filter)+" = "+mark)
+			args = append(args, keyArgs...)
+		}
+		op := " > "
+		if key.desc {
+			op = " < "
+		}
+		mark, keyArgs := key.valueSQL(p.after[i], filter.Currency)
+		parts = append(parts, key.sql(filter)+op+mark)
+		args = append(args, keyArgs...)
+		terms = append(terms, "("+strings.Join(parts, " AND ")+")")
+	}
+	return strings.Join(terms, " OR "), args
+}
+
+// sql returns the expression of the key, with prices in the filter currency
+// and overall scores with the filter weights
+func (k sortKey) sql(filter *PropertyFilter) string {
+	switch k.kind {
+	case sortPrice:
+		return convertedSQL(k.expr)
+	case sortScore:
+		return "COALESCE(" + overallScoreSQL(filter.FactorWeights()) + ", 0)"
+	}
+	return k.expr
+}
+
+// valueSQL returns the placeholder SQL and arguments of a cursor value
+func (k sortKey) valueSQL(value any, currency string) (string, []any) {
+	if price, ok := value.(cursorPrice); ok {
+		return "(SELECT CAST(? AS numeric) * " + priceFactorSQL("c.currency", "c.scraped_at", currency) +
+				" FROM (SELECT CAST(? AS text) AS currency, CAST(? AS timestamptz) AS scraped_at) c)",
+			[]any{price.Amount, price.Currency, price.ScrapedAt}
+	}
+	return "?", []any{value}
+}
+
+// pageCursor is the content of a cursor token: the sort it belongs to and the
+// sort values of the last row returned
+type pageCursor struct {
+	Sort   string            `json:"s"`
+	Values []json.RawMessage `json:"v"`
+}
+
+// nextCursor returns the token of the page after the one ending in last
+func (p *pageRequest) nextCursor(last *models.Property) string {
+	cursor := pageCursor{Sort: sortSpec(p.keys), Values: make([]json.RawMessage, len(p.keys))}
+	for i, key := range p.keys {
+		cursor.Values[i], _ = json.Marshal(key.value(last))
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(token string, keys []sortKey) ([]any, error) {
+	invalid := errors.New("is not a valid cursor")
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, invalid
+	}
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, invalid
+	}
+	if cursor.Sort != sortSpec(keys) {
+		return nil, fmt.Errorf("belongs to sort %q, not %q", cursor.Sort, sortSpec(keys))
+	}
+	if len(cursor.Values) != len(keys) {
+		return nil, invalid
+	}
+
+	values := make([]any, len(keys))
+	for i, key := range keys {
+		var err error
+		switch key.kind {
+		case sortInt:
+			var v int64
+			err = json.Unmarshal(cursor.Values[i], &v)
+			values[i] = v
+		case sortFloat, sortScore:
+			var v float64
+			err = json.Unmarshal(cursor.Values[i], &v)
+			values[i] = v
+		case sortTime:
+			var v time.Time
+			err = json.Unmarshal(cursor.Values[i], &v)
+			values[i] = v
+		case sortPrice:
+			var v cursorPrice
+			err = json.Unmarshal(cursor.Values[i], &v)
+			values[i] = v
+		}
+		if err != nil {
+			return nil, invalid
+		}
+	}
+	return values, nil
+}
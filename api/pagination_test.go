@@ -0,0 +1,133 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"pricemap-go/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestParseSort(t *testing.T) {
+	keys, err := parseSort("price, -overall_score,scraped_at")
+	require.NoError(t, err)
+	assert.Equal(t, "price,-overall_score,scraped_at,id", sortSpec(keys))
+
+	// An explicit id keeps its direction and position
+	keys, err = parseSort("-id")
+	require.NoError(t, err)
+	assert.Equal(t, "-id", sortSpec(keys))
+
+	keys, err = parseSort("")
+	require.NoError(t, err)
+	assert.Equal(t, "id", sortSpec(keys))
+
+	_, err = parseSort("price,description")
+	assert.ErrorContains(t, err, `unknown sort column "description"`)
+
+	_, err = parseSort("price,-price")
+	assert.ErrorContains(t, err, `sorts by "price" twice`)
+}
+
+func TestParsePageRequest_Errors(t *testing.T) {
+	values, _ := url.ParseQuery("limit=500&page=0&sort=bogus")
+	_, errs := parsePageRequest(values)
+	assert.Equal(t, FilterErrors{
+		"limit": "must be at most 100",
+		"page":  "must be at least 1",
+		"sort":  `unknown sort column "bogus" (available: area, bathrooms, bedrooms, created_at, crime_score, education_score, floor, id, overall_score, price, rooms, scraped_at, transport_score, year_built)`,
+	}, errs)
+
+	values, _ = url.ParseQuery("cursor=not-a-cursor")
+	_, errs = parsePageRequest(values)
+	assert.Equal(t, FilterErrors{"cursor": "is not a valid cursor"}, errs)
+}
+
+func TestPageCursor_RoundTrip(t *testing.T) {
+	values, _ := url.ParseQuery("sort=-price,scraped_at&limit=20")
+	page, errs := parsePageRequest(values)
+	require.Nil(t, errs)
+	assert.Equal(t, 20, page.limit)
+
+	scraped := time.Date(2024, 3, 1, 12, 30, 0, 123456000, time.UTC)
+	cursor := page.nextCursor(&models.Property{ID: 42, Price: 125000.5, Currency: "RUB", ScrapedAt: scraped})
+
+	values.Set("cursor", cursor)
+	next, errs := parsePageRequest(values)
+	require.Nil(t, errs)
+	assert.True(t, next.cursor)
+	assert.Equal(t, []any{cursorPrice{125000.5, "RUB", scraped}, scraped, int64(42)}, next.after)
+
+	// Prices are kept in the listing's own currency after conversion
+	converted := &models.Property{ID: 42, Price: 1358.7, Currency: "USD", NativePrice: 125000.5, NativeCurrency: "RUB", ScrapedAt: scraped}
+	assert.Equal(t, cursorPrice{125000.5, "RUB", scraped}, cursorPriceOf(converted))
+
+	// A cursor only continues the sort it was issued for
+	values.Set("sort", "price")
+	_, errs = parsePageRequest(values)
+	assert.Equal(t, FilterErrors{"cursor": `belongs to sort "-price,scraped_at,id", not "price,id"`}, errs)
+}
+
+func TestPageRequest_Apply(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	require.NoError(t, err)
+
+	pageSQL := func(query string, last *models.Property) (string, []interface{}) {
+		values, _ := url.ParseQuery(query)
+		page, errs := parsePageRequest(values)
+		require.Nil(t, errs)
+		if last != nil {
+			values.Set("cursor", page.nextCursor(last))
+			page, errs = parsePageRequest(values)
+			require.Nil(t, errs)
+		}
+		stmt := page.Apply(db.Model(&models.Property{}), &PropertyFilter{Currency: "EUR"}).Find(&[]models.Property{}).Statement
+		return stmt.SQL.String(), stmt.Vars
+	}
+
+	// Prices sort in the requested currency
+	price := convertedSQL("properties.price")
+	sql, vars := pageSQL("sort=-price&page=3&limit=10", nil)
+	assert.Contains(t, sql, "LEFT JOIN ("+priceFactorsSQL("EUR")+") AS price_factors")
+	assert.Contains(t, sql, "ORDER BY "+price+" DESC,properties.id LIMIT 11 OFFSET 20")
+	assert.Empty(t, vars)
+
+	// One direction: a row comparison. The cursor price is converted by the
+	// same SQL as the column.
+	scraped := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	sql, vars = pageSQL("sort=area,price&limit=10", &models.Property{ID: 7, Price: 100, Currency: "GBP", Area: 50, ScrapedAt: scraped})
+	assert.Contains(t, sql, "(properties.area, "+price+", properties.id) > "+
+		"($1, (SELECT CAST($2 AS numeric) * "+priceFactorSQL("c.currency", "c.scraped_at", "EUR")+
+		" FROM (SELECT CAST($3 AS text) AS currency, CAST($4 AS timestamptz) AS scraped_at) c), $5)")
+	assert.Contains(t, sql, "LIMIT 11")
+	assert.NotContains(t, sql, "OFFSET")
+	assert.Equal(t, []interface{}{50.0, 100.0, "GBP", scraped, int64(7)}, vars)
+
+	// Mixed directions: the expanded form
+	sql, vars = pageSQL("sort=-overall_score&limit=10", &models.Property{ID: 7, Factors: models.PropertyFactors{OverallScore: 80}})
+	assert.Contains(t, sql, "((COALESCE(property_factors.overall_score, 0) < $1) OR "+
+		"(COALESCE(property_factors.overall_score, 0) = $2 AND properties.id > $3))")
+	assert.Equal(t, []interface{}{80.0, 80.0, int64(7)}, vars)
+}
+
+func TestHandler_GetProperties_InvalidPagination(t *testing.T) {
+	router := setupTestRouter()
+
+	req, _ := http.NewRequest("GET", "/api/v1/properties?sort=-description&limit=1000", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"limit":"must be at most 100"`)
+	assert.Contains(t, w.Body.String(), `unknown sort column \"description\"`)
+}
@@ -2,12 +2,15 @@ package api
 
 import (
 	"github.com/gin-gonic/gin"
+
+	"pricemap-go/metrics"
 )
 
 func SetupRouter() *gin.Engine {
 	router := gin.Default()
 
 	// Middleware
+	router.Use(MetricsMiddleware())
 	router.Use(LoggerMiddleware())
 	router.Use(CORSMiddleware())
 	router.Use(RateLimitMiddleware())
@@ -19,6 +22,9 @@ func SetupRouter() *gin.Engine {
 	router.GET("/readiness", ReadinessHandler)
 	router.GET("/liveness", LivenessHandler)
 
+	// Prometheus/OpenMetrics exposition
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
 	// Static files (frontend)
 	router.Static("/web", "./web")
 	router.StaticFile("/", "./web/index.html")
@@ -33,9 +39,20 @@ func SetupRouter() *gin.Engine {
 	api := router.Group("/api/v1")
 	{
 		api.GET("/heatmap", handler.GetHeatmapData)
+		api.GET("/heatmap.geojson", handler.GetHeatmapGeoJSON)
 		api.GET("/properties", handler.GetProperties)
+		api.GET("/properties.geojson", handler.GetPropertiesGeoJSON)
+		api.POST("/properties/search", handler.GetProperties)
+		api.GET("/properties/export", handler.ExportProperties)
+		api.POST("/properties/export", handler.ExportProperties)
 		api.GET("/properties/:id", handler.GetPropertyDetails)
+		api.GET("/properties/:id/history", handler.GetPropertyHistory)
+		api.GET("/properties/:id/duplicates", handler.GetPropertyDuplicates)
+		api.GET("/canonical/:id", handler.GetCanonicalProperty)
+		api.GET("/tiles/:z/:x/:y", handler.GetTile) // :y is "{y}.mvt"
 		api.GET("/stats", handler.GetStats)
+		api.GET("/stats/summary", handler.GetStatsSummary)
+		api.GET("/weight-profiles", handler.GetWeightProfiles)
 		api.GET("/metrics", handler.GetMetrics)
 		api.GET("/metrics/parser/:parser", handler.GetParserMetrics)
 	}
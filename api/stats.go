@@ -0,0 +1,208 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxSummaryGroups bounds the groups of a summary, largest first
+const maxSummaryGroups = 1000
+
+// summaryGroups whitelists the group_by parameter of /stats/summary
+var summaryGroups = map[string]string{
+	"city":     "properties.city",
+	"district": "properties.district",
+	"type":     "properties.type",
+	"rooms":    "properties.rooms",
+	"source":   "properties.source",
+}
+
+// summaryPercentiles are the points of a Distribution besides the mean
+var summaryPercentiles = []struct {
+	name     string
+	fraction float64
+}{
+	{"p10", 0.1},
+	{"p25", 0.25},
+	{"median", 0.5},
+	{"p75", 0.75},
+	{"p90", 0.9},
+}
+
+// Distribution summarises the values of one measure in a group
+type Distribution struct {
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	P10    float64 `json:"p10"`
+	P25    float64 `json:"p25"`
+	P75    float64 `json:"p75"`
+	P90    float64 `json:"p90"`
+}
+
+// FactorScores are mean factor scores over the rated properties of a group,
+// null when none of them is rated
+type FactorScores struct {
+	Overall        *float64 `json:"overall"`
+	Crime          *float64 `json:"crime"`
+	Transport      *float64 `json:"transport"`
+	Education      *float64 `json:"education"`
+	Infrastructure *float64 `json:"infrastructure"`
+}
+
+// GroupSummary is the summary of the properties sharing a group_by value
+type GroupSummary struct {
+	Key         any           `json:"key"` // null without group_by
+	Count       int64         `json:"count"`
+	Price       Distribution  `json:"price"`
+	PricePerSqm *Distribution `json:"price_per_sqm"` // over properties with a known area, null without any
+	Scores      FactorScores  `json:"scores"`
+}
+
+// summaryRow is a group as the summary query returns it
+type summaryRow struct {
+	Key         *string
+	Count       int64
+	TotalGroups int64
+
+	PriceMean, PriceMedian, PriceP10, PriceP25, PriceP75, PriceP90 float64
+
+	PerSqmCount                                                          int64
+	PerSqmMean, PerSqmMedian, PerSqmP10, PerSqmP25, PerSqmP75, PerSqmP90 float64
+
+	OverallScore, CrimeScore, TransportScore, EducationScore, InfrastructureScore *float64
+}
+
+// GetStatsSummary returns the price and price per m² distributions and mean
+// factor scores of the properties matching the filters, optionally per
+// group_by value, largest groups first
+func (h *Handler) GetStatsSummary(c *gin.Context) {
+	filter := bindPropertyFilter(c)
+	if filter == nil {
+		return
+	}
+
+	groupBy := strings.TrimSpace(c.Query("group_by"))
+	if _, ok := summaryGroups[groupBy]; !ok && groupBy != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Invalid group_by",
+			"fields": FilterErrors{"group_by": "must be one of " + strings.Join(summaryGroupNames(), ", ")},
+		})
+		return
+	}
+
+	var rows []summaryRow
+	if err := statsSummaryQuery(uniqueListings(PropertiesQuery(filter)), filter, groupBy).Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	groups := make([]GroupSummary, 0, len(rows))
+	var total, totalGroups int64
+	for _, row := range rows {
+		if row.Count == 0 {
+			continue // no property matched, and there is no group_by
+		}
+		groups = append(groups, row.summary(groupBy))
+		total += row.Count
+		totalGroups = row.TotalGroups
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"group_by":         groupBy,
+		"groups":           groups,
+		"count":            total,
+		"truncated":        totalGroups > int64(len(groups)),
+		"current_exchange": currentExchangeRate(c, filter.Currency),
+	})
+}
+
+func summaryGroupNames() []string {
+	names := make([]string, 0, len(summaryGroups))
+	for name := range summaryGroups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// statsSummaryQuery aggregates the properties selected by query into one
+// summaryRow per groupBy value, or a single one without groupBy. Prices are
+// converted into the filter currency once per property, in a subquery.
+func statsSummaryQuery(query *gorm.DB, filter *PropertyFilter, groupBy string) *gorm.DB {
+	if !filter.HasScoreFilters() {
+		query = query.Joins("LEFT JOIN property_factors ON property_factors.property_id = properties.id")
+	}
+
+	key := "NULL"
+	if column, ok := summaryGroups[groupBy]; ok {
+		key = "CAST(" + column + " AS text)"
+	}
+	properties := joinPriceFactors(query, filter.Currency).Select(key + ` AS key,
+		` + convertedSQL("properties.price") + ` AS price,
+		` + convertedSQL("properties.price_per_sqm") + ` AS price_per_sqm,
+		NULLIF(` + overallScoreSQL(filter.FactorWeights()) + `, 0) AS overall_score,
+		NULLIF(property_factors.crime_score, 0) AS crime_score,
+		NULLIF(property_factors.transport_score, 0) AS transport_score,
+		NULLIF(property_factors.education_score, 0) AS education_score,
+		NULLIF(property_factors.infrastructure_score, 0) AS infrastructure_score`)
+
+	summary := query.Session(&gorm.Session{NewDB: true}).
+		Table("(?) AS s", properties).
+		Select(`key, COUNT(*) AS count, COUNT(*) OVER () AS total_groups,
+			` + distributionSQL("price", "price") + `,
+			COUNT(price_per_sqm) AS per_sqm_count,
+			` + distributionSQL("price_per_sqm", "per_sqm") + `,
+			AVG(overall_score) AS overall_score,
+			AVG(crime_score) AS crime_score,
+			AVG(transport_score) AS transport_score,
+			AVG(education_score) AS education_score,
+			AVG(infrastructure_score) AS infrastructure_score`)
+	if groupBy == "" {
+		return summary
+	}
+	return summary.Group("key").Order("count DESC, key").Limit(maxSummaryGroups)
+}
+
+// distributionSQL selects the mean and percentiles of column as prefix_mean,
+// prefix_median, prefix_p10 and so on
+func distributionSQL(column, prefix string) string {
+	fields := []string{fmt.Sprintf("COALESCE(AVG(%s), 0) AS %s_mean", column, prefix)}
+	for _, p := range summaryPercentiles {
+		fields = append(fields, fmt.Sprintf("COALESCE(PERCENTILE_CONT(%s) WITHIN GROUP (ORDER BY %s), 0) AS %s_%s",
+			strconv.FormatFloat(p.fraction, 'f', -1, 64), column, prefix, p.name))
+	}
+	return strings.Join(fields, ", ")
+}
+
+func (r *summaryRow) summary(groupBy string) GroupSummary {
+	group := GroupSummary{
+		Count: r.Count,
+		Price: Distribution{r.PriceMean, r.PriceMedian, r.PriceP10, r.PriceP25, r.PriceP75, r.PriceP90},
+		Scores: FactorScores{
+			Overall:        r.OverallScore,
+			Crime:          r.CrimeScore,
+			Transport:      r.TransportScore,
+			Education:      r.EducationScore,
+			Infrastructure: r.InfrastructureScore,
+		},
+	}
+	if r.PerSqmCount > 0 {
+		group.PricePerSqm = &Distribution{r.PerSqmMean, r.PerSqmMedian, r.PerSqmP10, r.PerSqmP25, r.PerSqmP75, r.PerSqmP90}
+	}
+
+	if r.Key != nil {
+		group.Key = *r.Key
+		if groupBy == "rooms" {
+			if rooms, err := strconv.Atoi(*r.Key); err == nil {
+				group.Key = rooms
+			}
+		}
+	}
+	return group
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"pricemap-go/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestStatsSummaryQuery(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	require.NoError(t, err)
+
+	values, _ := url.ParseQuery("city=Moscow&currency=EUR")
+	filter, err := ParsePropertyFilter(values)
+	require.NoError(t, err)
+
+	query := func() *gorm.DB {
+		return filter.Apply(db.Model(&models.Property{}).Where("is_active = ?", true))
+	}
+	stmt := statsSummaryQuery(query(), filter, "rooms").Find(&[]summaryRow{}).Statement
+	sql := stmt.SQL.String()
+
+	// Prices are converted once per property, in the subquery
+	assert.Contains(t, sql, `SELECT key, COUNT(*) AS count, COUNT(*) OVER () AS total_groups`)
+	assert.Contains(t, sql, `FROM (SELECT CAST(properties.rooms AS text) AS key,`)
+	assert.Contains(t, sql, convertedSQL("properties.price")+" AS price,")
+	assert.Equal(t, 1, strings.Count(sql, "AS price_factors"))
+	assert.Contains(t, sql, "LEFT JOIN property_factors ON property_factors.property_id = properties.id")
+	assert.Contains(t, sql, "properties.city = $2")
+	assert.Contains(t, sql, `) AS s GROUP BY "key" ORDER BY count DESC, key LIMIT 1000`)
+	assert.Contains(t, sql, "COALESCE(PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY price_per_sqm), 0) AS per_sqm_p90")
+	assert.Equal(t, true, stmt.Vars[0])
+	assert.Equal(t, "Moscow", stmt.Vars[1])
+
+	// Without group_by everything is one group
+	sql = statsSummaryQuery(query(), filter, "").Find(&[]summaryRow{}).Statement.SQL.String()
+	assert.Contains(t, sql, "SELECT NULL AS key,")
+	assert.NotContains(t, sql, "GROUP BY")
+}
+
+func TestDistributionSQL(t *testing.T) {
+	assert.Equal(t, "COALESCE(AVG(price), 0) AS price_mean, "+
+		"COALESCE(PERCENTILE_CONT(0.1) WITHIN GROUP (ORDER BY price), 0) AS price_p10, "+
+		"COALESCE(PERCENTILE_CONT(0.25) WITHIN GROUP (ORDER BY price), 0) AS price_p25, "+
+		"COALESCE(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY price), 0) AS price_median, "+
+		"COALESCE(PERCENTILE_CONT(0.75) WITHIN GROUP (ORDER BY price), 0) AS price_p75, "+
+		"COALESCE(PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY price), 0) AS price_p90",
+		distributionSQL("price", "price"))
+}
+
+func TestSummaryRow_Summary(t *testing.T) {
+	key := "3"
+	score := 72.5
+	row := summaryRow{
+		Key: &key, Count: 12,
+		PriceMean: 410000, PriceMedian: 395000, PriceP10: 250000, PriceP25: 320000, PriceP75: 480000, PriceP90: 560000,
+		OverallScore: &score,
+	}
+
+	group := row.summary("rooms")
+	assert.Equal(t, 3, group.Key)
+	assert.Equal(t, Distribution{410000, 395000, 250000, 320000, 480000, 560000}, group.Price)
+	assert.Nil(t, group.PricePerSqm, "no property of the group has a known area")
+	assert.Equal(t, &score, group.Scores.Overall)
+	assert.Nil(t, group.Scores.Crime)
+
+	row.PerSqmCount, row.PerSqmMedian = 10, 5100
+	group = row.summary("city")
+	assert.Equal(t, "3", group.Key)
+	require.NotNil(t, group.PricePerSqm)
+	assert.Equal(t, 5100.0, group.PricePerSqm.Median)
+}
+
+func TestHandler_StatsSummary_InvalidGroupBy(t *testing.T) {
+	router := setupTestRouter()
+
+	req, _ := http.NewRequest("GET", "/api/v1/stats/summary?group_by=country", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"group_by":"must be one of city, district, rooms, source, type"`)
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"pricemap-go/models"
+	"pricemap-go/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The overall score of a response is the stored one, weighted with
+// models.DefaultFactorWeights, unless profile= names a weighting profile or
+// weights= lists the weights, e.g. crime:0.4,transport:0.3,education:0.1,
+// infrastructure:0.2. It is then recomputed from the stored factor scores,
+// in SQL for filters, sorting and aggregates and in Go for the properties
+// returned, with the same rounding.
+
+// GetWeightProfiles lists the weighting profiles profile= can name
+func (h *Handler) GetWeightProfiles(c *gin.Context) {
+	profiles, err := services.LoadWeightProfiles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":    profiles,
+		"count":   len(profiles),
+		"default": models.DefaultWeightProfile,
+	})
+}
+
+// parseWeights reads comma-separated factor:weight pairs. Factors left out
+// weigh nothing.
+func parseWeights(spec string) (*models.FactorWeights, error) {
+	weights := &models.FactorWeights{}
+	seen := make(map[string]bool)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		factor, raw, found := strings.Cut(pair, ":")
+		if !found {
+			return nil, fmt.Errorf("must be factor:weight pairs, e.g. %s", defaultWeightsSpec())
+		}
+		factor = strings.TrimSpace(factor)
+
+		weight, ok := weights.Weight(factor)
+		if !ok {
+			return nil, fmt.Errorf("unknown factor %q (available: %s)", factor, strings.Join(models.Factors, ", "))
+		}
+		if seen[factor] {
+			return nil, fmt.Errorf("weighs %q twice", factor)
+		}
+		seen[factor] = true
+
+		value, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil {
+			return nil, fmt.Errorf("weight of %s must be a number", factor)
+		}
+		*weight = value
+	}
+
+	if len(seen) == 0 {
+		return nil, fmt.Errorf("must be factor:weight pairs, e.g. %s", defaultWeightsSpec())
+	}
+	if err := weights.Validate(); err != nil {
+		return nil, err
+	}
+	return weights, nil
+}
+
+// defaultWeightsSpec writes the default weights as a weights parameter
+func defaultWeightsSpec() string {
+	weights := models.DefaultFactorWeights
+	pairs := make([]string, len(models.Factors))
+	for i, factor := range models.Factors {
+		weight, _ := weights.Weight(factor)
+		pairs[i] = factor + ":" + strconv.FormatFloat(*weight, 'f', -1, 64)
+	}
+	return strings.Join(pairs, ",")
+}
+
+// resolveWeights returns the weights a profile name or weights parameter
+// asks for, recording what is wrong with them in errs. Nil means the default
+// weights, whose scores are stored.
+func resolveWeights(profile, spec string, errs FilterErrors) *models.FactorWeights {
+	var weights *models.FactorWeights
+	switch {
+	case profile != "" && spec != "":
+		errs["weights"] = "cannot be combined with profile"
+		return nil
+	case spec != "":
+		parsed, err := parseWeights(spec)
+		if err != nil {
+			errs["weights"] = err.Error()
+			return nil
+		}
+		weights = parsed
+	case profile != "":
+		found, err := services.FindWeightProfile(profile)
+		if err != nil {
+			log.Printf("Error loading weight profile %s: %v", profile, err)
+			errs["profile"] = "could not be loaded"
+			return nil
+		}
+		if found == nil {
+			errs["profile"] = "is not a weight profile; GET /api/v1/weight-profiles lists them"
+			return nil
+		}
+		weights = &found.FactorWeights
+	}
+
+	if weights == nil || *weights == models.DefaultFactorWeights {
+		return nil
+	}
+	return weights
+}
+
+// bindWeights reads profile and weights for endpoints that take no filters.
+// False means the response, 400 with the invalid fields, has been written.
+func bindWeights(c *gin.Context) (*models.FactorWeights, bool) {
+	errs := FilterErrors{}
+	weights := resolveWeights(strings.TrimSpace(c.Query("profile")), strings.TrimSpace(c.Query("weights")), errs)
+	if len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid filters", "fields": errs})
+		return nil, false
+	}
+	return weights, true
+}
+
+// overallScoreSQL returns the SQL of the overall score with weights: the
+// stored column for the default weights, or the weighted sum of the factor
+// scores rounded like FactorWeights.Score
+func overallScoreSQL(weights *models.FactorWeights) string {
+	if weights == nil {
+		return "property_factors.overall_score"
+	}
+
+	var terms []string
+	for _, factor := range models.Factors {
+		weight, _ := weights.Weight(factor)
+		if *weight == 0 {
+			continue
+		}
+		terms = append(terms, fmt.Sprintf("COALESCE(property_factors.%s_score, 0) * %s",
+			factor, strconv.FormatFloat(*weight, 'f', -1, 64)))
+	}
+	return "CAST(ROUND(CAST(" + strings.Join(terms, " + ") + " AS numeric), 2) AS double precision)"
+}
+
+// weighProperty recomputes the overall score of p with weights. Properties
+// without factors keep their zero scores.
+func weighProperty(p *models.Property, weights *models.FactorWeights) {
+	if weights == nil || p.Factors.ID == 0 {
+		return
+	}
+	p.Factors.OverallScore = weights.Score(&p.Factors)
+}
+
+func weighProperties(properties []models.Property, weights *models.FactorWeights) {
+	for i := range properties {
+		weighProperty(&properties[i], weights)
+	}
+}
+
+// ScoreExporter recomputes the overall score of every property with weights
+// before passing it on to exporter. The export command shares it with the API.
+func ScoreExporter(exporter services.PropertyExporter, weights *models.FactorWeights) services.PropertyExporter {
+	if weights == nil {
+		return exporter
+	}
+	return &scoreExporter{PropertyExporter: exporter, weights: weights}
+}
+
+type scoreExporter struct {
+	services.PropertyExporter
+	weights *models.FactorWeights
+}
+
+func (e *scoreExporter) Write(p *models.Property) error {
+	weighProperty(p, e.weights)
+	return e.PropertyExporter.Write(p)
+}
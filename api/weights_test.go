@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"pricemap-go/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestParseWeights(t *testing.T) {
+	weights, err := parseWeights("crime:0.4, transport:0.3,education:0.1,infrastructure:0.2")
+	require.NoError(t, err)
+	assert.Equal(t, models.FactorWeights{Crime: 0.4, Transport: 0.3, Education: 0.1, Infrastructure: 0.2}, *weights)
+
+	// Factors left out weigh nothing
+	weights, err = parseWeights("transport:0.5,infrastructure:0.5")
+	require.NoError(t, err)
+	assert.Equal(t, models.FactorWeights{Transport: 0.5, Infrastructure: 0.5}, *weights)
+
+	for spec, want := range map[string]string{
+		"crime:0.5,transport:0.4":  "weights must sum to 1, not 0.9",
+		"crime:1.2,transport:-0.2": "crime weight must be between 0 and 1",
+		"crime:0.5,parking:0.5":    `unknown factor "parking" (available: crime, transport, education, infrastructure)`,
+		"crime:0.5,crime:0.5":      `weighs "crime" twice`,
+		"crime:high":               "weight of crime must be a number",
+		"family":                   "must be factor:weight pairs, e.g. crime:0.25,transport:0.25,education:0.2,infrastructure:0.3",
+	} {
+		_, err := parseWeights(spec)
+		if assert.Error(t, err, spec) {
+			assert.Equal(t, want, err.Error(), spec)
+		}
+	}
+}
+
+func TestResolveWeights(t *testing.T) {
+	errs := FilterErrors{}
+	weights := resolveWeights("family", "", errs)
+	assert.Empty(t, errs)
+	require.NotNil(t, weights)
+	assert.Equal(t, 0.35, weights.Education)
+
+	// The default weights are those of the stored score
+	assert.Nil(t, resolveWeights(models.DefaultWeightProfile, "", errs))
+	assert.Nil(t, resolveWeights("", "crime:0.25,transport:0.25,education:0.2,infrastructure:0.3", errs))
+	assert.Nil(t, resolveWeights("", "", errs))
+	assert.Empty(t, errs)
+
+	resolveWeights("pensioner", "", errs)
+	assert.Contains(t, errs["profile"], "is not a weight profile")
+
+	errs = FilterErrors{}
+	resolveWeights("family", "crime:1", errs)
+	assert.Equal(t, FilterErrors{"weights": "cannot be combined with profile"}, errs)
+}
+
+func TestOverallScoreSQL(t *testing.T) {
+	assert.Equal(t, "property_factors.overall_score", overallScoreSQL(nil))
+	assert.Equal(t, "CAST(ROUND(CAST(COALESCE(property_factors.crime_score, 0) * 0.6 + "+
+		"COALESCE(property_factors.infrastructure_score, 0) * 0.4 AS numeric), 2) AS double precision)",
+		overallScoreSQL(&models.FactorWeights{Crime: 0.6, Infrastructure: 0.4}))
+}
+
+func TestWeightedQueries(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	require.NoError(t, err)
+
+	values, _ := url.ParseQuery("weights=crime:0.6,infrastructure:0.4&score_min=70&sort=-overall_score")
+	filter, err := ParsePropertyFilter(values)
+	require.NoError(t, err)
+	page, errs := parsePageRequest(values)
+	require.Nil(t, errs)
+	score := overallScoreSQL(filter.FactorWeights())
+
+	// Filters and sorting use the recomputed score
+	sql := page.Apply(filter.Apply(db.Model(&models.Property{})), filter).Find(&[]models.Property{}).Statement.SQL.String()
+	assert.Contains(t, sql, score+" >= $")
+	assert.Contains(t, sql, "ORDER BY COALESCE("+score+", 0) DESC,properties.id")
+
+	// So does the summary
+	sql = statsSummaryQuery(filter.Apply(db.Model(&models.Property{})), filter, "").Find(&[]summaryRow{}).Statement.SQL.String()
+	assert.Contains(t, sql, "NULLIF("+score+", 0) AS overall_score")
+}
+
+func TestWeighProperty(t *testing.T) {
+	weights := &models.FactorWeights{Crime: 0.6, Infrastructure: 0.4}
+
+	p := &models.Property{Factors: models.PropertyFactors{ID: 1, CrimeScore: 90, TransportScore: 10, InfrastructureScore: 60, OverallScore: 50}}
+	weighProperty(p, weights)
+	assert.Equal(t, 78.0, p.Factors.OverallScore)
+
+	// Without factors the score stays 0, as the SQL coalesces it
+	p = &models.Property{}
+	weighProperty(p, weights)
+	assert.Equal(t, 0.0, p.Factors.OverallScore)
+}
+
+func TestHandler_GetWeightProfiles(t *testing.T) {
+	router := setupTestRouter()
+
+	req, _ := http.NewRequest("GET", "/api/v1/weight-profiles", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data    []models.WeightProfile `json:"data"`
+		Default string                 `json:"default"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, models.DefaultWeightProfile, response.Default)
+	assert.Len(t, response.Data, len(models.BuiltinWeightProfiles))
+	assert.Equal(t, models.DefaultFactorWeights, response.Data[0].FactorWeights)
+}
+
+func TestHandler_InvalidWeights(t *testing.T) {
+	router := setupTestRouter()
+
+	for url, field := range map[string]string{
+		"/api/v1/properties?weights=crime:0.5":                 "weights",
+		"/api/v1/heatmap?profile=pensioner":                    "profile",
+		"/api/v1/properties/1?weights=crime:2":                 "weights",
+		"/api/v1/stats/summary?profile=family&weights=crime:1": "weights",
+	} {
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+		assert.Contains(t, w.Body.String(), `"`+field+`":`, url)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"pricemap-go/config"
+	"pricemap-go/database"
+	"pricemap-go/services"
+)
+
+func main() {
+	city := flag.String("city", "", "city the incidents belong to")
+	format := flag.String("format", services.CrimeFormatCSV, "file format: "+strings.Join(services.CrimeFormats, ", "))
+	idColumn := flag.String("id-column", "", "column of the incident ID (overrides the format's)")
+	categoryColumn := flag.String("category-column", "", "column of the offence or category (overrides the format's)")
+	dateColumn := flag.String("date-column", "", "column of the date (overrides the format's)")
+	latColumn := flag.String("lat-column", "", "column of the latitude (overrides the format's)")
+	lngColumn := flag.String("lng-column", "", "column of the longitude (overrides the format's)")
+	dateFormat := flag.String("date-format", "", "Go time layout of the date, e.g. 02.01.2006 (tried before the format's)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: crime --city=NAME [--format=FORMAT] [column flags] incidents.csv|archive.zip\n\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "Imports the crime incidents of an open-data file: NYPD complaint data (nyc), Chicago crimes\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "(chicago), data.police.uk street-level CSVs or archives (uk-police), or any CSV with location,\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "category and date columns (csv). Crime scores calculated afterwards use them.\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if strings.TrimSpace(*city) == "" || flag.NArg() != 1 {
+		flag.Usage()
+		log.Fatal("--city and one file are required")
+	}
+
+	columns, err := services.CrimeColumnsFor(*format)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, override := range []struct {
+		column string
+		field  *[]string
+	}{
+		{*idColumn, &columns.ID},
+		{*categoryColumn, &columns.Category},
+		{*dateColumn, &columns.Date},
+		{*latColumn, &columns.Latitude},
+		{*lngColumn, &columns.Longitude},
+	} {
+		if column := strings.TrimSpace(override.column); column != "" {
+			*override.field = []string{column}
+		}
+	}
+	if *dateFormat != "" {
+		columns.DateLayouts = append([]string{*dateFormat}, columns.DateLayouts...)
+	}
+
+	// Load configuration
+	config.Load()
+
+	// Connect to database
+	if err := database.Connect(); err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	// Run migrations
+	if err := database.Migrate(); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	count, err := services.ImportCrimes(context.Background(), strings.TrimSpace(*city), *format, columns, flag.Arg(0))
+	if err != nil {
+		log.Fatalf("Failed to import %s: %v", flag.Arg(0), err)
+	}
+	log.Printf("Imported %d crime incidents of %s from %s", count, *city, flag.Arg(0))
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"pricemap-go/api"
+	"pricemap-go/config"
+	"pricemap-go/database"
+	"pricemap-go/services"
+
+	"gorm.io/gorm/logger"
+)
+
+func main() {
+	format := flag.String("format", services.ExportCSV, "output format: "+strings.Join(services.ExportFormats, ", "))
+	columns := flag.String("columns", "", "comma-separated columns to export (default: all)")
+	output := flag.String("output", "-", "output file, - for stdout")
+	listColumns := flag.Bool("list-columns", false, "list exportable columns and exit")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: export [flags] [filter=value ...]\n\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "Filters are those of GET /api/v1/properties, e.g. city=Moscow price_max=500000 score_min=60.\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "Prices are converted into currency=CODE (default USD), and overall scores are\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "weighted by profile=NAME or weights=crime:0.4,transport:0.3,... when given.\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *listColumns {
+		fmt.Println(strings.Join(services.ExportColumnNames(), "\n"))
+		return
+	}
+
+	values, err := parseFilters(flag.Args())
+	if err != nil {
+		log.Fatalf("Invalid filter: %v", err)
+	}
+	selected, err := services.ParseExportColumns(*columns)
+	if err != nil {
+		log.Fatalf("Invalid --columns: %v", err)
+	}
+	if !slices.Contains(services.ExportFormats, *format) {
+		log.Fatalf("Invalid --format %q (available: %s)", *format, strings.Join(services.ExportFormats, ", "))
+	}
+
+	// Load configuration
+	config.Load()
+
+	// Connect to database
+	if err := database.Connect(); err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	// The default GORM logger prints every query to stdout, where the export goes
+	database.DB.Logger = logger.New(log.New(os.Stderr, "\r\n", log.LstdFlags), logger.Config{
+		SlowThreshold: 5 * time.Second,
+		LogLevel:      logger.Warn,
+	})
+
+	// Parsed once connected, so that currency= knows the imported exchange rates
+	filter, err := api.ParsePropertyFilter(values)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	out := os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			log.Fatalf("Failed to create output: %v", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	buffered := bufio.NewWriter(out)
+	exporter, err := services.NewPropertyExporter(buffered, *format, selected)
+	if err != nil {
+		log.Fatalf("Failed to start export: %v", err)
+	}
+
+	exporter = api.ScoreExporter(api.CurrencyExporter(exporter, filter.Currency), filter.FactorWeights())
+
+	exported, err := services.ExportProperties(api.PropertiesQuery(filter), exporter)
+	if err != nil {
+		log.Fatalf("Export failed after %d properties: %v", exported, err)
+	}
+	if err := exporter.Close(); err != nil {
+		log.Fatalf("Failed to finish export: %v", err)
+	}
+	if err := buffered.Flush(); err != nil {
+		log.Fatalf("Failed to write export: %v", err)
+	}
+
+	log.Printf("Exported %d properties as %s", exported, *format)
+}
+
+// parseFilters turns key=value arguments into the query parameters the API takes
+func parseFilters(args []string) (url.Values, error) {
+	filters := url.Values{}
+	for _, arg := range args {
+		key, value, found := strings.Cut(arg, "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("%q is not in filter=value form", arg)
+		}
+		filters.Add(key, value)
+	}
+	return filters, nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"pricemap-go/config"
+	"pricemap-go/database"
+	"pricemap-go/services"
+)
+
+func main() {
+	city := flag.String("city", "", "city the feed serves; its stored stops are replaced")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: gtfs --city=NAME feed.zip|URL\n\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "Imports the stops of a GTFS feed with their transit modes and departures per day.\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "Transport scores of properties calculated afterwards use them.\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if strings.TrimSpace(*city) == "" || flag.NArg() != 1 {
+		flag.Usage()
+		log.Fatal("--city and one feed are required")
+	}
+
+	// Load configuration
+	config.Load()
+
+	// Connect to database
+	if err := database.Connect(); err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	// Run migrations
+	if err := database.Migrate(); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	count, err := services.ImportGTFS(context.Background(), strings.TrimSpace(*city), flag.Arg(0))
+	if err != nil {
+		log.Fatalf("Failed to import %s: %v", flag.Arg(0), err)
+	}
+	log.Printf("Imported %d transit stops of %s from %s", count, *city, flag.Arg(0))
+}
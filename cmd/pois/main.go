@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"pricemap-go/config"
+	"pricemap-go/database"
+	"pricemap-go/services"
+)
+
+func main() {
+	city := flag.String("city", "", "city the extract covers; its stored points of interest are replaced")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: pois --city=NAME extract.osm.pbf|overpass.json\n\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "Imports the shops, restaurants, parks, healthcare, schools and services of an\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "OpenStreetMap extract. Infrastructure scores and walkability calculated afterwards use them.\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if strings.TrimSpace(*city) == "" || flag.NArg() != 1 {
+		flag.Usage()
+		log.Fatal("--city and one extract are required")
+	}
+
+	// Load configuration
+	config.Load()
+
+	// Connect to database
+	if err := database.Connect(); err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	// Run migrations
+	if err := database.Migrate(); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	count, err := services.ImportPOIs(context.Background(), strings.TrimSpace(*city), flag.Arg(0))
+	if err != nil {
+		log.Fatalf("Failed to import %s: %v", flag.Arg(0), err)
+	}
+	log.Printf("Imported %d points of interest of %s from %s", count, *city, flag.Arg(0))
+}
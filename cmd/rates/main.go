@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"pricemap-go/config"
+	"pricemap-go/database"
+	"pricemap-go/models"
+	"pricemap-go/services"
+)
+
+func main() {
+	source := flag.String("source", "", "rate source: "+strings.Join(services.RateSources, ", "))
+	location := flag.String("url", "", "URL to fetch the rates from (default: ECB_RATES_URL or CBR_RATES_URL)")
+	since := flag.String("since", "", "with --source=cbr, fetch the rates of every day from this date (YYYY-MM-DD) to today")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: rates --source=ecb|cbr [flags] [file ...]\n\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "Imports exchange rates from rate files, or from the source's URL when no files are given.\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "For the ECB history use --url=https://www.ecb.europa.eu/stats/eurofxref/eurofxref-hist.xml.\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if !slices.Contains(services.RateSources, *source) {
+		log.Fatalf("Invalid --source %q (available: %s)", *source, strings.Join(services.RateSources, ", "))
+	}
+
+	// Load configuration
+	config.Load()
+
+	locations := flag.Args()
+	if len(locations) == 0 {
+		base := *location
+		if base == "" {
+			base = services.ExchangeRatesURL(*source)
+		}
+		locations = []string{base}
+
+		if *since != "" {
+			if *source != models.RateSourceCBR {
+				log.Fatalf("--since only applies to --source=%s", models.RateSourceCBR)
+			}
+			from, err := time.Parse("2006-01-02", *since)
+			if err != nil {
+				log.Fatalf("Invalid --since %q: %v", *since, err)
+			}
+			locations = nil
+			for day := from; !day.After(time.Now()); day = day.AddDate(0, 0, 1) {
+				locations = append(locations, services.CBRRatesURL(base, day))
+			}
+		}
+	}
+
+	// Connect to database
+	if err := database.Connect(); err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	// Run migrations
+	if err := database.Migrate(); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	ctx := context.Background()
+	imported := 0
+	for _, location := range locations {
+		count, err := services.ImportExchangeRates(ctx, *source, location)
+		if err != nil {
+			log.Fatalf("Failed to import %s: %v", location, err)
+		}
+		log.Printf("Imported %d %s rates from %s", count, *source, location)
+		imported += count
+	}
+
+	log.Printf("Imported %d %s rates in total", imported, *source)
+}
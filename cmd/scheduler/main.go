@@ -11,6 +11,8 @@ import (
 	"github.com/robfig/cron/v3"
 	"pricemap-go/config"
 	"pricemap-go/database"
+	"pricemap-go/metrics"
+	"pricemap-go/models"
 	"pricemap-go/services"
 )
 
@@ -29,6 +31,11 @@ func main() {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
 	
+	// Expose metrics for Prometheus while the scheduler runs
+	metricsCtx, stopMetrics := context.WithCancel(context.Background())
+	defer stopMetrics()
+	metrics.Serve(metricsCtx, config.AppConfig.MetricsAddr)
+	
 	// Create scheduler
 	c := cron.New()
 	
@@ -51,6 +58,25 @@ func main() {
 		log.Fatalf("Failed to schedule task: %v", err)
 	}
 	
+	// Add exchange rate import; ECB goes last, so its rates win where the sources overlap
+	_, err = c.AddFunc(config.AppConfig.ExchangeRatesSchedule, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+		
+		for _, source := range []string{models.RateSourceCBR, models.RateSourceECB} {
+			count, err := services.ImportExchangeRates(ctx, source, services.ExchangeRatesURL(source))
+			if err != nil {
+				log.Printf("Scheduled %s rate import failed: %v", source, err)
+				continue
+			}
+			log.Printf("Imported %d %s exchange rates", count, source)
+		}
+	})
+	
+	if err != nil {
+		log.Fatalf("Failed to schedule exchange rate import: %v", err)
+	}
+	
 	// Start scheduler
 	c.Start()
 	log.Printf("Scheduler started with schedule: %s", config.AppConfig.CronSchedule)
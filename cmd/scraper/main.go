@@ -2,33 +2,79 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"text/tabwriter"
 
 	"pricemap-go/config"
 	"pricemap-go/database"
+	"pricemap-go/metrics"
+	"pricemap-go/parsers"
 	"pricemap-go/services"
 )
 
 func main() {
+	sources := flag.String("source", "", "comma-separated sources to scrape, e.g. cian,zillow (default: all enabled)")
+	cities := flag.String("city", "", "comma-separated cities to scrape, e.g. Moscow (default: all)")
+	workers := flag.Int("workers", 3, "number of sources scraped concurrently")
+	dryRun := flag.Bool("dry-run", false, "validate properties and write them as NDJSON instead of saving them to the database")
+	output := flag.String("output", "-", "dry run NDJSON file, - for stdout")
+	list := flag.Bool("list", false, "list available sources and exit")
+	flag.Parse()
+
 	// Load configuration
 	config.Load()
 
-	// Connect to database
-	if err := database.Connect(); err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+	if *list {
+		if err := listSources(); err != nil {
+			log.Fatalf("Failed to list sources: %v", err)
+		}
+		return
+	}
+
+	if *workers < 1 {
+		log.Fatalf("--workers must be at least 1")
 	}
-	defer database.Close()
 
-	// Run migrations
-	if err := database.Migrate(); err != nil {
-		log.Fatalf("Failed to migrate database: %v", err)
+	options := services.ScrapeOptions{
+		Sources: splitList(*sources),
+		Cities:  splitList(*cities),
+		DryRun:  *dryRun,
+	}
+	if *dryRun && *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			log.Fatalf("Failed to create dry run output: %v", err)
+		}
+		defer file.Close()
+		options.Output = file
 	}
 
 	// Create scraper service
-	scraperService := services.NewScraperService()
+	scraperService := services.NewScraperServiceWithOptions(options)
+
+	// Fail fast on unknown source names before touching the database
+	if _, err := scraperService.SelectedSources(); err != nil {
+		log.Fatalf("Invalid --source: %v", err)
+	}
+
+	if !*dryRun {
+		// Connect to database
+		if err := database.Connect(); err != nil {
+			log.Fatalf("Failed to connect to database: %v", err)
+		}
+		defer database.Close()
+
+		// Run migrations
+		if err := database.Migrate(); err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+	}
 
 	// Create context with cancellation capability
 	ctx, cancel := context.WithCancel(context.Background())
@@ -44,15 +90,60 @@ func main() {
 		cancel()
 	}()
 
-	// Start parsing (concurrent mode with 3 workers for better performance)
+	// Expose metrics for Prometheus while the run lasts
+	metrics.Serve(ctx, config.AppConfig.MetricsAddr)
+
 	log.Println("Starting scraper...")
 
-	// Use concurrent scraping for better performance
-	// Workers=3 is a good balance between speed and resource usage
-	if err := scraperService.ScrapeAllConcurrent(ctx, 3); err != nil {
+	if err := scraperService.ScrapeAllConcurrent(ctx, *workers); err != nil {
 		log.Printf("Scraping completed with errors: %v", err)
 		// Don't fatal - some parsers may have succeeded
 	} else {
 		log.Println("Scraper completed successfully")
 	}
+
+	if *dryRun {
+		log.Printf("Dry run summary: %s", scraperService.DryRunSummary())
+	}
+}
+
+// listSources prints the registered sources and site definitions
+func listSources() error {
+	sources, err := parsers.Sources(config.AppConfig.ParserDefinitionsDir)
+	if err != nil {
+		log.Printf("Error loading parser definitions: %v", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SOURCE\tKIND\tCOUNTRY\tCITIES\tSTATUS")
+	for _, info := range sources {
+		status := "enabled"
+		if !config.AppConfig.SourceEnabled(info.Name) {
+			status = "disabled"
+		}
+		if info.Definition {
+			status += " (definition)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", info.Name, info.Kind, info.Country, summarizeCities(info.Cities), status)
+	}
+	return w.Flush()
+}
+
+// summarizeCities shortens long city lists to keep the table readable
+func summarizeCities(cities []string) string {
+	const shown = 3
+	if len(cities) <= shown {
+		return strings.Join(cities, "; ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(cities[:shown], "; "), len(cities)-shown)
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
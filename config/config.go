@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -19,6 +20,9 @@ type Config struct {
 	// Server
 	ServerPort string
 
+	// Metrics listener of the scraper and scheduler (the API server serves /metrics itself)
+	MetricsAddr string
+
 	// API Keys
 	GoogleMapsAPIKey string
 	OpenCageAPIKey   string // For geocoding
@@ -27,6 +31,16 @@ type Config struct {
 	UserAgent      string
 	RequestTimeout int // seconds
 
+	// HTTP fixtures for offline parser testing ("", "record" or "replay")
+	HTTPFixtureMode string
+	HTTPFixtureDir  string
+
+	// Declarative parser definitions (YAML/JSON site descriptions)
+	ParserDefinitionsDir string
+
+	// Sources skipped unless selected explicitly, e.g. ["zillow"]
+	DisabledSources []string
+
 	// Tor Proxy (for bypassing blocks)
 	UseTor             bool
 	TorProxyHost       string
@@ -39,6 +53,22 @@ type Config struct {
 	MaxRetries     int
 	RetryDelay     int // seconds
 
+	// Pagination of listing search results
+	MaxPages       int            // default page limit per search
+	SourceMaxPages map[string]int // per-source overrides, e.g. {"cian": 10}
+
+	// Detail page enrichment
+	DetailEnrichment bool // visit listing pages of new and changed listings after each run
+	DetailMaxPerRun  int  // detail pages visited per source and run
+
+	// Delisting
+	DelistMinSeenPercent int // minimum share of active listings a run must see before unseen ones are delisted
+
+	// Exchange rates, imported by the scheduler and cmd/rates
+	ECBRatesURL           string
+	CBRRatesURL           string
+	ExchangeRatesSchedule string
+
 	// Cron
 	CronSchedule string
 }
@@ -58,12 +88,21 @@ func Load() {
 
 		ServerPort: getEnv("SERVER_PORT", "3000"),
 
+		MetricsAddr: getEnv("METRICS_ADDR", ":2112"),
+
 		GoogleMapsAPIKey: getEnv("GOOGLE_MAPS_API_KEY", ""),
 		OpenCageAPIKey:   getEnv("OPENCAGE_API_KEY", ""),
 
 		UserAgent:      getEnv("USER_AGENT", "PriceMap-Go/1.0"),
 		RequestTimeout: getEnvInt("REQUEST_TIMEOUT", 30),
 
+		HTTPFixtureMode: getEnv("HTTP_FIXTURE_MODE", ""),
+		HTTPFixtureDir:  getEnv("HTTP_FIXTURE_DIR", "./parsers/testdata/fixtures"),
+
+		ParserDefinitionsDir: getEnv("PARSER_DEFINITIONS_DIR", "./definitions"),
+
+		DisabledSources: getEnvList("DISABLED_SOURCES"),
+
 		UseTor:             getEnv("USE_TOR", "false") == "true",
 		TorProxyHost:       getEnv("TOR_PROXY_HOST", "127.0.0.1"),
 		TorProxyPort:       getEnv("TOR_PROXY_PORT", "9050"),
@@ -74,12 +113,45 @@ func Load() {
 		MaxRetries:     getEnvInt("MAX_RETRIES", 3),
 		RetryDelay:     getEnvInt("RETRY_DELAY", 5),
 
+		MaxPages:       getEnvInt("MAX_PAGES", 5),
+		SourceMaxPages: getEnvIntMap("SOURCE_MAX_PAGES"),
+
+		DetailEnrichment: getEnv("DETAIL_ENRICHMENT", "true") == "true",
+		DetailMaxPerRun:  getEnvInt("DETAIL_MAX_PER_RUN", 100),
+
+		DelistMinSeenPercent: getEnvInt("DELIST_MIN_SEEN_PERCENT", 50),
+
+		ECBRatesURL:           getEnv("ECB_RATES_URL", "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"),
+		CBRRatesURL:           getEnv("CBR_RATES_URL", "https://www.cbr.ru/scripts/XML_daily.asp"),
+		ExchangeRatesSchedule: getEnv("EXCHANGE_RATES_SCHEDULE", "0 17 * * *"), // Daily, after the ECB publishes
+
 		CronSchedule: getEnv("CRON_SCHEDULE", "0 */6 * * *"), // Every 6 hours
 	}
 
 	log.Println("Configuration loaded successfully")
 }
 
+// MaxPagesFor returns how many search result pages to fetch for a source
+func (c *Config) MaxPagesFor(source string) int {
+	if pages, ok := c.SourceMaxPages[source]; ok && pages > 0 {
+		return pages
+	}
+	if c.MaxPages > 0 {
+		return c.MaxPages
+	}
+	return 1
+}
+
+// SourceEnabled reports whether a source runs when no sources are selected
+func (c *Config) SourceEnabled(source string) bool {
+	for _, disabled := range c.DisabledSources {
+		if disabled == source {
+			return false
+		}
+	}
+	return true
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -95,3 +167,29 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvIntMap parses "name=value,name=value" pairs; malformed pairs are skipped
+func getEnvIntMap(key string) map[string]int {
+	result := make(map[string]int)
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found {
+			continue
+		}
+		if intVal, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+			result[strings.TrimSpace(name)] = intVal
+		}
+	}
+	return result
+}
+
+// getEnvList parses a comma-separated list; empty items are skipped
+func getEnvList(key string) []string {
+	var result []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -7,9 +7,11 @@ import (
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 
 	"pricemap-go/config"
+	"pricemap-go/metrics"
 	"pricemap-go/models"
 )
 
@@ -46,6 +48,9 @@ func Connect() error {
 	sqlDB.SetConnMaxLifetime(5 * time.Minute) // Maximum lifetime of a connection
 	sqlDB.SetConnMaxIdleTime(1 * time.Minute) // Maximum idle time for a connection
 
+	// Expose pool usage on /metrics
+	metrics.RegisterDB(sqlDB)
+
 	log.Println("Database connected with optimized pool settings")
 	return nil
 }
@@ -54,12 +59,33 @@ func Migrate() error {
 	err := DB.AutoMigrate(
 		&models.Property{},
 		&models.PropertyFactors{},
+		&models.PriceObservation{},
+		&models.CanonicalProperty{},
+		&models.ScrapeRun{},
+		&models.ExchangeRate{},
+		&models.TransitStop{},
+		&models.PointOfInterest{},
+		&models.WeightProfile{},
+		&models.CrimeIncident{},
 	)
 
 	if err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
 
+	// Fill price per m² of properties saved before it was stored
+	if err := DB.Exec(`UPDATE properties SET price_per_sqm = ROUND((price / area)::numeric, 2)
+		WHERE price_per_sqm IS NULL AND price > 0 AND area > 0`).Error; err != nil {
+		return fmt.Errorf("failed to backfill price per m²: %w", err)
+	}
+
+	// Create the built-in weighting profiles, keeping stored ones as edited
+	profiles := append([]models.WeightProfile(nil), models.BuiltinWeightProfiles...)
+	if err := DB.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "name"}}, DoNothing: true}).
+		Create(&profiles).Error; err != nil {
+		return fmt.Errorf("failed to create weight profiles: %w", err)
+	}
+
 	log.Println("Database migration completed")
 	return nil
 }
@@ -0,0 +1,149 @@
+// Package metrics defines the Prometheus metrics every process exposes.
+// It depends on nothing else in the module, so any package can record into it.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "pricemap"
+
+// HTTP API
+var (
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "API request latency by route, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+)
+
+// Scraping
+var (
+	FetchAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "fetch_attempts_total",
+		Help:      "HTTP requests made by parsers by host and response status (\"error\" when there was no response).",
+	}, []string{"host", "status"})
+
+	FetchRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "fetch_retries_total",
+		Help:      "Parser requests repeated after a failed attempt, by host.",
+	}, []string{"host"})
+
+	FetchFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "fetch_failures_total",
+		Help:      "Parser fetches that failed after all retries, by host.",
+	}, []string{"host"})
+
+	TorRotations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tor_circuit_rotations_total",
+		Help:      "Tor circuit rotations by result (success or failure).",
+	}, []string{"result"})
+
+	PropertiesParsed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "parser_properties_parsed_total",
+		Help:      "Properties returned by parsers, by source.",
+	}, []string{"source"})
+
+	PropertiesSaved = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "parser_properties_saved_total",
+		Help:      "Properties written to the database, by source.",
+	}, []string{"source"})
+
+	ParserErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "parser_errors_total",
+		Help:      "Failed parser runs and properties that failed to save, by source.",
+	}, []string{"source"})
+
+	ParserRunDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "parser_run_duration_seconds",
+		Help:      "Duration of scraping one source.",
+		Buckets:   []float64{1, 10, 30, 60, 300, 600, 1800, 3600, 7200},
+	}, []string{"source"})
+)
+
+// Caching
+var (
+	CacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_hits_total",
+		Help:      "CacheService lookups that found a live entry.",
+	})
+
+	CacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_misses_total",
+		Help:      "CacheService lookups that found nothing or an expired entry.",
+	})
+)
+
+// StatusLabel turns an HTTP status into a label value; 0 means no response
+func StatusLabel(status int) string {
+	if status <= 0 {
+		return "error"
+	}
+	return strconv.Itoa(status)
+}
+
+// RegisterDB exposes connection pool statistics of the database
+func RegisterDB(db *sql.DB) {
+	collector := collectors.NewDBStatsCollector(db, namespace)
+	if err := prometheus.Register(collector); err != nil {
+		var already prometheus.AlreadyRegisteredError
+		if !errors.As(err, &already) {
+			log.Printf("Failed to register database metrics: %v", err)
+		}
+	}
+}
+
+// Handler serves all metrics in the Prometheus text or OpenMetrics format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{
+		EnableOpenMetrics: true,
+	})
+}
+
+// Serve runs a metrics listener for processes without an HTTP API until ctx
+// is done. An empty addr or "off" disables it.
+func Serve(ctx context.Context, addr string) {
+	if addr == "" || addr == "off" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	go func() {
+		log.Printf("Serving metrics on %s/metrics", addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Metrics listener failed: %v", err)
+		}
+	}()
+}
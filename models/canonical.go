@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// CanonicalProperty is a physical property that one or more listings describe.
+// Listings of the same flat from different sources point to the same record.
+type CanonicalProperty struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Country           string  `gorm:"index" json:"country"`
+	City              string  `gorm:"index" json:"city"`
+	NormalizedAddress string  `json:"normalized_address"`
+	Latitude          float64 `json:"latitude"`
+	Longitude         float64 `json:"longitude"`
+	Area              float64 `json:"area"`
+	Rooms             int     `json:"rooms"`
+	Floor             int     `json:"floor"`
+
+	MemberCount int `gorm:"default:1" json:"member_count"`
+
+	Members []Property `gorm:"foreignKey:CanonicalID" json:"members,omitempty"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+)
+
+// Crime categories, normalized from the offence names of the sources
+const (
+	CrimeCategoryViolent   = "violent"   // homicide, assault, robbery, sexual offences, kidnapping
+	CrimeCategoryWeapons   = "weapons"   // possession and use of weapons
+	CrimeCategoryBurglary  = "burglary"  // breaking into homes and premises
+	CrimeCategoryVehicle   = "vehicle"   // theft of and from vehicles
+	CrimeCategoryTheft     = "theft"     // any other theft, shoplifting, pickpocketing
+	CrimeCategoryVandalism = "vandalism" // criminal damage, arson, graffiti
+	CrimeCategoryDrugs     = "drugs"     // possession and dealing
+	CrimeCategoryDisorder  = "disorder"  // anti-social behaviour, public order, trespass
+	CrimeCategoryOther     = "other"
+)
+
+// CrimeCategories lists the crime categories from the most to the least
+// severe
+var CrimeCategories = []string{
+	CrimeCategoryViolent,
+	CrimeCategoryWeapons,
+	CrimeCategoryBurglary,
+	CrimeCategoryVehicle,
+	CrimeCategoryTheft,
+	CrimeCategoryVandalism,
+	CrimeCategoryDrugs,
+	CrimeCategoryDisorder,
+	CrimeCategoryOther,
+}
+
+// CrimeIncident is a reported crime of a city imported from an open-data
+// incident file, located where the source places it (often snapped to the
+// nearest street segment or block for privacy)
+type CrimeIncident struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	City       string    `gorm:"not null;uniqueIndex:idx_crime_incident_source;index:idx_crime_incident_city_time" json:"city"`
+	Source     string    `gorm:"size:16;not null;uniqueIndex:idx_crime_incident_source" json:"source"` // the import format, e.g. nyc
+	IncidentID string    `gorm:"not null;uniqueIndex:idx_crime_incident_source" json:"incident_id"`    // the source's ID, or a hash of the row without one
+	Category   string    `gorm:"size:16;not null;index" json:"category"`
+	Offense    string    `json:"offense"` // as the source names it
+	OccurredAt time.Time `gorm:"not null;index:idx_crime_incident_city_time" json:"occurred_at"`
+	Latitude   float64   `gorm:"not null" json:"latitude"`
+	Longitude  float64   `gorm:"not null" json:"longitude"`
+}
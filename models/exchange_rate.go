@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// Exchange rate sources
+const (
+	RateSourceECB = "ecb" // European Central Bank euro reference rates
+	RateSourceCBR = "cbr" // Central Bank of Russia daily rates
+)
+
+// ExchangeRate is how many units of Currency one US dollar bought on Date,
+// derived from the reference rates a central bank published for that day.
+// Prices are converted at the rates of the day their listing was scraped.
+type ExchangeRate struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Currency string    `gorm:"size:3;not null;uniqueIndex:idx_exchange_rate_currency_date" json:"currency"`
+	Date     time.Time `gorm:"type:date;not null;uniqueIndex:idx_exchange_rate_currency_date" json:"date"`
+	Rate     float64   `gorm:"not null" json:"rate"`
+	Source   string    `gorm:"not null" json:"source"`
+}
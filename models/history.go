@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// PriceObservation is a point in the price timeline of a property.
+// A new observation is written whenever a scrape sees a different price,
+// currency or active state than the one stored for the listing.
+type PriceObservation struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	PropertyID uint    `gorm:"not null;index:idx_price_obs_property_time" json:"property_id"`
+	Price      float64 `gorm:"not null" json:"price"`
+	Currency   string  `json:"currency"`
+	IsActive   bool    `json:"is_active"`
+
+	// The observed price, set when the API converts Price into another currency
+	NativePrice    float64 `gorm:"-" json:"native_price,omitempty"`
+	NativeCurrency string  `gorm:"-" json:"native_currency,omitempty"`
+
+	ObservedAt time.Time `gorm:"not null;index:idx_price_obs_property_time" json:"observed_at"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+)
+
+// Point of interest categories, from OpenStreetMap tags
+const (
+	POICategoryGroceries   = "groceries"   // supermarkets, convenience stores, bakeries
+	POICategoryShops       = "shops"       // any other shop
+	POICategoryRestaurants = "restaurants" // restaurants, cafes, fast food, bars
+	POICategoryParks       = "parks"       // parks, gardens, playgrounds
+	POICategoryHealthcare  = "healthcare"  // hospitals, clinics, doctors, pharmacies
+	POICategorySchools     = "schools"     // kindergartens, schools, colleges, universities
+	POICategoryServices    = "services"    // banks, post offices, libraries
+)
+
+// POICategories lists the point of interest categories
+var POICategories = []string{
+	POICategoryGroceries,
+	POICategoryShops,
+	POICategoryRestaurants,
+	POICategoryParks,
+	POICategoryHealthcare,
+	POICategorySchools,
+	POICategoryServices,
+}
+
+// PointOfInterest is an amenity of a city imported from an OpenStreetMap
+// extract. Areas (ways) are stored at the centre of their nodes.
+type PointOfInterest struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	City      string  `gorm:"not null;uniqueIndex:idx_poi_city_osm" json:"city"`
+	OSMType   string  `gorm:"size:8;not null;uniqueIndex:idx_poi_city_osm" json:"osm_type"` // node, way or relation
+	OSMID     int64   `gorm:"not null;uniqueIndex:idx_poi_city_osm" json:"osm_id"`
+	Category  string  `gorm:"not null;index" json:"category"`
+	Kind      string  `json:"kind"` // the OSM tag value, e.g. supermarket
+	Name      string  `json:"name"`
+	Latitude  float64 `gorm:"not null" json:"latitude"`
+	Longitude float64 `gorm:"not null" json:"longitude"`
+}
+
+// TableName names the table points_of_interest rather than point_of_interests
+func (PointOfInterest) TableName() string {
+	return "points_of_interest"
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"math"
 	"time"
 	"gorm.io/gorm"
 )
@@ -30,6 +31,12 @@ type Property struct {
 	Price        float64   `gorm:"not null;index" json:"price"`
 	Currency     string    `gorm:"default:'USD'" json:"currency"`
 	Area         float64   `json:"area"` // area in m²
+	PricePerSqm  *float64  `gorm:"index" json:"price_per_sqm"` // Price / Area, nil while the area is unknown
+
+	// The listing's own price, set when the API converts Price into another currency
+	NativePrice    float64 `gorm:"-" json:"native_price,omitempty"`
+	NativeCurrency string  `gorm:"-" json:"native_currency,omitempty"`
+	Unconverted    bool    `gorm:"-" json:"unconverted,omitempty"` // Currency has no exchange rate, so Price is the listing's own
 	Rooms        int       `json:"rooms"`
 	Bedrooms     int       `json:"bedrooms"`
 	Bathrooms    int       `json:"bathrooms"`
@@ -39,16 +46,70 @@ type Property struct {
 	
 	// Additional information
 	Description  string    `gorm:"type:text" json:"description"`
-	Images       []string  `gorm:"type:text[]" json:"images"`
+	Images       StringArray `gorm:"type:text[]" json:"images"`
 	
 	// Metadata
-	ScrapedAt    time.Time `gorm:"not null" json:"scraped_at"`
-	IsActive     bool      `gorm:"default:true;index" json:"is_active"`
+	DealType     string     `gorm:"index" json:"deal_type"` // sale or rent
+	ScrapedAt    time.Time  `gorm:"not null" json:"scraped_at"`
+	IsActive     bool       `gorm:"default:true;index" json:"is_active"`
+	FirstSeenAt  time.Time  `json:"first_seen_at"`
+	DelistedAt   *time.Time `gorm:"index" json:"delisted_at"`
+	DaysOnMarket int        `gorm:"-" json:"days_on_market"`
+
+	// Detail page enrichment; a nil DetailsFetchedAt marks the listing as due for a visit
+	DetailsFetchedAt *time.Time `gorm:"index" json:"details_fetched_at"`
+	DetailAttempts   int        `json:"-"` // failed detail page visits since the last success
+
+	// Deduplication
+	CanonicalID     *uint   `gorm:"index" json:"canonical_id"`
+	MatchConfidence float64 `json:"match_confidence"` // 0-1, confidence that this listing belongs to the canonical property
 	
 	// Relations
 	Factors      PropertyFactors `gorm:"foreignKey:PropertyID" json:"factors"`
 }
 
+// BeforeSave keeps the stored price per m² in step with price and area
+func (p *Property) BeforeSave(tx *gorm.DB) error {
+	p.PricePerSqm = PricePerSqm(p.Price, p.Area)
+	return nil
+}
+
+// PricePerSqm returns price divided by area, or nil if either is unknown
+func PricePerSqm(price, area float64) *float64 {
+	if price <= 0 || area <= 0 {
+		return nil
+	}
+	perSqm := math.Round(price/area*100) / 100
+	return &perSqm
+}
+
+// AfterFind fills derived fields after loading from database
+func (p *Property) AfterFind(tx *gorm.DB) error {
+	p.DaysOnMarket = p.DaysListed(time.Now())
+	return nil
+}
+
+// DaysListed returns how many days the listing has been (or was) on the market
+func (p *Property) DaysListed(now time.Time) int {
+	firstSeen := p.FirstSeenAt
+	if firstSeen.IsZero() {
+		firstSeen = p.CreatedAt
+	}
+	if firstSeen.IsZero() {
+		return 0
+	}
+
+	end := now
+	if p.DelistedAt != nil {
+		end = *p.DelistedAt
+	}
+	if end.Before(firstSeen) {
+		return 0
+	}
+
+	return int(end.Sub(firstSeen).Hours() / 24)
+}
+
 // PropertyFactors contains factors affecting the price
 type PropertyFactors struct {
 	ID         uint      `gorm:"primaryKey" json:"id"`
@@ -67,7 +128,7 @@ type PropertyFactors struct {
 	
 	// Education (0-100, where 100 is the best schools)
 	EducationScore  float64 `gorm:"default:0" json:"education_score"`
-	EducationData   string  `gorm:"type:jsonb" json:"education_data"` // School ratings
+	EducationData   string  `gorm:"type:jsonb" json:"education_data"` // Schools around the property
 	
 	// Infrastructure
 	InfrastructureScore float64 `gorm:"default:0" json:"infrastructure_score"`
@@ -82,12 +143,15 @@ type PropertyFactors struct {
 	Walkability     float64 `json:"walkability"`
 }
 
-// PriceHeatmapPoint represents a point for heatmap
+// PriceHeatmapPoint represents a grid cell of the heatmap, placed at its center
 type PriceHeatmapPoint struct {
-	Latitude  float64 `json:"lat"`
-	Longitude float64 `json:"lng"`
-	Price     float64 `json:"price"`
-	Score     float64 `json:"score"` // Overall rating
-	Count     int     `json:"count"` // Number of properties in this area
+	Latitude    float64 `json:"lat"`
+	Longitude   float64 `json:"lng"`
+	Price       float64 `json:"price"`         // Mean price
+	MedianPrice float64 `json:"median_price"`
+	PricePerSqm float64 `json:"price_per_sqm"` // Mean over properties with a known area
+	Score       float64 `json:"score"`         // Mean overall rating of rated properties
+	Count       int     `json:"count"`         // Number of properties in this area
+	Value       float64 `json:"value"`         // Intensity: the requested metric of the cell
 }
 
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProperty_DaysListed(t *testing.T) {
+	firstSeen := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	delisted := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		property Property
+		want     int
+	}{
+		{
+			name:     "still active",
+			property: Property{FirstSeenAt: firstSeen},
+			want:     60,
+		},
+		{
+			name:     "delisted",
+			property: Property{FirstSeenAt: firstSeen, DelistedAt: &delisted},
+			want:     30,
+		},
+		{
+			name:     "falls back to created_at",
+			property: Property{CreatedAt: firstSeen},
+			want:     60,
+		},
+		{
+			name:     "unknown first seen",
+			property: Property{},
+			want:     0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.property.DaysListed(now); got != tt.want {
+				t.Errorf("DaysListed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPricePerSqm(t *testing.T) {
+	tests := []struct {
+		name  string
+		price float64
+		area  float64
+		want  *float64
+	}{
+		{name: "known area", price: 12500000, area: 54.5, want: floatPtr(229357.8)},
+		{name: "rounds to cents", price: 100000, area: 3, want: floatPtr(33333.33)},
+		{name: "unknown area", price: 450000, area: 0, want: nil},
+		{name: "unknown price", price: 0, area: 60, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PricePerSqm(tt.price, tt.area)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("PricePerSqm(%v, %v) = %v, want %v", tt.price, tt.area, got, tt.want)
+			}
+		})
+	}
+
+	// Saving keeps the stored value in step with price and area
+	p := Property{Price: 300000, Area: 60}
+	p.BeforeSave(nil)
+	if p.PricePerSqm == nil || *p.PricePerSqm != 5000 {
+		t.Errorf("BeforeSave() price_per_sqm = %v, want 5000", p.PricePerSqm)
+	}
+	p.Area = 0
+	p.BeforeSave(nil)
+	if p.PricePerSqm != nil {
+		t.Errorf("BeforeSave() price_per_sqm = %v after the area became unknown, want nil", *p.PricePerSqm)
+	}
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
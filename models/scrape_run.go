@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+)
+
+// Scrape run states
+const (
+	ScrapeRunRunning   = "running"
+	ScrapeRunSucceeded = "succeeded"
+	ScrapeRunFailed    = "failed"
+	ScrapeRunCancelled = "cancelled"
+)
+
+// ScrapeRun is the journal entry of one scrape of a source. The scraper and
+// scheduler write it, so the API server can report what they did.
+type ScrapeRun struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	Source     string     `gorm:"not null;index:idx_scrape_run_source_started" json:"source"`
+	StartedAt  time.Time  `gorm:"not null;index:idx_scrape_run_source_started" json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	Status     string     `gorm:"not null;index" json:"status"`
+
+	Parsed   int `json:"parsed"`
+	Saved    int `json:"saved"`
+	Failed   int `json:"failed"`
+	Delisted int `json:"delisted"`
+	Enriched int `json:"enriched"`
+
+	// HTTPStatuses counts responses by status code; "error" counts requests
+	// that got no response at all
+	HTTPStatuses IntMap      `gorm:"type:jsonb" json:"http_statuses"`
+	Errors       StringArray `gorm:"type:text[]" json:"errors"`
+}
+
+// Duration returns how long the run took, or has been running
+func (r *ScrapeRun) Duration(now time.Time) time.Duration {
+	if r.FinishedAt != nil {
+		return r.FinishedAt.Sub(r.StartedAt)
+	}
+	return now.Sub(r.StartedAt)
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Transit modes, derived from the GTFS route_type of the routes calling at a stop
+const (
+	TransitModeMetro = "metro"
+	TransitModeRail  = "rail"
+	TransitModeTram  = "tram"
+	TransitModeBus   = "bus"
+)
+
+// TransitModes lists the transit modes from the most to the least valuable
+var TransitModes = []string{TransitModeMetro, TransitModeRail, TransitModeTram, TransitModeBus}
+
+// TransitStop is a stop or station of a city's GTFS feed, with the modes of
+// the routes calling at it and how many departures it has a day. Platforms
+// of a station are merged into the station.
+type TransitStop struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	City             string  `gorm:"not null;uniqueIndex:idx_transit_stop_city_stop" json:"city"`
+	StopID           string  `gorm:"not null;uniqueIndex:idx_transit_stop_city_stop" json:"stop_id"` // stop_id in the feed
+	Name             string  `json:"name"`
+	Latitude         float64 `gorm:"not null;index:idx_transit_stop_location" json:"latitude"`
+	Longitude        float64 `gorm:"not null;index:idx_transit_stop_location" json:"longitude"`
+	Modes            string  `gorm:"not null" json:"modes"` // comma-separated, in TransitModes order
+	DeparturesPerDay float64 `json:"departures_per_day"`    // averaged over the week when the feed has a calendar
+}
+
+// ModeList returns the modes of the stop in TransitModes order
+func (s *TransitStop) ModeList() []string {
+	if s.Modes == "" {
+		return nil
+	}
+	return strings.Split(s.Modes, ",")
+}
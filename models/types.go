@@ -0,0 +1,124 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// StringArray maps a Go string slice to a PostgreSQL text[] column
+type StringArray []string
+
+// Value encodes the slice as a PostgreSQL array literal
+func (a StringArray) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+
+	quoted := make([]string, len(a))
+	for i, s := range a {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+		s = strings.ReplaceAll(s, `"`, `\"`)
+		quoted[i] = `"` + s + `"`
+	}
+	return "{" + strings.Join(quoted, ",") + "}", nil
+}
+
+// Scan decodes a PostgreSQL array literal such as {a,"b c"}
+func (a *StringArray) Scan(src interface{}) error {
+	var literal string
+	switch v := src.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case string:
+		literal = v
+	case []byte:
+		literal = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into StringArray", src)
+	}
+
+	if len(literal) < 2 || literal[0] != '{' || literal[len(literal)-1] != '}' {
+		return fmt.Errorf("invalid array literal %q", literal)
+	}
+	literal = literal[1 : len(literal)-1]
+
+	result := StringArray{}
+	if literal == "" {
+		*a = result
+		return nil
+	}
+
+	var b strings.Builder
+	quoted, inQuotes, escaped := false, false, false
+	flush := func() {
+		value := b.String()
+		if !quoted && strings.EqualFold(value, "NULL") {
+			value = ""
+		}
+		result = append(result, value)
+		b.Reset()
+		quoted = false
+	}
+
+	for _, r := range literal {
+		switch {
+		case escaped:
+			b.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			inQuotes = !inQuotes
+			quoted = true
+		case r == ',' && !inQuotes:
+			flush()
+		default:
+			b.WriteRune(r)
+		}
+	}
+	flush()
+
+	*a = result
+	return nil
+}
+
+// IntMap maps a map of counters to a PostgreSQL jsonb column
+type IntMap map[string]int
+
+// Value encodes the map as JSON
+func (m IntMap) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(map[string]int(m))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan decodes a JSON object of integers
+func (m *IntMap) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*m = nil
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("cannot scan %T into IntMap", src)
+	}
+
+	result := IntMap{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("invalid IntMap JSON: %w", err)
+	}
+	*m = result
+	return nil
+}
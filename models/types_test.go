@@ -0,0 +1,79 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStringArray_RoundTrip(t *testing.T) {
+	original := StringArray{"https://img.example.com/1.jpg", `quote " and \ backslash`, "comma, inside", ""}
+
+	value, err := original.Value()
+	if err != nil {
+		t.Fatalf("Value() error = %v", err)
+	}
+
+	var decoded StringArray
+	if err := decoded.Scan(value); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded, original) {
+		t.Errorf("round trip = %#v, want %#v", decoded, original)
+	}
+}
+
+func TestStringArray_Scan(t *testing.T) {
+	tests := []struct {
+		src  interface{}
+		want StringArray
+	}{
+		{nil, nil},
+		{"{}", StringArray{}},
+		{[]byte("{a,b}"), StringArray{"a", "b"}},
+		{`{"a b",c}`, StringArray{"a b", "c"}},
+	}
+
+	for _, tt := range tests {
+		var got StringArray
+		if err := got.Scan(tt.src); err != nil {
+			t.Errorf("Scan(%v) error = %v", tt.src, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Scan(%v) = %#v, want %#v", tt.src, got, tt.want)
+		}
+	}
+
+	var got StringArray
+	if err := got.Scan("not an array"); err == nil {
+		t.Errorf("Scan() should reject values that are not array literals")
+	}
+}
+
+func TestStringArray_ValueNil(t *testing.T) {
+	var a StringArray
+	if value, err := a.Value(); err != nil || value != nil {
+		t.Errorf("Value() of nil array = %v, %v; want NULL", value, err)
+	}
+}
+
+func TestIntMap_RoundTrip(t *testing.T) {
+	original := IntMap{"200": 12, "404": 1, "error": 2}
+
+	value, err := original.Value()
+	if err != nil {
+		t.Fatalf("Value() error = %v", err)
+	}
+
+	var decoded IntMap
+	if err := decoded.Scan([]byte(value.(string))); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded, original) {
+		t.Errorf("round trip = %#v, want %#v", decoded, original)
+	}
+
+	if err := decoded.Scan("[1, 2]"); err == nil {
+		t.Errorf("Scan() should reject JSON that is not an object")
+	}
+}
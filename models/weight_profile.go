@@ -0,0 +1,119 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Factors weighed into the overall score, as named by weights parameters
+const (
+	FactorCrime          = "crime"
+	FactorTransport      = "transport"
+	FactorEducation      = "education"
+	FactorInfrastructure = "infrastructure"
+)
+
+// Factors lists the weighed factors
+var Factors = []string{FactorCrime, FactorTransport, FactorEducation, FactorInfrastructure}
+
+// weightSumTolerance is how far from 1 the weights may sum, so that thirds
+// written as 0.333 pass
+const weightSumTolerance = 0.001
+
+// FactorWeights are the shares of the factor scores in an overall score
+type FactorWeights struct {
+	Crime          float64 `gorm:"not null" json:"crime"`
+	Transport      float64 `gorm:"not null" json:"transport"`
+	Education      float64 `gorm:"not null" json:"education"`
+	Infrastructure float64 `gorm:"not null" json:"infrastructure"`
+}
+
+// DefaultFactorWeights weigh the OverallScore stored with the factors
+var DefaultFactorWeights = FactorWeights{
+	Crime:          0.25,
+	Transport:      0.25,
+	Education:      0.20,
+	Infrastructure: 0.30,
+}
+
+// Weight points to the weight of a factor, to read or set it, and returns
+// false for an unknown factor
+func (w *FactorWeights) Weight(factor string) (*float64, bool) {
+	switch factor {
+	case FactorCrime:
+		return &w.Crime, true
+	case FactorTransport:
+		return &w.Transport, true
+	case FactorEducation:
+		return &w.Education, true
+	case FactorInfrastructure:
+		return &w.Infrastructure, true
+	}
+	return nil, false
+}
+
+// Validate checks that every weight is between 0 and 1 and that they sum to 1
+func (w FactorWeights) Validate() error {
+	sum := 0.0
+	for _, factor := range Factors {
+		weight, _ := w.Weight(factor)
+		if !(*weight >= 0 && *weight <= 1) {
+			return fmt.Errorf("%s weight must be between 0 and 1", factor)
+		}
+		sum += *weight
+	}
+	if math.Abs(sum-1) > weightSumTolerance {
+		return fmt.Errorf("weights must sum to 1, not %g", math.Round(sum*1000)/1000)
+	}
+	return nil
+}
+
+// Score returns the overall score of factors, rounded to 2 decimals
+func (w FactorWeights) Score(f *PropertyFactors) float64 {
+	overall := f.CrimeScore*w.Crime +
+		f.TransportScore*w.Transport +
+		f.EducationScore*w.Education +
+		f.InfrastructureScore*w.Infrastructure
+	return math.Round(overall*100) / 100
+}
+
+// WeightProfile is a named set of factor weights, e.g. for families, that the
+// API can recompute overall scores with
+type WeightProfile struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Name          string `gorm:"size:64;not null;uniqueIndex" json:"name"`
+	Description   string `json:"description"`
+	FactorWeights `gorm:"embedded"`
+}
+
+// DefaultWeightProfile names the profile of DefaultFactorWeights
+const DefaultWeightProfile = "default"
+
+// BuiltinWeightProfiles are created by the migration unless a profile of the
+// same name exists, so edits to the stored ones are kept
+var BuiltinWeightProfiles = []WeightProfile{
+	{
+		Name:          DefaultWeightProfile,
+		Description:   "The weights of the stored overall score",
+		FactorWeights: DefaultFactorWeights,
+	},
+	{
+		Name:          "family",
+		Description:   "Safety and schools first",
+		FactorWeights: FactorWeights{Crime: 0.35, Transport: 0.15, Education: 0.35, Infrastructure: 0.15},
+	},
+	{
+		Name:          "student",
+		Description:   "Transport and amenities first; schools matter little",
+		FactorWeights: FactorWeights{Crime: 0.15, Transport: 0.45, Education: 0.10, Infrastructure: 0.30},
+	},
+	{
+		Name:          "retiree",
+		Description:   "Safety and amenities within walking distance; schools do not matter",
+		FactorWeights: FactorWeights{Crime: 0.35, Transport: 0.20, Education: 0, Infrastructure: 0.45},
+	},
+}
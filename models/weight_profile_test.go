@@ -0,0 +1,62 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFactorWeights_Validate(t *testing.T) {
+	tests := []struct {
+		weights FactorWeights
+		valid   bool
+	}{
+		{DefaultFactorWeights, true},
+		{FactorWeights{Crime: 1}, true},
+		{FactorWeights{Crime: 0.333, Transport: 0.333, Education: 0.334}, true},
+		{FactorWeights{Crime: 0.3, Transport: 0.3, Education: 0.3}, false},
+		{FactorWeights{Crime: 1.5, Transport: -0.5}, false},
+		{FactorWeights{Crime: math.NaN(), Transport: 1}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.weights.Validate(); (err == nil) != tt.valid {
+			t.Errorf("Validate(%+v) = %v, want valid %v", tt.weights, err, tt.valid)
+		}
+	}
+
+	for _, profile := range BuiltinWeightProfiles {
+		if err := profile.Validate(); err != nil {
+			t.Errorf("built-in profile %s: %v", profile.Name, err)
+		}
+	}
+}
+
+func TestFactorWeights_Score(t *testing.T) {
+	factors := &PropertyFactors{CrimeScore: 100, TransportScore: 50, EducationScore: 50, InfrastructureScore: 50}
+
+	if got := DefaultFactorWeights.Score(factors); got != 62.5 {
+		t.Errorf("DefaultFactorWeights.Score() = %v, want 62.5", got)
+	}
+
+	thirds := FactorWeights{Crime: 1.0 / 3, Transport: 1.0 / 3, Infrastructure: 1.0 / 3}
+	if got := thirds.Score(factors); got != 66.67 {
+		t.Errorf("Score() = %v, want 66.67, rounded", got)
+	}
+}
+
+func TestFactorWeights_Weight(t *testing.T) {
+	var weights FactorWeights
+	for _, factor := range Factors {
+		weight, ok := weights.Weight(factor)
+		if !ok {
+			t.Fatalf("Weight(%q) is unknown", factor)
+		}
+		*weight = 0.25
+	}
+	if weights != (FactorWeights{0.25, 0.25, 0.25, 0.25}) {
+		t.Errorf("weights = %+v, want all 0.25", weights)
+	}
+
+	if _, ok := weights.Weight("parking"); ok {
+		t.Error("Weight(\"parking\") should be unknown")
+	}
+}
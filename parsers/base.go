@@ -2,18 +2,21 @@ package parsers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"math"
 	"net"
 	"net/http"
+	neturl "net/url"
 	"sync"
 	"time"
 
 	"golang.org/x/net/proxy"
 
 	"pricemap-go/config"
+	"pricemap-go/metrics"
 	"pricemap-go/models"
 	"pricemap-go/utils"
 )
@@ -25,6 +28,21 @@ type Parser interface {
 	GetBaseURL() string
 }
 
+// ListingParser is implemented by parsers that scrape live listings rather than
+// historical records, so a listing missing from a complete run has been removed
+type ListingParser interface {
+	Parser
+	TracksListings() bool
+}
+
+// DetailParser is implemented by parsers that can visit a listing's own page
+// to fill fields the search result card does not show
+type DetailParser interface {
+	Parser
+	HasDetailPages() bool
+	FetchDetails(ctx context.Context, property *models.Property) error
+}
+
 // BaseParser contains common logic for all parsers
 type BaseParser struct {
 	client        *http.Client
@@ -66,7 +84,7 @@ func NewBaseParser(baseURL string) *BaseParser {
 	return &BaseParser{
 		client: &http.Client{
 			Timeout:   time.Duration(config.AppConfig.RequestTimeout) * time.Second,
-			Transport: transport,
+			Transport: utils.NewFixtureTransport(transport),
 		},
 		baseURL:       baseURL,
 		torController: torController,
@@ -82,12 +100,16 @@ func (bp *BaseParser) Fetch(ctx context.Context, url string) (io.ReadCloser, err
 	var lastErr error
 	maxRetries := config.AppConfig.MaxRetries
 
+	// Recorded responses need neither politeness delays nor new Tor circuits
+	replay := utils.IsReplayMode()
+
 	// Thread-safe access to requestCount
 	bp.mu.Lock()
 	currentCount := bp.requestCount
 
-	// Rate limiting with random delay (before the request)
-	if currentCount > 0 {
+	// Rate limiting with random delay (before the request); RATE_LIMIT_DELAY=0
+	// turns it off
+	if currentCount > 0 && !replay && config.AppConfig.RateLimitDelay > 0 {
 		bp.mu.Unlock()
 		delay := utils.GetRandomDelay(config.AppConfig.RateLimitDelay, config.AppConfig.RateLimitDelay+2)
 		time.Sleep(delay)
@@ -95,7 +117,7 @@ func (bp *BaseParser) Fetch(ctx context.Context, url string) (io.ReadCloser, err
 	}
 
 	// Rotate Tor circuit every 10 requests to avoid tracking
-	if bp.torController != nil && currentCount > 0 && currentCount%10 == 0 {
+	if bp.torController != nil && currentCount > 0 && currentCount%10 == 0 && !replay {
 		bp.mu.Unlock()
 		log.Println("Rotating Tor circuit...")
 		if err := bp.torController.RotateCircuit(); err != nil {
@@ -108,6 +130,9 @@ func (bp *BaseParser) Fetch(ctx context.Context, url string) (io.ReadCloser, err
 	bp.requestCount++
 	bp.mu.Unlock()
 
+	stats := fetchStatsFrom(ctx)
+	host := fetchHost(url)
+
 	for attempt := 0; attempt <= maxRetries; attempt++ {
 		// Attempt to fetch
 		body, err := bp.fetchWithRetry(ctx, url, attempt)
@@ -122,10 +147,18 @@ func (bp *BaseParser) Fetch(ctx context.Context, url string) (io.ReadCloser, err
 			return nil, ctx.Err()
 		}
 
+		// A missing fixture will not appear on retry
+		if errors.Is(err, utils.ErrFixtureNotFound) {
+			stats.recordError(url, err)
+			metrics.FetchFailures.WithLabelValues(host).Inc()
+			return nil, err
+		}
+
 		// Exponential backoff
 		if attempt < maxRetries {
 			backoffDelay := time.Duration(math.Pow(2, float64(attempt))) * time.Second * time.Duration(config.AppConfig.RetryDelay)
 			log.Printf("Attempt %d failed: %v. Retrying in %v...", attempt+1, err, backoffDelay)
+			metrics.FetchRetries.WithLabelValues(host).Inc()
 
 			// Rotate Tor circuit on retry if available
 			if bp.torController != nil && attempt > 0 {
@@ -138,7 +171,19 @@ func (bp *BaseParser) Fetch(ctx context.Context, url string) (io.ReadCloser, err
 		}
 	}
 
-	return nil, fmt.Errorf("failed after %d attempts: %w", maxRetries+1, lastErr)
+	lastErr = fmt.Errorf("failed after %d attempts: %w", maxRetries+1, lastErr)
+	stats.recordError(url, lastErr)
+	metrics.FetchFailures.WithLabelValues(host).Inc()
+	return nil, lastErr
+}
+
+// fetchHost labels fetch metrics; paths and queries would explode their cardinality
+func fetchHost(rawURL string) string {
+	u, err := neturl.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "unknown"
+	}
+	return u.Host
 }
 
 func (bp *BaseParser) fetchWithRetry(ctx context.Context, url string, attempt int) (io.ReadCloser, error) {
@@ -166,10 +211,19 @@ func (bp *BaseParser) fetchWithRetry(ctx context.Context, url string, attempt in
 	req.Header.Set("Cache-Control", "max-age=0")
 	req.Header.Set("DNT", "1")
 
+	stats := fetchStatsFrom(ctx)
+
 	resp, err := bp.client.Do(req)
 	if err != nil {
+		// A cancelled run did not get an answer from the site
+		if ctx.Err() == nil {
+			stats.recordStatus(0)
+			metrics.FetchAttempts.WithLabelValues(req.URL.Host, metrics.StatusLabel(0)).Inc()
+		}
 		return nil, fmt.Errorf("failed to fetch URL: %w", err)
 	}
+	stats.recordStatus(resp.StatusCode)
+	metrics.FetchAttempts.WithLabelValues(req.URL.Host, metrics.StatusLabel(resp.StatusCode)).Inc()
 
 	// Accept 200 OK
 	if resp.StatusCode == http.StatusOK {
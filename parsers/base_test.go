@@ -67,3 +67,47 @@ func TestBaseParser_Fetch_404(t *testing.T) {
 	}
 }
 
+
+func TestBaseParser_Fetch_Stats(t *testing.T) {
+	saved := *config.AppConfig
+	config.AppConfig.MaxRetries = 1
+	config.AppConfig.RetryDelay = 0
+	config.AppConfig.RateLimitDelay = 0
+	defer func() { *config.AppConfig = saved }()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch {
+		case r.URL.Path == "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case requests == 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	bp := NewBaseParser(server.URL)
+	stats := NewFetchStats()
+	ctx := WithFetchStats(context.Background(), stats)
+
+	body, err := bp.Fetch(ctx, server.URL)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	body.Close()
+
+	if _, err := bp.Fetch(ctx, server.URL+"/missing"); err == nil {
+		t.Fatalf("Fetch() should fail for 404 status")
+	}
+
+	statuses := stats.Statuses()
+	if statuses["503"] != 1 || statuses["200"] != 1 || statuses["404"] != 2 {
+		t.Errorf("Statuses() = %v, want one 503, one 200 and two 404", statuses)
+	}
+	if errors := stats.Errors(); len(errors) != 1 {
+		t.Errorf("Errors() = %v, want the failed /missing request", errors)
+	}
+}
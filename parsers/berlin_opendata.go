@@ -18,6 +18,16 @@ type BerlinOpenDataParser struct {
 	geocoding *utils.GeocodingService
 }
 
+func init() {
+	Register(SourceInfo{
+		Name:    "berlin_opendata",
+		Kind:    KindOpenData,
+		Country: "Germany",
+		Cities:  []string{"Berlin"},
+		New:     func() Parser { return NewBerlinOpenDataParser() },
+	})
+}
+
 func NewBerlinOpenDataParser() *BerlinOpenDataParser {
 	return &BerlinOpenDataParser{
 		BaseParser: NewBaseParser("https://daten.berlin.de"),
@@ -101,7 +111,7 @@ func (ber *BerlinOpenDataParser) Parse(ctx context.Context) ([]models.Property,
 				property.Latitude = lat
 				property.Longitude = lng
 			}
-			time.Sleep(1 * time.Second)
+			geocodePause()
 		}
 
 		properties = append(properties, *property)
@@ -19,12 +19,30 @@ import (
 type CianParser struct {
 	*BaseParser
 	geocoding *utils.GeocodingService
+	cities    []string
+}
+
+// cianCities starts with the top 5 cities to avoid overwhelming on first run.
+// Users can expand this list as needed.
+var cianCities = []string{
+	"Moscow", "Saint Petersburg", "Novosibirsk", "Yekaterinburg", "Kazan",
+}
+
+func init() {
+	Register(SourceInfo{
+		Name:    "cian",
+		Kind:    KindCommercial,
+		Country: "Russia",
+		Cities:  cianCities,
+		New:     func() Parser { return NewCianParser() },
+	})
 }
 
 func NewCianParser() *CianParser {
 	return &CianParser{
 		BaseParser: NewBaseParser("https://www.cian.ru"),
 		geocoding:  utils.NewGeocodingService(),
+		cities:     cianCities,
 	}
 }
 
@@ -32,25 +50,29 @@ func (cp *CianParser) Name() string {
 	return "cian"
 }
 
+// TracksListings reports that Cian results are live listings
+func (cp *CianParser) TracksListings() bool {
+	return true
+}
+
+// SetCities limits the parser to the given cities
+func (cp *CianParser) SetCities(cities []string) {
+	cp.cities = filterCities(cianCities, cities)
+}
+
 func (cp *CianParser) Parse(ctx context.Context) ([]models.Property, error) {
 	var allProperties []models.Property
 	
-	// Start with top 5 cities to avoid overwhelming on first run
-	// Users can expand this list as needed
-	cities := []string{
-		"Moscow", "Saint Petersburg", "Novosibirsk", "Yekaterinburg", "Kazan",
-	}
-	
 	// Parse different property types
 	types := []string{"flat"}  // Start with flats only, add others later
 	
 	// Parse for both sale and rent
 	dealTypes := []string{"sale"}  // Start with sales only
 	
-	totalCombinations := len(cities) * len(dealTypes) * len(types)
+	totalCombinations := len(cp.cities) * len(dealTypes) * len(types)
 	processed := 0
 	
-	for _, city := range cities {
+	for _, city := range cp.cities {
 		for _, dealType := range dealTypes {
 			for _, propType := range types {
 				// Check context cancellation
@@ -65,7 +87,9 @@ func (cp *CianParser) Parse(ctx context.Context) ([]models.Property, error) {
 				log.Printf("Cian: Processing %s/%s/%s (%d/%d)", city, dealType, propType, processed, totalCombinations)
 				
 				properties, err := cp.parseType(ctx, propType, dealType, city)
-				if err != nil {
+				coverageFrom(ctx).Record(city, dealType, err)
+				// A search cut short still returns the listings it found
+				if err != nil && !IsIncomplete(err) {
 					log.Printf("Error parsing %s/%s/%s from Cian: %v", city, dealType, propType, err)
 					// Continue to next combination instead of failing completely
 					continue
@@ -84,8 +108,6 @@ func (cp *CianParser) Parse(ctx context.Context) ([]models.Property, error) {
 }
 
 func (cp *CianParser) parseType(ctx context.Context, propType, dealType, city string) ([]models.Property, error) {
-	var properties []models.Property
-	
 	// Cian search URL structure with city and deal type
 	// Note: Region codes need to be mapped for each city
 	// For now, using a generic search that works for major cities
@@ -95,22 +117,29 @@ func (cp *CianParser) parseType(ctx context.Context, propType, dealType, city st
 		propType,
 	)
 	
-	body, err := cp.Fetch(ctx, url)
-	if err != nil {
+	// Cian pages are selected with the p parameter
+	properties, err := cp.FetchPages(ctx, cp.Name(), Pagination{
+		URL:     url,
+		PageURL: NumberedPageURL(url, "p"),
+	}, func(doc *goquery.Document) ([]models.Property, error) {
+		return cp.parsePage(doc, propType, dealType, city), nil
+	})
+	if err != nil && !IsIncomplete(err) {
 		return nil, fmt.Errorf("failed to fetch Cian listings: %w", err)
 	}
-	defer body.Close()
 	
-	doc, err := goquery.NewDocumentFromReader(body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse HTML: %w", err)
-	}
+	return properties, err
+}
+
+func (cp *CianParser) parsePage(doc *goquery.Document, propType, dealType, city string) []models.Property {
+	var properties []models.Property
 	
 	// Cian uses specific selectors - these may need adjustment based on actual site structure
 	doc.Find("[data-name='CardComponent']").Each(func(i int, s *goquery.Selection) {
 		property := cp.parseProperty(s, propType)
 		if property != nil {
 			property.City = city
+			property.DealType = dealType
 			properties = append(properties, *property)
 		}
 	})
@@ -121,12 +150,13 @@ func (cp *CianParser) parseType(ctx context.Context, propType, dealType, city st
 			property := cp.parseProperty(s, propType)
 			if property != nil {
 				property.City = city
+				property.DealType = dealType
 				properties = append(properties, *property)
 			}
 		})
 	}
 	
-	return properties, nil
+	return properties
 }
 
 func (cp *CianParser) parseProperty(s *goquery.Selection, propType string) *models.Property {
@@ -298,3 +328,43 @@ func (cp *CianParser) mapType(propType string) string {
 	}
 }
 
+
+// HasDetailPages reports that Cian listings have their own pages
+func (cp *CianParser) HasDetailPages() bool {
+	return true
+}
+
+// FetchDetails visits the listing page and adds floor, building and description details
+func (cp *CianParser) FetchDetails(ctx context.Context, property *models.Property) error {
+	doc, err := cp.fetchDetailPage(ctx, property)
+	if err != nil {
+		return fmt.Errorf("failed to fetch Cian listing: %w", err)
+	}
+	
+	details := cp.parseDetails(doc)
+	details.Apply(property)
+	return nil
+}
+
+func (cp *CianParser) parseDetails(doc *goquery.Document) ListingDetails {
+	var details ListingDetails
+	
+	// Summary facts are rendered as "label value" pairs, e.g. "Этаж 7 из 12"
+	facts := detailText(doc, "[data-name='ObjectFactoidsItem'], [data-name='OfferSummaryInfoItem']")
+	
+	floorRe := regexp.MustCompile(`Этаж\s*(\d+)\s*из\s*(\d+)`)
+	details.Floor = matchInt(floorRe, facts, 1)
+	details.TotalFloors = matchInt(floorRe, facts, 2)
+	details.YearBuilt = matchInt(regexp.MustCompile(`Год (?:постройки|сдачи)\s*(\d{4})`), facts, 1)
+	details.Bathrooms = matchInt(regexp.MustCompile(`Санузел\s*(\d+)`), facts, 1)
+	
+	if matches := regexp.MustCompile(`Общая площадь\s*(\d+(?:[.,]\d+)?)`).FindStringSubmatch(facts); len(matches) > 1 {
+		details.Area = parseNumber(matches[1])
+	}
+	
+	details.Description = strings.TrimSpace(doc.Find("[data-name='Description'] [itemprop='description'], [data-name='Description']").First().Text())
+	details.Images = collectImages(doc, "[data-name='GalleryInnerComponent'] img", "src", "data-src")
+	
+	details.fillFrom(structuredDetails(doc))
+	return details
+}
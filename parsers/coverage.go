@@ -0,0 +1,64 @@
+package parsers
+
+import (
+	"context"
+	"sync"
+)
+
+// Coverage records which cities and deal types a run of a listing source
+// enumerated to the last results page. Attach it with WithCoverage before
+// calling Parse; listings missing from a slice that is not complete may still
+// be on the site.
+type Coverage struct {
+	mu     sync.Mutex
+	slices map[coverageKey]bool
+}
+
+type coverageKey struct {
+	city     string
+	dealType string
+}
+
+type coverageCtxKey struct{}
+
+// NewCoverage creates an empty collector
+func NewCoverage() *Coverage {
+	return &Coverage{slices: make(map[coverageKey]bool)}
+}
+
+// WithCoverage returns a context whose parsers record their slices in coverage
+func WithCoverage(ctx context.Context, coverage *Coverage) context.Context {
+	return context.WithValue(ctx, coverageCtxKey{}, coverage)
+}
+
+// coverageFrom returns the collector attached to ctx, or nil
+func coverageFrom(ctx context.Context) *Coverage {
+	coverage, _ := ctx.Value(coverageCtxKey{}).(*Coverage)
+	return coverage
+}
+
+// Record notes the outcome of one search of a city and deal type. A slice
+// searched several times is complete only if every search returned without
+// error, including the IncompleteError of a search cut short.
+func (c *Coverage) Record(city, dealType string, err error) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := coverageKey{city: city, dealType: dealType}
+	complete, seen := c.slices[key]
+	c.slices[key] = err == nil && (complete || !seen)
+}
+
+// Complete reports whether the run enumerated all listings of a city and deal type
+func (c *Coverage) Complete(city, dealType string) bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.slices[coverageKey{city: city, dealType: dealType}]
+}
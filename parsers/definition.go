@@ -0,0 +1,413 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SiteDefinition describes how to scrape a listing site with CSS selectors,
+// so selector fixes can be shipped as data instead of a code release
+type SiteDefinition struct {
+	Name     string `yaml:"name" json:"name"`
+	Enabled  *bool  `yaml:"enabled" json:"enabled"` // defaults to true
+	Kind     string `yaml:"kind" json:"kind"`       // "commercial" (live listings) or "opendata"
+	BaseURL  string `yaml:"base_url" json:"base_url"`
+	Country  string `yaml:"country" json:"country"`
+	Currency string `yaml:"currency" json:"currency"`
+
+	// PropertyType is used when no type field is configured
+	PropertyType string `yaml:"property_type" json:"property_type"`
+
+	Cities    []CityDefinition     `yaml:"cities" json:"cities"`
+	DealTypes []DealTypeDefinition `yaml:"deal_types" json:"deal_types"`
+
+	// SearchURL is a text/template rendered with SearchURLData
+	SearchURL string `yaml:"search_url" json:"search_url"`
+
+	Pagination PaginationDefinition `yaml:"pagination" json:"pagination"`
+
+	// Cards lists card selectors tried in order until one matches
+	Cards  []string                   `yaml:"cards" json:"cards"`
+	Fields map[string]FieldDefinition `yaml:"fields" json:"fields"`
+
+	// Details extracts fields from a listing's own page (optional)
+	Details DetailsDefinition `yaml:"details" json:"details"`
+
+	// Required lists fields a card must yield to be kept (price and external_id are always required)
+	Required []string `yaml:"required" json:"required"`
+
+	// GeocodeSuffix is appended to "address, city" when coordinates are missing
+	Geocode       bool   `yaml:"geocode" json:"geocode"`
+	GeocodeSuffix string `yaml:"geocode_suffix" json:"geocode_suffix"`
+
+	searchTemplate *template.Template
+}
+
+// CityDefinition is a city to scrape; Query is what goes into the search URL
+// and defaults to Name
+type CityDefinition struct {
+	Name  string `yaml:"name" json:"name"`
+	Query string `yaml:"query" json:"query"`
+}
+
+// DealTypeDefinition maps a deal type (sale, rent) to its site-specific URL part
+type DealTypeDefinition struct {
+	Name string `yaml:"name" json:"name"`
+	Path string `yaml:"path" json:"path"`
+}
+
+// PaginationDefinition describes how to reach further result pages.
+// A search_url that uses .Page or .Offset is paginated by parameter;
+// Next additionally follows a "next page" link when the page has one.
+type PaginationDefinition struct {
+	Next     string `yaml:"next" json:"next"`
+	MaxPages int    `yaml:"max_pages" json:"max_pages"`
+	// PageSize is the number of results per page, used to compute .Offset
+	PageSize int `yaml:"page_size" json:"page_size"`
+}
+
+// DetailsDefinition describes a listing's detail page. Selectors are relative
+// to the whole page; every image matched by Images is collected.
+type DetailsDefinition struct {
+	Fields map[string]FieldDefinition `yaml:"fields" json:"fields"`
+	Images FieldDefinition            `yaml:"images" json:"images"`
+}
+
+// FieldDefinition extracts one property field from a card
+type FieldDefinition struct {
+	// Selector is relative to the card; empty means the card itself
+	Selector string `yaml:"selector" json:"selector"`
+	// Attr reads an attribute instead of the element text
+	Attr string `yaml:"attr" json:"attr"`
+	// Regex post-processes the value; the first capture group is used if present
+	Regex string `yaml:"regex" json:"regex"`
+	// Multipliers scale numeric values when the raw text contains a marker,
+	// e.g. {"млн": 1000000} or {"sq ft": 0.092903}
+	Multipliers map[string]float64 `yaml:"multipliers" json:"multipliers"`
+
+	regex *regexp.Regexp
+}
+
+// SearchURLData is passed to the search URL template
+type SearchURLData struct {
+	BaseURL  string
+	City     string
+	Query    string
+	DealType string
+	DealPath string
+	Page     int
+	Offset   int // (Page-1) * pagination.page_size
+}
+
+// definitionFields are the property fields a definition may extract
+var definitionFields = map[string]bool{
+	"price": true, "external_id": true, "url": true, "address": true, "city": true,
+	"district": true, "type": true, "area": true, "rooms": true, "bedrooms": true,
+	"bathrooms": true, "floor": true, "total_floors": true, "year_built": true,
+	"latitude": true, "longitude": true, "description": true,
+}
+
+var templateFuncs = template.FuncMap{
+	"lower":   strings.ToLower,
+	"upper":   strings.ToUpper,
+	"replace": strings.ReplaceAll,
+}
+
+// IsEnabled reports whether the definition should be registered
+func (d *SiteDefinition) IsEnabled() bool {
+	return d.Enabled == nil || *d.Enabled
+}
+
+// SourceInfo describes the definition as a selectable source
+func (d *SiteDefinition) SourceInfo() SourceInfo {
+	def := *d
+	kind := d.Kind
+	if kind == "" {
+		kind = KindCommercial
+	}
+
+	cities := make([]string, 0, len(d.Cities))
+	for _, city := range d.Cities {
+		cities = append(cities, city.Name)
+	}
+
+	return SourceInfo{
+		Name:       d.Name,
+		Kind:       kind,
+		Country:    d.Country,
+		Cities:     cities,
+		Definition: true,
+		New: func() Parser {
+			return NewDefinitionParser(def)
+		},
+	}
+}
+
+// Validate checks the definition and compiles its template and regexes
+func (d *SiteDefinition) Validate() error {
+	if d.Name == "" {
+		return fmt.Errorf("definition has no name")
+	}
+	if d.BaseURL == "" {
+		return fmt.Errorf("%s: base_url is required", d.Name)
+	}
+	if d.SearchURL == "" {
+		return fmt.Errorf("%s: search_url is required", d.Name)
+	}
+	if len(d.Cards) == 0 {
+		return fmt.Errorf("%s: at least one card selector is required", d.Name)
+	}
+	if len(d.Cities) == 0 {
+		return fmt.Errorf("%s: at least one city is required", d.Name)
+	}
+	for _, field := range []string{"price", "external_id"} {
+		if _, ok := d.Fields[field]; !ok {
+			return fmt.Errorf("%s: field %q is required", d.Name, field)
+		}
+	}
+
+	tmpl, err := template.New(d.Name).Funcs(templateFuncs).Parse(d.SearchURL)
+	if err != nil {
+		return fmt.Errorf("%s: invalid search_url: %w", d.Name, err)
+	}
+	d.searchTemplate = tmpl
+
+	for name, field := range d.Fields {
+		if !definitionFields[name] {
+			return fmt.Errorf("%s: unknown field %q", d.Name, name)
+		}
+		if field.Regex != "" {
+			re, err := regexp.Compile(field.Regex)
+			if err != nil {
+				return fmt.Errorf("%s: invalid regex for %s: %w", d.Name, name, err)
+			}
+			field.regex = re
+			d.Fields[name] = field
+		}
+	}
+
+	for name, field := range d.Details.Fields {
+		if !definitionFields[name] {
+			return fmt.Errorf("%s: unknown detail field %q", d.Name, name)
+		}
+		if field.Regex != "" {
+			re, err := regexp.Compile(field.Regex)
+			if err != nil {
+				return fmt.Errorf("%s: invalid regex for detail %s: %w", d.Name, name, err)
+			}
+			field.regex = re
+			d.Details.Fields[name] = field
+		}
+	}
+
+	for i := range d.Cities {
+		if d.Cities[i].Query == "" {
+			d.Cities[i].Query = d.Cities[i].Name
+		}
+	}
+	if len(d.DealTypes) == 0 {
+		d.DealTypes = []DealTypeDefinition{{Name: "sale"}}
+	}
+	if d.PropertyType == "" {
+		d.PropertyType = "apartment"
+	}
+	if d.Currency == "" {
+		d.Currency = "USD"
+	}
+
+	return nil
+}
+
+// BuildSearchURL renders the search URL for a city, deal type and page
+func (d *SiteDefinition) BuildSearchURL(city CityDefinition, deal DealTypeDefinition, page int) (string, error) {
+	var buf bytes.Buffer
+	err := d.searchTemplate.Execute(&buf, SearchURLData{
+		BaseURL:  strings.TrimRight(d.BaseURL, "/"),
+		City:     city.Name,
+		Query:    city.Query,
+		DealType: deal.Name,
+		DealPath: deal.Path,
+		Page:     page,
+		Offset:   (page - 1) * d.Pagination.PageSize,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render search URL: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// Extract applies the regex to a raw value
+func (f *FieldDefinition) Extract(raw string) string {
+	value := strings.TrimSpace(raw)
+	if f.regex == nil {
+		return value
+	}
+
+	matches := f.regex.FindStringSubmatch(value)
+	if len(matches) == 0 {
+		return ""
+	}
+	if len(matches) > 1 {
+		return strings.TrimSpace(matches[1])
+	}
+	return strings.TrimSpace(matches[0])
+}
+
+// Number parses an extracted value as a number and applies multipliers
+// matched against the raw text
+func (f *FieldDefinition) Number(raw string) float64 {
+	value := parseNumber(f.Extract(raw))
+	if value == 0 || len(f.Multipliers) == 0 {
+		return value
+	}
+
+	// Check longer markers first so "sq ft" wins over "ft"
+	markers := make([]string, 0, len(f.Multipliers))
+	for marker := range f.Multipliers {
+		markers = append(markers, marker)
+	}
+	sort.Slice(markers, func(i, j int) bool {
+		if len(markers[i]) != len(markers[j]) {
+			return len(markers[i]) > len(markers[j])
+		}
+		return markers[i] < markers[j]
+	})
+
+	lower := strings.ToLower(raw)
+	for _, marker := range markers {
+		if strings.Contains(lower, strings.ToLower(marker)) {
+			return value * f.Multipliers[marker]
+		}
+	}
+	return value
+}
+
+// parseNumber reads a number written with any common grouping convention:
+// "12 500 000", "1,234,567", "1.234.567", "45,5" or "45.5"
+func parseNumber(text string) float64 {
+	negative := strings.HasPrefix(strings.TrimSpace(text), "-")
+
+	var b strings.Builder
+	for _, r := range text {
+		if (r >= '0' && r <= '9') || r == '.' || r == ',' {
+			b.WriteRune(r)
+		}
+	}
+	cleaned := strings.Trim(b.String(), ".,")
+	if cleaned == "" {
+		return 0
+	}
+
+	commas := strings.Count(cleaned, ",")
+	dots := strings.Count(cleaned, ".")
+
+	switch {
+	case commas > 0 && dots > 0:
+		// The later separator is the decimal one
+		if strings.LastIndex(cleaned, ",") > strings.LastIndex(cleaned, ".") {
+			cleaned = strings.ReplaceAll(cleaned, ".", "")
+			cleaned = strings.Replace(cleaned, ",", ".", 1)
+		} else {
+			cleaned = strings.ReplaceAll(cleaned, ",", "")
+		}
+	case commas > 1:
+		cleaned = strings.ReplaceAll(cleaned, ",", "")
+	case dots > 1:
+		cleaned = strings.ReplaceAll(cleaned, ".", "")
+	case commas == 1:
+		if isThousandsGroup(cleaned, ",") {
+			cleaned = strings.ReplaceAll(cleaned, ",", "")
+		} else {
+			cleaned = strings.Replace(cleaned, ",", ".", 1)
+		}
+	case dots == 1:
+		if isThousandsGroup(cleaned, ".") {
+			cleaned = strings.ReplaceAll(cleaned, ".", "")
+		}
+	}
+
+	value, err := strconv.ParseFloat(cleaned, 64)
+	if err != nil {
+		return 0
+	}
+	if negative {
+		return -value
+	}
+	return value
+}
+
+// isThousandsGroup reports whether a single separator is followed by exactly
+// three digits, as in "1,234" or "250.000"
+func isThousandsGroup(text, sep string) bool {
+	idx := strings.Index(text, sep)
+	return idx > 0 && len(text)-idx-1 == 3
+}
+
+// LoadDefinitions reads all .yaml, .yml and .json site definitions in a directory.
+// A missing directory is not an error and yields no definitions.
+func LoadDefinitions(dir string) ([]SiteDefinition, error) {
+	if dir == "" {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read definitions directory: %w", err)
+	}
+
+	var definitions []SiteDefinition
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if ext != ".yaml" && ext != ".yml" && ext != ".json" {
+			continue
+		}
+
+		def, err := LoadDefinition(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		definitions = append(definitions, *def)
+	}
+
+	return definitions, nil
+}
+
+// LoadDefinition reads and validates a single site definition file
+func LoadDefinition(path string) (*SiteDefinition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read definition %s: %w", path, err)
+	}
+
+	var def SiteDefinition
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		err = json.Unmarshal(data, &def)
+	} else {
+		err = yaml.Unmarshal(data, &def)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode definition %s: %w", path, err)
+	}
+
+	if err := def.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid definition %s: %w", path, err)
+	}
+
+	return &def, nil
+}
@@ -0,0 +1,315 @@
+package parsers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"pricemap-go/models"
+	"pricemap-go/utils"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// DefinitionParser scrapes a listing site described by a SiteDefinition
+type DefinitionParser struct {
+	*BaseParser
+	def       SiteDefinition
+	geocoding *utils.GeocodingService
+}
+
+// NewDefinitionParser creates a parser from a validated site definition
+func NewDefinitionParser(def SiteDefinition) *DefinitionParser {
+	dp := &DefinitionParser{
+		BaseParser: NewBaseParser(def.BaseURL),
+		def:        def,
+	}
+	if def.Geocode {
+		dp.geocoding = utils.NewGeocodingService()
+	}
+	return dp
+}
+
+func (dp *DefinitionParser) Name() string {
+	return dp.def.Name
+}
+
+// TracksListings reports whether the site lists live offers
+func (dp *DefinitionParser) TracksListings() bool {
+	return dp.def.Kind != "opendata"
+}
+
+// Definition returns the site definition the parser was built from
+func (dp *DefinitionParser) Definition() SiteDefinition {
+	return dp.def
+}
+
+// HasDetailPages reports whether the definition describes detail pages
+func (dp *DefinitionParser) HasDetailPages() bool {
+	return len(dp.def.Details.Fields) > 0 || dp.def.Details.Images.Selector != ""
+}
+
+// FetchDetails visits the listing page and applies the definition's detail fields
+func (dp *DefinitionParser) FetchDetails(ctx context.Context, property *models.Property) error {
+	doc, err := dp.fetchDetailPage(ctx, property)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s listing: %w", dp.Name(), err)
+	}
+
+	details := dp.parseDetails(doc)
+	details.Apply(property)
+	return nil
+}
+
+func (dp *DefinitionParser) parseDetails(doc *goquery.Document) ListingDetails {
+	// Extract into a scratch property so field handling matches the search cards
+	var scratch models.Property
+	for name, field := range dp.def.Details.Fields {
+		raw := dp.rawValue(doc.Selection, field)
+		if field.Extract(raw) == "" {
+			continue
+		}
+		dp.setField(&scratch, name, field, raw)
+	}
+
+	details := ListingDetails{
+		Floor:       scratch.Floor,
+		TotalFloors: scratch.TotalFloors,
+		YearBuilt:   scratch.YearBuilt,
+		Bathrooms:   scratch.Bathrooms,
+		Description: scratch.Description,
+		Area:        scratch.Area,
+		Rooms:       scratch.Rooms,
+		Bedrooms:    scratch.Bedrooms,
+		Latitude:    scratch.Latitude,
+		Longitude:   scratch.Longitude,
+	}
+
+	if images := dp.def.Details.Images; images.Selector != "" {
+		attr := images.Attr
+		if attr == "" {
+			attr = "src"
+		}
+		for _, src := range collectImages(doc, images.Selector, attr) {
+			details.Images = append(details.Images, dp.absoluteURL(src))
+		}
+	}
+
+	return details
+}
+
+// SetCities limits the parser to the named cities of its definition
+func (dp *DefinitionParser) SetCities(cities []string) {
+	var kept []CityDefinition
+	for _, city := range dp.def.Cities {
+		if matchesAnyCity(city.Name, cities) {
+			kept = append(kept, city)
+		}
+	}
+	dp.def.Cities = kept
+}
+
+func (dp *DefinitionParser) Parse(ctx context.Context) ([]models.Property, error) {
+	var allProperties []models.Property
+
+	for _, city := range dp.def.Cities {
+		for _, deal := range dp.def.DealTypes {
+			select {
+			case <-ctx.Done():
+				log.Printf("Context cancelled. Returning %d properties parsed so far.", len(allProperties))
+				return allProperties, ctx.Err()
+			default:
+			}
+
+			properties, err := dp.parseCity(ctx, city, deal)
+			coverageFrom(ctx).Record(city.Name, deal.Name, err)
+			// A search cut short still returns the listings it found
+			if err != nil && !IsIncomplete(err) {
+				log.Printf("Error parsing %s/%s from %s: %v", city.Name, deal.Name, dp.Name(), err)
+				continue
+			}
+			allProperties = append(allProperties, properties...)
+		}
+	}
+
+	log.Printf("Parsed %d properties from %s", len(allProperties), dp.Name())
+	return allProperties, nil
+}
+
+func (dp *DefinitionParser) parseCity(ctx context.Context, city CityDefinition, deal DealTypeDefinition) ([]models.Property, error) {
+	url, err := dp.def.BuildSearchURL(city, deal, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	pagination := Pagination{
+		URL:          url,
+		MaxPages:     dp.def.Pagination.MaxPages,
+		NextSelector: dp.def.Pagination.Next,
+	}
+	// Only templates that use .Page or .Offset render different page URLs
+	if next, err := dp.def.BuildSearchURL(city, deal, 2); err == nil && next != url {
+		pagination.PageURL = func(page int) string {
+			pageURL, _ := dp.def.BuildSearchURL(city, deal, page)
+			return pageURL
+		}
+	}
+
+	properties, err := dp.FetchPages(ctx, dp.Name(), pagination, func(doc *goquery.Document) ([]models.Property, error) {
+		return dp.parseDocument(doc, city.Name, deal.Name), nil
+	})
+	if err != nil && !IsIncomplete(err) {
+		return nil, fmt.Errorf("failed to fetch %s listings: %w", dp.Name(), err)
+	}
+	return properties, err
+}
+
+// ParseListings extracts properties from a search results page
+func (dp *DefinitionParser) ParseListings(body io.Reader, city, dealType string) ([]models.Property, error) {
+	doc, err := goquery.NewDocumentFromReader(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	return dp.parseDocument(doc, city, dealType), nil
+}
+
+func (dp *DefinitionParser) parseDocument(doc *goquery.Document, city, dealType string) []models.Property {
+	var properties []models.Property
+	for _, cardSelector := range dp.def.Cards {
+		doc.Find(cardSelector).Each(func(i int, s *goquery.Selection) {
+			property := dp.parseCard(s, city, dealType)
+			if property != nil {
+				properties = append(properties, *property)
+			}
+		})
+
+		// Fall back to the next card selector only if nothing matched
+		if len(properties) > 0 {
+			break
+		}
+	}
+
+	return properties
+}
+
+func (dp *DefinitionParser) parseCard(s *goquery.Selection, city, dealType string) *models.Property {
+	property := &models.Property{
+		Source:    dp.Name(),
+		ScrapedAt: time.Now(),
+		IsActive:  true,
+		Currency:  dp.def.Currency,
+		Country:   dp.def.Country,
+		City:      city,
+		DealType:  dealType,
+		Type:      dp.def.PropertyType,
+	}
+
+	found := make(map[string]bool)
+	for name, field := range dp.def.Fields {
+		raw := dp.rawValue(s, field)
+		if field.Extract(raw) == "" {
+			continue
+		}
+		if dp.setField(property, name, field, raw) {
+			found[name] = true
+		}
+	}
+
+	if property.Price <= 0 || property.ExternalID == "" {
+		return nil
+	}
+	for _, name := range dp.def.Required {
+		if !found[name] {
+			return nil
+		}
+	}
+
+	if property.Latitude == 0 && property.Longitude == 0 && property.Address != "" && dp.geocoding != nil {
+		lat, lng, err := dp.geocoding.GeocodeAddress(property.Address + ", " + property.City + dp.def.GeocodeSuffix)
+		if err == nil {
+			property.Latitude = lat
+			property.Longitude = lng
+		}
+	}
+
+	return property
+}
+
+func (dp *DefinitionParser) rawValue(s *goquery.Selection, field FieldDefinition) string {
+	sel := s
+	if field.Selector != "" {
+		sel = s.Find(field.Selector).First()
+	}
+	if sel.Length() == 0 {
+		return ""
+	}
+	if field.Attr != "" {
+		value, _ := sel.Attr(field.Attr)
+		return value
+	}
+	return sel.Text()
+}
+
+// setField stores a field value on the property and reports whether it was usable
+func (dp *DefinitionParser) setField(property *models.Property, name string, field FieldDefinition, raw string) bool {
+	switch name {
+	case "external_id":
+		property.ExternalID = field.Extract(raw)
+	case "url":
+		property.URL = dp.absoluteURL(field.Extract(raw))
+	case "address":
+		property.Address = field.Extract(raw)
+	case "city":
+		property.City = field.Extract(raw)
+	case "district":
+		property.District = field.Extract(raw)
+	case "type":
+		property.Type = strings.ToLower(field.Extract(raw))
+	case "description":
+		property.Description = field.Extract(raw)
+	case "price":
+		property.Price = field.Number(raw)
+		return property.Price > 0
+	case "area":
+		property.Area = field.Number(raw)
+		return property.Area > 0
+	case "latitude":
+		property.Latitude = field.Number(raw)
+		return property.Latitude != 0
+	case "longitude":
+		property.Longitude = field.Number(raw)
+		return property.Longitude != 0
+	case "rooms":
+		property.Rooms = int(field.Number(raw))
+		return property.Rooms > 0
+	case "bedrooms":
+		property.Bedrooms = int(field.Number(raw))
+		return property.Bedrooms > 0
+	case "bathrooms":
+		property.Bathrooms = int(field.Number(raw))
+		return property.Bathrooms > 0
+	case "floor":
+		property.Floor = int(field.Number(raw))
+		return property.Floor > 0
+	case "total_floors":
+		property.TotalFloors = int(field.Number(raw))
+		return property.TotalFloors > 0
+	case "year_built":
+		property.YearBuilt = int(field.Number(raw))
+		return property.YearBuilt > 0
+	default:
+		return false
+	}
+	return true
+}
+
+func (dp *DefinitionParser) absoluteURL(href string) string {
+	if href == "" || strings.HasPrefix(href, "http") {
+		return href
+	}
+	return strings.TrimRight(dp.def.BaseURL, "/") + "/" + strings.TrimLeft(href, "/")
+}
@@ -0,0 +1,153 @@
+package parsers
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadDefinitions_Shipped(t *testing.T) {
+	definitions, err := LoadDefinitions("../definitions")
+	if err != nil {
+		t.Fatalf("LoadDefinitions() error = %v", err)
+	}
+
+	names := make(map[string]bool)
+	for _, def := range definitions {
+		names[def.Name] = true
+		if def.IsEnabled() {
+			t.Errorf("shipped definition %s should be disabled by default", def.Name)
+		}
+	}
+
+	for _, want := range []string{"cian", "rightmove", "zillow", "idealista"} {
+		if !names[want] {
+			t.Errorf("LoadDefinitions() missing definition %s", want)
+		}
+	}
+}
+
+func TestLoadDefinitions_MissingDir(t *testing.T) {
+	definitions, err := LoadDefinitions(filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Fatalf("LoadDefinitions() error = %v, want nil for missing directory", err)
+	}
+	if len(definitions) != 0 {
+		t.Errorf("LoadDefinitions() = %d definitions, want 0", len(definitions))
+	}
+}
+
+func TestLoadDefinition_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "broken.json")
+	content := `{"name": "broken", "base_url": "https://example.com", "search_url": "{{.BaseURL}}",
+		"cities": [{"name": "X"}], "cards": [".card"], "fields": {"price": {"selector": ".p", "regex": "("}}}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadDefinition(path); err == nil {
+		t.Errorf("LoadDefinition() should fail without external_id field and with invalid regex")
+	}
+}
+
+func TestSiteDefinition_BuildSearchURL(t *testing.T) {
+	def, err := LoadDefinition("../definitions/idealista.yaml")
+	if err != nil {
+		t.Fatalf("LoadDefinition() error = %v", err)
+	}
+
+	url, err := def.BuildSearchURL(CityDefinition{Name: "Madrid", Query: "Madrid"}, def.DealTypes[1], 1)
+	if err != nil {
+		t.Fatalf("BuildSearchURL() error = %v", err)
+	}
+
+	want := "https://www.idealista.com/alquiler-viviendas/madrid/"
+	if url != want {
+		t.Errorf("BuildSearchURL() = %v, want %v", url, want)
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		text string
+		want float64
+	}{
+		{"12 500 000 ₽", 12500000},
+		{"£1,250,000", 1250000},
+		{"250.000 €", 250000},
+		{"1.234.567 €", 1234567},
+		{"45,5 м²", 45.5},
+		{"45.5", 45.5},
+		{"1,234.56", 1234.56},
+		{"1.234,56", 1234.56},
+		{"-0.1276", -0.1276},
+		{"no digits", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := parseNumber(tt.text); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("parseNumber(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefinitionParser_ParseListings(t *testing.T) {
+	def, err := LoadDefinition("../definitions/rightmove.yaml")
+	if err != nil {
+		t.Fatalf("LoadDefinition() error = %v", err)
+	}
+	def.Geocode = false
+
+	html := `<html><body><div class="l-searchResults">
+		<div class="propertyCard" data-lat="51.5014" data-lng="-0.1419">
+			<a class="propertyCard-link" href="/properties/123456789#/">Flat</a>
+			<div class="propertyCard-price">£1,250,000</div>
+			<address class="propertyCard-address">Buckingham Gate, London</address>
+			<div class="propertyCard-details">2 bedrooms, 1,000 sq ft</div>
+		</div>
+		<div class="propertyCard">
+			<a class="propertyCard-link" href="/properties/987654321">No price</a>
+		</div>
+	</div></body></html>`
+
+	dp := NewDefinitionParser(*def)
+	properties, err := dp.ParseListings(strings.NewReader(html), "London", "sale")
+	if err != nil {
+		t.Fatalf("ParseListings() error = %v", err)
+	}
+
+	if len(properties) != 1 {
+		t.Fatalf("ParseListings() returned %d properties, want 1", len(properties))
+	}
+
+	p := properties[0]
+	if p.Source != "rightmove" || p.ExternalID != "123456789" {
+		t.Errorf("source/external_id = %s/%s, want rightmove/123456789", p.Source, p.ExternalID)
+	}
+	if p.URL != "https://www.rightmove.co.uk/properties/123456789#/" {
+		t.Errorf("URL = %v", p.URL)
+	}
+	if p.Price != 1250000 || p.Currency != "GBP" {
+		t.Errorf("price = %v %s, want 1250000 GBP", p.Price, p.Currency)
+	}
+	if p.Bedrooms != 2 {
+		t.Errorf("Bedrooms = %v, want 2", p.Bedrooms)
+	}
+	if math.Abs(p.Area-92.903) > 0.01 {
+		t.Errorf("Area = %v, want ~92.9 m²", p.Area)
+	}
+	if p.Latitude != 51.5014 || p.Longitude != -0.1419 {
+		t.Errorf("coordinates = %v,%v, want 51.5014,-0.1419", p.Latitude, p.Longitude)
+	}
+	if p.City != "London" || p.DealType != "sale" || p.Country != "United Kingdom" {
+		t.Errorf("city/deal/country = %s/%s/%s", p.City, p.DealType, p.Country)
+	}
+	if !dp.TracksListings() {
+		t.Errorf("commercial definition should track listings")
+	}
+}
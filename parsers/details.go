@@ -0,0 +1,278 @@
+package parsers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"pricemap-go/models"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ListingDetails are the fields read from a listing's own page
+type ListingDetails struct {
+	Floor       int
+	TotalFloors int
+	YearBuilt   int
+	Bathrooms   int
+	Description string
+	Images      []string
+
+	// Also shown on search cards; used only when the card lacked them
+	Area      float64
+	Rooms     int
+	Bedrooms  int
+	Latitude  float64
+	Longitude float64
+}
+
+// Apply copies details onto a property. Fields only a detail page has are
+// replaced when found; fields the search card provides are only filled in.
+func (d *ListingDetails) Apply(property *models.Property) {
+	if d.Floor > 0 {
+		property.Floor = d.Floor
+	}
+	if d.TotalFloors > 0 {
+		property.TotalFloors = d.TotalFloors
+	}
+	if d.YearBuilt > 0 {
+		property.YearBuilt = d.YearBuilt
+	}
+	if d.Bathrooms > 0 {
+		property.Bathrooms = d.Bathrooms
+	}
+	if d.Description != "" {
+		property.Description = d.Description
+	}
+	if len(d.Images) > 0 {
+		property.Images = d.Images
+	}
+
+	if property.Area == 0 {
+		property.Area = d.Area
+	}
+	if property.Rooms == 0 {
+		property.Rooms = d.Rooms
+	}
+	if property.Bedrooms == 0 {
+		property.Bedrooms = d.Bedrooms
+	}
+	if property.Latitude == 0 && property.Longitude == 0 {
+		property.Latitude = d.Latitude
+		property.Longitude = d.Longitude
+	}
+}
+
+// fillFrom copies the values d is missing from other
+func (d *ListingDetails) fillFrom(other ListingDetails) {
+	fillInt := func(dst *int, v int) {
+		if *dst == 0 {
+			*dst = v
+		}
+	}
+	fillInt(&d.Floor, other.Floor)
+	fillInt(&d.TotalFloors, other.TotalFloors)
+	fillInt(&d.YearBuilt, other.YearBuilt)
+	fillInt(&d.Bathrooms, other.Bathrooms)
+	fillInt(&d.Rooms, other.Rooms)
+	fillInt(&d.Bedrooms, other.Bedrooms)
+	if d.Description == "" {
+		d.Description = other.Description
+	}
+	if len(d.Images) == 0 {
+		d.Images = other.Images
+	}
+	if d.Area == 0 {
+		d.Area = other.Area
+	}
+	if d.Latitude == 0 && d.Longitude == 0 {
+		d.Latitude = other.Latitude
+		d.Longitude = other.Longitude
+	}
+}
+
+// fetchDetailPage downloads a listing page through Fetch, so detail visits share
+// the parser's rate limiting and Tor circuit rotation
+func (bp *BaseParser) fetchDetailPage(ctx context.Context, property *models.Property) (*goquery.Document, error) {
+	if property.URL == "" {
+		return nil, fmt.Errorf("property %s has no URL", property.ExternalID)
+	}
+
+	// Fragments are client-side state and never reach the server
+	pageURL := property.URL
+	if u, err := url.Parse(pageURL); err == nil {
+		u.Fragment = ""
+		pageURL = u.String()
+	}
+
+	return bp.fetchDocument(ctx, pageURL)
+}
+
+// structuredDetails reads schema.org JSON-LD blocks, which most listing sites
+// embed for search engines
+func structuredDetails(doc *goquery.Document) ListingDetails {
+	var details ListingDetails
+
+	doc.Find("script[type='application/ld+json']").Each(func(i int, s *goquery.Selection) {
+		var data interface{}
+		if err := json.Unmarshal([]byte(s.Text()), &data); err != nil {
+			return
+		}
+		for _, node := range jsonLDNodes(data) {
+			details.fillFrom(jsonLDDetails(node))
+		}
+	})
+
+	return details
+}
+
+// jsonLDNodes flattens top-level arrays and @graph containers into objects
+func jsonLDNodes(data interface{}) []map[string]interface{} {
+	var nodes []map[string]interface{}
+	switch v := data.(type) {
+	case []interface{}:
+		for _, item := range v {
+			nodes = append(nodes, jsonLDNodes(item)...)
+		}
+	case map[string]interface{}:
+		nodes = append(nodes, v)
+		if graph, ok := v["@graph"]; ok {
+			nodes = append(nodes, jsonLDNodes(graph)...)
+		}
+	}
+	return nodes
+}
+
+func jsonLDDetails(node map[string]interface{}) ListingDetails {
+	details := ListingDetails{
+		Floor:       int(jsonLDNumber(node["floorLevel"])),
+		YearBuilt:   int(jsonLDNumber(node["yearBuilt"])),
+		Bathrooms:   int(jsonLDNumber(node["numberOfBathroomsTotal"])),
+		Rooms:       int(jsonLDNumber(node["numberOfRooms"])),
+		Bedrooms:    int(jsonLDNumber(node["numberOfBedrooms"])),
+		Description: strings.TrimSpace(jsonLDString(node["description"])),
+		Images:      jsonLDImages(node["image"]),
+	}
+
+	if size, ok := node["floorSize"].(map[string]interface{}); ok {
+		details.Area = jsonLDNumber(size["value"])
+		// FTK is the UN/CEFACT code for square feet
+		if unit := jsonLDString(size["unitCode"]); strings.EqualFold(unit, "FTK") || strings.EqualFold(unit, "sqft") {
+			details.Area *= 0.092903
+		}
+	}
+
+	if geo, ok := node["geo"].(map[string]interface{}); ok {
+		details.Latitude = jsonLDNumber(geo["latitude"])
+		details.Longitude = jsonLDNumber(geo["longitude"])
+	}
+
+	// Listings often describe the dwelling as the offer's item
+	for _, key := range []string{"mainEntity", "itemOffered", "about"} {
+		if nested, ok := node[key].(map[string]interface{}); ok {
+			details.fillFrom(jsonLDDetails(nested))
+		}
+	}
+
+	return details
+}
+
+func jsonLDString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+// jsonLDNumber reads numbers that may be encoded as JSON numbers, strings or
+// QuantitativeValue objects
+func jsonLDNumber(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case string:
+		return parseNumber(v)
+	case map[string]interface{}:
+		return jsonLDNumber(v["value"])
+	}
+	return 0
+}
+
+func jsonLDImages(value interface{}) []string {
+	var images []string
+	switch v := value.(type) {
+	case string:
+		if v != "" {
+			images = append(images, v)
+		}
+	case []interface{}:
+		for _, item := range v {
+			images = append(images, jsonLDImages(item)...)
+		}
+	case map[string]interface{}:
+		if u := jsonLDString(v["url"]); u != "" {
+			images = append(images, u)
+		} else if u := jsonLDString(v["contentUrl"]); u != "" {
+			images = append(images, u)
+		}
+	}
+	return images
+}
+
+// collectImages returns the distinct absolute image URLs matched by a selector
+func collectImages(doc *goquery.Document, selector string, attrs ...string) []string {
+	var images []string
+	seen := make(map[string]bool)
+
+	doc.Find(selector).Each(func(i int, s *goquery.Selection) {
+		for _, attr := range attrs {
+			src, ok := s.Attr(attr)
+			src = strings.TrimSpace(src)
+			if !ok || src == "" || strings.HasPrefix(src, "data:") {
+				continue
+			}
+			if strings.HasPrefix(src, "//") {
+				src = "https:" + src
+			}
+			if !seen[src] {
+				seen[src] = true
+				images = append(images, src)
+			}
+			break
+		}
+	})
+
+	return images
+}
+
+// detailText joins the text of all elements matched by a selector, one per line
+func detailText(doc *goquery.Document, selector string) string {
+	var lines []string
+	doc.Find(selector).Each(func(i int, s *goquery.Selection) {
+		if text := strings.Join(strings.Fields(s.Text()), " "); text != "" {
+			lines = append(lines, text)
+		}
+	})
+	return strings.Join(lines, "\n")
+}
+
+// matchInt returns the integer in the given capture group of the first match
+func matchInt(re *regexp.Regexp, text string, group int) int {
+	matches := re.FindStringSubmatch(text)
+	if len(matches) <= group {
+		return 0
+	}
+	value, err := strconv.Atoi(matches[group])
+	if err != nil {
+		return 0
+	}
+	return value
+}
@@ -0,0 +1,88 @@
+package parsers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"pricemap-go/models"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestListingDetails_Apply(t *testing.T) {
+	property := &models.Property{
+		Area:        54,
+		Rooms:       2,
+		Latitude:    55.76,
+		Longitude:   37.61,
+		Floor:       3,
+		Description: "old description",
+	}
+
+	details := ListingDetails{
+		Floor:       7,
+		TotalFloors: 12,
+		YearBuilt:   1955,
+		Description: "new description",
+		Images:      []string{"https://img.example.com/1.jpg"},
+		Area:        56,
+		Rooms:       3,
+		Bedrooms:    1,
+		Latitude:    1,
+		Longitude:   1,
+	}
+	details.Apply(property)
+
+	// Detail-only fields are replaced
+	if property.Floor != 7 || property.TotalFloors != 12 || property.YearBuilt != 1955 {
+		t.Errorf("Apply() floor/total/year = %d/%d/%d, want 7/12/1955", property.Floor, property.TotalFloors, property.YearBuilt)
+	}
+	if property.Description != "new description" || len(property.Images) != 1 {
+		t.Errorf("Apply() should replace description and images")
+	}
+
+	// Card fields are only filled in
+	if property.Area != 54 || property.Rooms != 2 {
+		t.Errorf("Apply() area/rooms = %v/%d, want card values 54/2", property.Area, property.Rooms)
+	}
+	if property.Latitude != 55.76 || property.Longitude != 37.61 {
+		t.Errorf("Apply() should keep card coordinates")
+	}
+	if property.Bedrooms != 1 {
+		t.Errorf("Apply() bedrooms = %d, want 1 (missing on card)", property.Bedrooms)
+	}
+}
+
+func TestStructuredDetails(t *testing.T) {
+	html := `<html><head>
+<script type="application/ld+json">not json</script>
+<script type="application/ld+json">[{"@type":"Organization","name":"Agency"},{"@type":"Offer",
+  "itemOffered":{"@type":"Apartment","numberOfRooms":"3","numberOfBathroomsTotal":2,"yearBuilt":1987,
+  "floorLevel":"5","floorSize":{"value":"1,000","unitCode":"FTK"},
+  "geo":{"latitude":"40.1","longitude":-73.2},
+  "image":["https://img.example.com/a.jpg",{"url":"https://img.example.com/b.jpg"}],
+  "description":"  Corner unit  "}}]</script>
+</head><body></body></html>`
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := structuredDetails(doc)
+	want := ListingDetails{
+		Floor:       5,
+		YearBuilt:   1987,
+		Bathrooms:   2,
+		Rooms:       3,
+		Description: "Corner unit",
+		Images:      []string{"https://img.example.com/a.jpg", "https://img.example.com/b.jpg"},
+		Area:        92.903,
+		Latitude:    40.1,
+		Longitude:   -73.2,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("structuredDetails() = %+v, want %+v", got, want)
+	}
+}
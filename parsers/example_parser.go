@@ -28,28 +28,26 @@ func (ep *ExampleParser) Name() string {
 }
 
 func (ep *ExampleParser) Parse(ctx context.Context) ([]models.Property, error) {
-	var properties []models.Property
-	
-	// Example: parsing property listings
+	// Example: parsing property listings, following the "next" link between pages
 	url := fmt.Sprintf("%s/listings", ep.baseURL)
 	
-	body, err := ep.Fetch(ctx, url)
-	if err != nil {
+	properties, err := ep.FetchPages(ctx, ep.Name(), Pagination{
+		URL:          url,
+		NextSelector: ".pagination a.next",
+	}, func(doc *goquery.Document) ([]models.Property, error) {
+		var properties []models.Property
+		doc.Find(".property-listing").Each(func(i int, s *goquery.Selection) {
+			property := ep.parseProperty(s)
+			if property != nil {
+				properties = append(properties, *property)
+			}
+		})
+		return properties, nil
+	})
+	// A search cut short still returns the listings it found
+	if err != nil && !IsIncomplete(err) {
 		return nil, fmt.Errorf("failed to fetch listings: %w", err)
 	}
-	defer body.Close()
-	
-	doc, err := goquery.NewDocumentFromReader(body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse HTML: %w", err)
-	}
-	
-	doc.Find(".property-listing").Each(func(i int, s *goquery.Selection) {
-		property := ep.parseProperty(s)
-		if property != nil {
-			properties = append(properties, *property)
-		}
-	})
 	
 	log.Printf("Parsed %d properties from %s", len(properties), ep.Name())
 	return properties, nil
@@ -0,0 +1,91 @@
+package parsers
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"pricemap-go/metrics"
+)
+
+// maxFetchErrors bounds the error messages kept per run; a blocked source
+// would otherwise repeat the same message for every page
+const maxFetchErrors = 20
+
+// FetchStats collects the HTTP outcomes of the requests made with a context.
+// Attach it with WithFetchStats before calling Parse or FetchDetails.
+type FetchStats struct {
+	mu       sync.Mutex
+	statuses map[string]int
+	errors   []string
+	dropped  int
+}
+
+type fetchStatsKey struct{}
+
+// NewFetchStats creates an empty collector
+func NewFetchStats() *FetchStats {
+	return &FetchStats{statuses: make(map[string]int)}
+}
+
+// WithFetchStats returns a context whose Fetch calls are counted in stats
+func WithFetchStats(ctx context.Context, stats *FetchStats) context.Context {
+	return context.WithValue(ctx, fetchStatsKey{}, stats)
+}
+
+// fetchStatsFrom returns the collector attached to ctx, or nil
+func fetchStatsFrom(ctx context.Context) *FetchStats {
+	stats, _ := ctx.Value(fetchStatsKey{}).(*FetchStats)
+	return stats
+}
+
+// recordStatus counts one response; status 0 means no response was received
+func (s *FetchStats) recordStatus(status int) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.statuses[metrics.StatusLabel(status)]++
+	s.mu.Unlock()
+}
+
+// recordError keeps the message of a request that failed for good
+func (s *FetchStats) recordError(url string, err error) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.errors) < maxFetchErrors {
+		s.errors = append(s.errors, url+": "+err.Error())
+	} else {
+		s.dropped++
+	}
+}
+
+// Statuses returns the response counts by status code
+func (s *FetchStats) Statuses() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make(map[string]int, len(s.statuses))
+	for status, count := range s.statuses {
+		statuses[status] = count
+	}
+	return statuses
+}
+
+// Errors returns the kept error messages, noting how many were left out
+func (s *FetchStats) Errors() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	errors := make([]string, len(s.errors), len(s.errors)+1)
+	copy(errors, s.errors)
+	if s.dropped > 0 {
+		errors = append(errors, strconv.Itoa(s.dropped)+" more failed requests")
+	}
+	return errors
+}
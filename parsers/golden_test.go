@@ -0,0 +1,273 @@
+package parsers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"pricemap-go/config"
+	"pricemap-go/models"
+	"pricemap-go/utils"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files from the current parser output")
+
+// goldenProperty is the part of a property a parser extracts.
+// Timestamps and database fields are left out so golden files stay stable.
+type goldenProperty struct {
+	Source      string   `json:"source"`
+	ExternalID  string   `json:"external_id"`
+	URL         string   `json:"url,omitempty"`
+	Country     string   `json:"country"`
+	City        string   `json:"city"`
+	District    string   `json:"district,omitempty"`
+	Address     string   `json:"address,omitempty"`
+	Latitude    float64  `json:"latitude"`
+	Longitude   float64  `json:"longitude"`
+	Type        string   `json:"type"`
+	DealType    string   `json:"deal_type,omitempty"`
+	Price       float64  `json:"price"`
+	Currency    string   `json:"currency"`
+	Area        float64  `json:"area,omitempty"`
+	Rooms       int      `json:"rooms,omitempty"`
+	Bedrooms    int      `json:"bedrooms,omitempty"`
+	Bathrooms   int      `json:"bathrooms,omitempty"`
+	Floor       int      `json:"floor,omitempty"`
+	TotalFloors int      `json:"total_floors,omitempty"`
+	YearBuilt   int      `json:"year_built,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Images      []string `json:"images,omitempty"`
+	IsActive    bool     `json:"is_active"`
+}
+
+// useFixtures switches HTTP to replay mode for the duration of a test
+func useFixtures(t *testing.T) {
+	t.Helper()
+
+	saved := *config.AppConfig
+	config.AppConfig.HTTPFixtureMode = utils.FixtureModeReplay
+	config.AppConfig.HTTPFixtureDir = filepath.Join("testdata", "fixtures")
+	config.AppConfig.UseTor = false
+	config.AppConfig.OpenCageAPIKey = ""
+	config.AppConfig.MaxRetries = 0
+
+	t.Cleanup(func() {
+		*config.AppConfig = saved
+	})
+}
+
+// checkGolden compares parsed properties with testdata/golden/<name>.json
+func checkGolden(t *testing.T, name string, properties []models.Property) {
+	t.Helper()
+
+	golden := make([]goldenProperty, 0, len(properties))
+	for _, p := range properties {
+		golden = append(golden, goldenProperty{
+			Source:      p.Source,
+			ExternalID:  p.ExternalID,
+			URL:         p.URL,
+			Country:     p.Country,
+			City:        p.City,
+			District:    p.District,
+			Address:     p.Address,
+			Latitude:    p.Latitude,
+			Longitude:   p.Longitude,
+			Type:        p.Type,
+			DealType:    p.DealType,
+			Price:       p.Price,
+			Currency:    p.Currency,
+			Area:        p.Area,
+			Rooms:       p.Rooms,
+			Bedrooms:    p.Bedrooms,
+			Bathrooms:   p.Bathrooms,
+			Floor:       p.Floor,
+			TotalFloors: p.TotalFloors,
+			YearBuilt:   p.YearBuilt,
+			Description: p.Description,
+			Images:      p.Images,
+			IsActive:    p.IsActive,
+		})
+	}
+
+	got, err := json.MarshalIndent(golden, "", "  ")
+	if err != nil {
+		t.Fatalf("failed to marshal properties: %v", err)
+	}
+	got = append(got, '\n')
+
+	path := filepath.Join("testdata", "golden", name+".json")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("missing golden file %s (run go test ./parsers -run Golden -update): %v", path, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s output differs from %s\ngot:\n%s\nwant:\n%s", name, path, got, want)
+	}
+}
+
+func TestParsers_Golden(t *testing.T) {
+	useFixtures(t)
+	ctx := context.Background()
+
+	// Listing parsers walk dozens of cities with fixed pauses between them,
+	// so only the recorded first city is replayed
+	tests := []struct {
+		name  string
+		parse func() ([]models.Property, error)
+	}{
+		{"cian", func() ([]models.Property, error) {
+			return NewCianParser().parseType(ctx, "flat", "sale", "Moscow")
+		}},
+		{"rightmove", func() ([]models.Property, error) {
+			return NewRightmoveParser().parseCity(ctx, "London", "property-for-sale", "sale")
+		}},
+		{"zillow", func() ([]models.Property, error) {
+			return NewZillowParser().parseCity(ctx, "New York, NY", "homes", "sale")
+		}},
+		{"idealista", func() ([]models.Property, error) {
+			return NewIdealistaParser().parseCity(ctx, "Madrid", "venta-viviendas", "sale")
+		}},
+		{"example_parser", func() ([]models.Property, error) {
+			return NewExampleParser().Parse(ctx)
+		}},
+		{"nyc_opendata", func() ([]models.Property, error) {
+			return NewNYCOpenDataParser().Parse(ctx)
+		}},
+		{"london_opendata", func() ([]models.Property, error) {
+			return NewLondonOpenDataParser().Parse(ctx)
+		}},
+		{"berlin_opendata", func() ([]models.Property, error) {
+			return NewBerlinOpenDataParser().Parse(ctx)
+		}},
+		{"paris_opendata", func() ([]models.Property, error) {
+			return NewParisOpenDataParser().Parse(ctx)
+		}},
+		{"tokyo_opendata", func() ([]models.Property, error) {
+			return NewTokyoOpenDataParser().Parse(ctx)
+		}},
+		{"sydney_opendata", func() ([]models.Property, error) {
+			return NewSydneyOpenDataParser().Parse(ctx)
+		}},
+		{"moscow_opendata", func() ([]models.Property, error) {
+			return NewMoscowOpenDataParser().Parse(ctx)
+		}},
+		{"opendata", func() ([]models.Property, error) {
+			return NewOpenDataParser("https://opendata.example.org/api/properties.json", "Italy", "Rome").Parse(ctx)
+		}},
+	}
+
+	// Only the recorded pages are replayed, so searches end incomplete
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			properties, err := tt.parse()
+			if err != nil && !IsIncomplete(err) {
+				t.Fatalf("parse error = %v", err)
+			}
+			if len(properties) == 0 {
+				t.Fatalf("no properties parsed from fixture")
+			}
+			checkGolden(t, tt.name, properties)
+		})
+	}
+}
+
+func TestDefinitionParsers_Golden(t *testing.T) {
+	useFixtures(t)
+	ctx := context.Background()
+
+	definitions, err := LoadDefinitions(filepath.Join("..", "definitions"))
+	if err != nil {
+		t.Fatalf("LoadDefinitions() error = %v", err)
+	}
+
+	for _, def := range definitions {
+		t.Run(def.Name, func(t *testing.T) {
+			dp := NewDefinitionParser(def)
+			properties, err := dp.parseCity(ctx, def.Cities[0], def.DealTypes[0])
+			if err != nil && !IsIncomplete(err) {
+				t.Fatalf("parse error = %v", err)
+			}
+			if len(properties) == 0 {
+				t.Fatalf("no properties parsed from fixture")
+			}
+			checkGolden(t, "definition_"+def.Name, properties)
+		})
+	}
+}
+
+func TestDetailParsers_Golden(t *testing.T) {
+	useFixtures(t)
+	ctx := context.Background()
+
+	cian := NewCianParser()
+	rightmove := NewRightmoveParser()
+	zillow := NewZillowParser()
+	idealista := NewIdealistaParser()
+
+	type detailCase struct {
+		name   string
+		parser DetailParser
+		parse  func() ([]models.Property, error)
+	}
+
+	// The first listing of each recorded search page has a recorded detail page
+	tests := []detailCase{
+		{"cian", cian, func() ([]models.Property, error) {
+			return cian.parseType(ctx, "flat", "sale", "Moscow")
+		}},
+		{"rightmove", rightmove, func() ([]models.Property, error) {
+			return rightmove.parseCity(ctx, "London", "property-for-sale", "sale")
+		}},
+		{"zillow", zillow, func() ([]models.Property, error) {
+			return zillow.parseCity(ctx, "New York, NY", "homes", "sale")
+		}},
+		{"idealista", idealista, func() ([]models.Property, error) {
+			return idealista.parseCity(ctx, "Madrid", "venta-viviendas", "sale")
+		}},
+	}
+
+	definitions, err := LoadDefinitions(filepath.Join("..", "definitions"))
+	if err != nil {
+		t.Fatalf("LoadDefinitions() error = %v", err)
+	}
+	for _, def := range definitions {
+		dp := NewDefinitionParser(def)
+		city, deal := def.Cities[0], def.DealTypes[0]
+		tests = append(tests, detailCase{"definition_" + def.Name, dp, func() ([]models.Property, error) {
+			return dp.parseCity(ctx, city, deal)
+		}})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.parser.HasDetailPages() {
+				t.Fatalf("%s should have detail pages", tt.name)
+			}
+
+			properties, err := tt.parse()
+			if (err != nil && !IsIncomplete(err)) || len(properties) == 0 {
+				t.Fatalf("parse returned %d properties, error = %v", len(properties), err)
+			}
+
+			property := properties[0]
+			if err := tt.parser.FetchDetails(ctx, &property); err != nil {
+				t.Fatalf("FetchDetails() error = %v", err)
+			}
+			checkGolden(t, "details_"+tt.name, []models.Property{property})
+		})
+	}
+}
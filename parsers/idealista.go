@@ -18,12 +18,32 @@ import (
 type IdealistaParser struct {
 	*BaseParser
 	geocoding *utils.GeocodingService
+	cities    []string
+}
+
+// idealistaCities are the Spanish cities to parse
+var idealistaCities = []string{
+	"Madrid", "Barcelona", "Valencia", "Seville", "Zaragoza",
+	"Málaga", "Murcia", "Palma", "Las Palmas", "Bilbao",
+	"Alicante", "Córdoba", "Valladolid", "Vigo", "Gijón",
+	"Granada", "Vitoria", "A Coruña", "Elche", "Santa Cruz de Tenerife",
+}
+
+func init() {
+	Register(SourceInfo{
+		Name:    "idealista",
+		Kind:    KindCommercial,
+		Country: "Spain",
+		Cities:  idealistaCities,
+		New:     func() Parser { return NewIdealistaParser() },
+	})
 }
 
 func NewIdealistaParser() *IdealistaParser {
 	return &IdealistaParser{
 		BaseParser: NewBaseParser("https://www.idealista.com"),
 		geocoding:  utils.NewGeocodingService(),
+		cities:     idealistaCities,
 	}
 }
 
@@ -31,17 +51,19 @@ func (ip *IdealistaParser) Name() string {
 	return "idealista"
 }
 
+// TracksListings reports that Idealista results are live listings
+func (ip *IdealistaParser) TracksListings() bool {
+	return true
+}
+
+// SetCities limits the parser to the given cities
+func (ip *IdealistaParser) SetCities(cities []string) {
+	ip.cities = filterCities(idealistaCities, cities)
+}
+
 func (ip *IdealistaParser) Parse(ctx context.Context) ([]models.Property, error) {
 	var allProperties []models.Property
 	
-	// Spanish cities
-	cities := []string{
-		"Madrid", "Barcelona", "Valencia", "Seville", "Zaragoza",
-		"Málaga", "Murcia", "Palma", "Las Palmas", "Bilbao",
-		"Alicante", "Córdoba", "Valladolid", "Vigo", "Gijón",
-		"Granada", "Vitoria", "A Coruña", "Elche", "Santa Cruz de Tenerife",
-	}
-	
 	// Parse for both sale and rent
 	dealTypes := []struct {
 		path string
@@ -51,10 +73,12 @@ func (ip *IdealistaParser) Parse(ctx context.Context) ([]models.Property, error)
 		{"alquiler-viviendas", "rent"},
 	}
 	
-	for _, city := range cities {
+	for _, city := range ip.cities {
 		for _, dealType := range dealTypes {
 			properties, err := ip.parseCity(ctx, city, dealType.path, dealType.name)
-			if err != nil {
+			coverageFrom(ctx).Record(city, dealType.name, err)
+			// A search cut short still returns the listings it found
+			if err != nil && !IsIncomplete(err) {
 				log.Printf("Error parsing %s/%s from Idealista: %v", city, dealType.name, err)
 				continue
 			}
@@ -68,22 +92,36 @@ func (ip *IdealistaParser) Parse(ctx context.Context) ([]models.Property, error)
 	return allProperties, nil
 }
 
-func (ip *IdealistaParser) parseCity(ctx context.Context, city, path string, _ string) ([]models.Property, error) {
-	var properties []models.Property
-	
+func (ip *IdealistaParser) parseCity(ctx context.Context, city, path, dealType string) ([]models.Property, error) {
 	url := fmt.Sprintf("%s/%s/%s/", ip.baseURL, path, strings.ToLower(city))
 	
-	body, err := ip.Fetch(ctx, url)
-	if err != nil {
+	// Idealista links the next page; page N is also reachable as .../pagina-N.htm
+	properties, err := ip.FetchPages(ctx, ip.Name(), Pagination{
+		URL:          url,
+		NextSelector: ".pagination .next a",
+		PageURL: func(page int) string {
+			if page <= 1 {
+				return url
+			}
+			return fmt.Sprintf("%spagina-%d.htm", url, page)
+		},
+	}, func(doc *goquery.Document) ([]models.Property, error) {
+		return ip.parsePage(doc, city), nil
+	})
+	if err != nil && !IsIncomplete(err) {
 		return nil, fmt.Errorf("failed to fetch Idealista listings: %w", err)
 	}
-	defer body.Close()
 	
-	doc, err := goquery.NewDocumentFromReader(body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	for i := range properties {
+		properties[i].DealType = dealType
 	}
 	
+	return properties, err
+}
+
+func (ip *IdealistaParser) parsePage(doc *goquery.Document, city string) []models.Property {
+	var properties []models.Property
+	
 	doc.Find(".item").Each(func(i int, s *goquery.Selection) {
 		property := ip.parseProperty(s, city)
 		if property != nil {
@@ -91,7 +129,7 @@ func (ip *IdealistaParser) parseCity(ctx context.Context, city, path string, _ s
 		}
 	})
 	
-	return properties, nil
+	return properties
 }
 
 func (ip *IdealistaParser) parseProperty(s *goquery.Selection, city string) *models.Property {
@@ -145,7 +183,8 @@ func (ip *IdealistaParser) parseProperty(s *goquery.Selection, city string) *mod
 }
 
 func (ip *IdealistaParser) extractPrice(text string) float64 {
-	re := regexp.MustCompile(`[^\d.]`)
+	// Idealista writes whole euros with "." as the thousands separator (985.000€)
+	re := regexp.MustCompile(`[^\d]`)
 	cleaned := re.ReplaceAllString(text, "")
 	
 	price, err := strconv.ParseFloat(cleaned, 64)
@@ -187,3 +226,38 @@ func (ip *IdealistaParser) extractIDFromURL(url string) string {
 	return url
 }
 
+
+// HasDetailPages reports that Idealista listings have their own pages
+func (ip *IdealistaParser) HasDetailPages() bool {
+	return true
+}
+
+// FetchDetails visits the listing page and adds floor, building and description details
+func (ip *IdealistaParser) FetchDetails(ctx context.Context, property *models.Property) error {
+	doc, err := ip.fetchDetailPage(ctx, property)
+	if err != nil {
+		return fmt.Errorf("failed to fetch Idealista listing: %w", err)
+	}
+	
+	details := ip.parseDetails(doc)
+	details.Apply(property)
+	return nil
+}
+
+func (ip *IdealistaParser) parseDetails(doc *goquery.Document) ListingDetails {
+	var details ListingDetails
+	
+	// Basic features, e.g. "3 habitaciones", "2 baños", "Construido en 1960", "Planta 4ª exterior"
+	features := detailText(doc, ".details-property_features li, .details-property-feature-one li, .details-property-feature-two li")
+	details.Bathrooms = matchInt(regexp.MustCompile(`(\d+)\s*baños?`), features, 1)
+	details.Rooms = matchInt(regexp.MustCompile(`(\d+)\s*habitaci`), features, 1)
+	details.YearBuilt = matchInt(regexp.MustCompile(`Construido en\s*(\d{4})`), features, 1)
+	details.Floor = matchInt(regexp.MustCompile(`Planta\s*(\d+)`), features, 1)
+	details.Area = ip.extractArea(features)
+	
+	details.Description = strings.TrimSpace(doc.Find(".comment .adCommentsLanguage, .comment p").First().Text())
+	details.Images = collectImages(doc, "#main-multimedia img, .detail-image-gallery img", "data-ondemand-img", "src")
+	
+	details.fillFrom(structuredDetails(doc))
+	return details
+}
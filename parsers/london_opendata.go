@@ -20,6 +20,16 @@ type LondonOpenDataParser struct {
 	geocoding *utils.GeocodingService
 }
 
+func init() {
+	Register(SourceInfo{
+		Name:    "london_opendata",
+		Kind:    KindOpenData,
+		Country: "United Kingdom",
+		Cities:  []string{"London"},
+		New:     func() Parser { return NewLondonOpenDataParser() },
+	})
+}
+
 func NewLondonOpenDataParser() *LondonOpenDataParser {
 	return &LondonOpenDataParser{
 		BaseParser: NewBaseParser("https://data.london.gov.uk"),
@@ -93,7 +103,7 @@ func (ldn *LondonOpenDataParser) Parse(ctx context.Context) ([]models.Property,
 			property.Latitude = lat
 			property.Longitude = lng
 		}
-		time.Sleep(1 * time.Second) // Rate limiting
+		geocodePause()
 
 		properties = append(properties, *property)
 	}
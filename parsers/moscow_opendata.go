@@ -20,6 +20,16 @@ type MoscowOpenDataParser struct {
 	geocoding *utils.GeocodingService
 }
 
+func init() {
+	Register(SourceInfo{
+		Name:    "moscow_opendata",
+		Kind:    KindOpenData,
+		Country: "Russia",
+		Cities:  []string{"Moscow"},
+		New:     func() Parser { return NewMoscowOpenDataParser() },
+	})
+}
+
 func NewMoscowOpenDataParser() *MoscowOpenDataParser {
 	return &MoscowOpenDataParser{
 		BaseParser: NewBaseParser("https://data.mos.ru"),
@@ -116,7 +126,7 @@ func (mos *MoscowOpenDataParser) Parse(ctx context.Context) ([]models.Property,
 				property.Latitude = lat
 				property.Longitude = lng
 			}
-			time.Sleep(1 * time.Second)
+			geocodePause()
 		}
 
 		properties = append(properties, *property)
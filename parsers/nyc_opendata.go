@@ -19,6 +19,16 @@ type NYCOpenDataParser struct {
 	geocoding *utils.GeocodingService
 }
 
+func init() {
+	Register(SourceInfo{
+		Name:    "nyc_opendata",
+		Kind:    KindOpenData,
+		Country: "United States",
+		Cities:  []string{"New York"},
+		New:     func() Parser { return NewNYCOpenDataParser() },
+	})
+}
+
 func NewNYCOpenDataParser() *NYCOpenDataParser {
 	return &NYCOpenDataParser{
 		BaseParser: NewBaseParser("https://data.cityofnewyork.us"),
@@ -124,7 +134,7 @@ func (nyc *NYCOpenDataParser) Parse(ctx context.Context) ([]models.Property, err
 				property.Latitude = lat
 				property.Longitude = lng
 			}
-			time.Sleep(1 * time.Second) // Rate limiting
+			geocodePause()
 		}
 
 		properties = append(properties, *property)
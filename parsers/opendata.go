@@ -162,3 +162,11 @@ func (odp *OpenDataParser) getCurrency() string {
 	}
 }
 
+
+// geocodePause keeps open-data parsers to the geocoder's limit of one request
+// per second; recorded responses need no pause
+func geocodePause() {
+	if !utils.IsReplayMode() {
+		time.Sleep(time.Second)
+	}
+}
@@ -0,0 +1,206 @@
+package parsers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"pricemap-go/config"
+	"pricemap-go/models"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Pagination describes how to walk the result pages of one search
+type Pagination struct {
+	// URL is the first results page
+	URL string
+	// MaxPages is the source's own page limit; SOURCE_MAX_PAGES overrides it
+	// and MAX_PAGES applies when both are unset
+	MaxPages int
+	// NextSelector finds the "next page" link; it is followed when present
+	NextSelector string
+	// PageURL builds the URL of a 1-based page number (offset or page
+	// parameter) and is used when there is no next link; nil means only next
+	// links are followed
+	PageURL func(page int) string
+}
+
+// IncompleteError is returned by FetchPages, together with the listings
+// collected so far, when pagination stopped before the last results page
+type IncompleteError struct {
+	Page int   // Last page whose listings were collected
+	Err  error // Why a later page could not be read; nil at the page limit
+}
+
+func (e *IncompleteError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("stopped at the page limit after page %d", e.Page)
+	}
+	return fmt.Sprintf("stopped after page %d: %v", e.Page, e.Err)
+}
+
+func (e *IncompleteError) Unwrap() error {
+	return e.Err
+}
+
+// errNoListings is why pagination stops at a later page without any listing
+// cards, which is a captcha, block page or layout change rather than the end
+var errNoListings = errors.New("page has no listings")
+
+// IsIncomplete reports whether err only says that pagination was cut short,
+// so the listings returned with it are valid but not all there are
+func IsIncomplete(err error) bool {
+	var incomplete *IncompleteError
+	return errors.As(err, &incomplete)
+}
+
+// PageParser extracts properties from one search results page
+type PageParser func(doc *goquery.Document) ([]models.Property, error)
+
+// OffsetPageURL builds page URLs for sites that paginate by result offset
+func OffsetPageURL(firstURL, param string, pageSize int) func(page int) string {
+	return func(page int) string {
+		if page <= 1 {
+			return firstURL
+		}
+		return addQueryParam(firstURL, param, fmt.Sprint((page-1)*pageSize))
+	}
+}
+
+// NumberedPageURL builds page URLs for sites that take a page number parameter
+func NumberedPageURL(firstURL, param string) func(page int) string {
+	return func(page int) string {
+		if page <= 1 {
+			return firstURL
+		}
+		return addQueryParam(firstURL, param, fmt.Sprint(page))
+	}
+}
+
+// FetchPages fetches search result pages starting at the first URL until the
+// page limit is reached, there is no next page, or a page adds no listings
+// that were not already seen. A failure on the first page is returned; a later
+// failure, a later page without listings, or reaching the page limit with a
+// next page left returns the listings collected so far with an IncompleteError.
+func (bp *BaseParser) FetchPages(ctx context.Context, source string, p Pagination, parsePage PageParser) ([]models.Property, error) {
+	maxPages := p.MaxPages
+	if _, override := config.AppConfig.SourceMaxPages[source]; override || maxPages <= 0 {
+		maxPages = config.AppConfig.MaxPagesFor(source)
+	}
+
+	var properties []models.Property
+	seen := make(map[string]bool)
+	visited := make(map[string]bool)
+	current := p.URL
+
+	for page := 1; page <= maxPages && current != ""; page++ {
+		select {
+		case <-ctx.Done():
+			return properties, ctx.Err()
+		default:
+		}
+
+		visited[current] = true
+		doc, err := bp.fetchDocument(ctx, current)
+		if err != nil {
+			if page == 1 {
+				return nil, err
+			}
+			log.Printf("%s: stopping pagination at page %d: %v", source, page, err)
+			return properties, &IncompleteError{Page: page - 1, Err: err}
+		}
+
+		items, err := parsePage(doc)
+		if err != nil {
+			if page == 1 {
+				return nil, err
+			}
+			log.Printf("%s: stopping pagination at page %d: %v", source, page, err)
+			return properties, &IncompleteError{Page: page - 1, Err: err}
+		}
+
+		added := 0
+		for _, item := range items {
+			key := item.ExternalID
+			if key == "" {
+				key = item.URL
+			}
+			if key != "" && seen[key] {
+				continue
+			}
+			seen[key] = true
+			properties = append(properties, item)
+			added++
+		}
+
+		// Sites often repeat the last page for out-of-range page numbers, but a
+		// later page without any cards did not show the rest of the listings
+		if len(items) == 0 && page > 1 {
+			log.Printf("%s: stopping pagination at page %d: %v", source, page, errNoListings)
+			return properties, &IncompleteError{Page: page - 1, Err: errNoListings}
+		}
+		if added == 0 {
+			break
+		}
+
+		next := ""
+		if p.NextSelector != "" {
+			if href, ok := doc.Find(p.NextSelector).First().Attr("href"); ok {
+				next = resolveURL(current, href)
+			}
+		}
+		if next == "" && p.PageURL != nil {
+			next = p.PageURL(page + 1)
+		}
+		if visited[next] {
+			break
+		}
+		if next != "" && page == maxPages {
+			log.Printf("%s: stopping pagination at the limit of %d pages", source, maxPages)
+			return properties, &IncompleteError{Page: page}
+		}
+		current = next
+	}
+
+	return properties, nil
+}
+
+func (bp *BaseParser) fetchDocument(ctx context.Context, pageURL string) (*goquery.Document, error) {
+	body, err := bp.Fetch(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	doc, err := goquery.NewDocumentFromReader(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	return doc, nil
+}
+
+// addQueryParam appends a query parameter without re-encoding the rest of the URL
+func addQueryParam(rawURL, name, value string) string {
+	sep := "?"
+	if strings.Contains(rawURL, "?") {
+		sep = "&"
+	}
+	return rawURL + sep + url.QueryEscape(name) + "=" + url.QueryEscape(value)
+}
+
+// resolveURL resolves a possibly relative link against the page it was found on
+func resolveURL(base, href string) string {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return href
+	}
+	ref, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return ""
+	}
+	return baseURL.ResolveReference(ref).String()
+}
@@ -0,0 +1,297 @@
+package parsers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"pricemap-go/config"
+	"pricemap-go/models"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// listingPage renders a results page with the given listing ids and an optional next link
+func listingPage(next string, ids ...int) string {
+	html := "<html><body>"
+	for _, id := range ids {
+		html += fmt.Sprintf(`<div class="card" data-id="%d"></div>`, id)
+	}
+	if next != "" {
+		html += fmt.Sprintf(`<a class="next" href="%s">Next</a>`, next)
+	}
+	return html + "</body></html>"
+}
+
+func parseCards(doc *goquery.Document) ([]models.Property, error) {
+	var properties []models.Property
+	doc.Find(".card").Each(func(i int, s *goquery.Selection) {
+		id, _ := s.Attr("data-id")
+		properties = append(properties, models.Property{ExternalID: id})
+	})
+	return properties, nil
+}
+
+func externalIDs(properties []models.Property) []string {
+	ids := make([]string, 0, len(properties))
+	for _, p := range properties {
+		ids = append(ids, p.ExternalID)
+	}
+	return ids
+}
+
+// paginationConfig makes pagination tests fast and independent of the environment
+func paginationConfig(t *testing.T) {
+	t.Helper()
+
+	saved := *config.AppConfig
+	config.AppConfig.MaxRetries = 0
+	config.AppConfig.RateLimitDelay = 0
+	config.AppConfig.UseTor = false
+	config.AppConfig.MaxPages = 10
+	config.AppConfig.SourceMaxPages = map[string]int{}
+
+	t.Cleanup(func() {
+		*config.AppConfig = saved
+	})
+}
+
+func TestFetchPages_PageParameter(t *testing.T) {
+	paginationConfig(t)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		switch {
+		case page <= 1:
+			w.Write([]byte(listingPage("", 1, 2)))
+		case page == 2:
+			w.Write([]byte(listingPage("", 3, 4)))
+		default:
+			// Out-of-range pages repeat the last page
+			w.Write([]byte(listingPage("", 3, 4)))
+		}
+	}))
+	defer server.Close()
+
+	bp := NewBaseParser(server.URL)
+	url := server.URL + "/search?city=London"
+	properties, err := bp.FetchPages(context.Background(), "test", Pagination{
+		URL:     url,
+		PageURL: NumberedPageURL(url, "page"),
+	}, parseCards)
+	if err != nil {
+		t.Fatalf("FetchPages() error = %v", err)
+	}
+
+	if got := fmt.Sprint(externalIDs(properties)); got != "[1 2 3 4]" {
+		t.Errorf("FetchPages() ids = %s, want [1 2 3 4]", got)
+	}
+	if requests != 3 {
+		t.Errorf("FetchPages() made %d requests, want 3 (stop when a page adds nothing new)", requests)
+	}
+}
+
+func TestFetchPages_NextLink(t *testing.T) {
+	paginationConfig(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/listings":
+			w.Write([]byte(listingPage("page-2", 1)))
+		case "/page-2":
+			w.Write([]byte(listingPage("/page-3", 2)))
+		case "/page-3":
+			w.Write([]byte(listingPage("", 3)))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	bp := NewBaseParser(server.URL)
+	properties, err := bp.FetchPages(context.Background(), "test", Pagination{
+		URL:          server.URL + "/listings",
+		NextSelector: "a.next",
+	}, parseCards)
+	if err != nil {
+		t.Fatalf("FetchPages() error = %v", err)
+	}
+
+	if got := fmt.Sprint(externalIDs(properties)); got != "[1 2 3]" {
+		t.Errorf("FetchPages() ids = %s, want [1 2 3]", got)
+	}
+}
+
+func TestFetchPages_MaxPages(t *testing.T) {
+	paginationConfig(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("index"))
+		w.Write([]byte(listingPage("", offset+1, offset+2)))
+	}))
+	defer server.Close()
+
+	bp := NewBaseParser(server.URL)
+	url := server.URL + "/find"
+	pagination := Pagination{
+		URL:      url,
+		MaxPages: 3,
+		PageURL:  OffsetPageURL(url, "index", 2),
+	}
+
+	// Stopping at the limit with pages left returns what was collected as incomplete
+	properties, err := bp.FetchPages(context.Background(), "test", pagination, parseCards)
+	var incomplete *IncompleteError
+	if !errors.As(err, &incomplete) || incomplete.Page != 3 || incomplete.Err != nil {
+		t.Fatalf("FetchPages() error = %v, want IncompleteError at page 3", err)
+	}
+	if len(properties) != 6 {
+		t.Errorf("FetchPages() returned %d properties, want 6 from 3 pages", len(properties))
+	}
+
+	// SOURCE_MAX_PAGES takes precedence over the parser's own limit
+	config.AppConfig.SourceMaxPages = map[string]int{"test": 1}
+	properties, err = bp.FetchPages(context.Background(), "test", pagination, parseCards)
+	if !IsIncomplete(err) {
+		t.Fatalf("FetchPages() error = %v, want IncompleteError", err)
+	}
+	if len(properties) != 2 {
+		t.Errorf("FetchPages() returned %d properties, want 2 from 1 page", len(properties))
+	}
+
+	// A slice cut short at the limit is not complete
+	coverage := NewCoverage()
+	coverage.Record("London", "sale", err)
+	if coverage.Complete("London", "sale") {
+		t.Errorf("Complete() = true for a search stopped at the page limit")
+	}
+
+	// The last page within the limit is complete
+	config.AppConfig.SourceMaxPages = map[string]int{}
+	if _, err := bp.FetchPages(context.Background(), "test", Pagination{URL: url, MaxPages: 1}, parseCards); err != nil {
+		t.Errorf("FetchPages() without a next page error = %v", err)
+	}
+}
+
+func TestFetchPages_Errors(t *testing.T) {
+	paginationConfig(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/first" {
+			w.Write([]byte(listingPage("/broken", 1, 2)))
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	bp := NewBaseParser(server.URL)
+
+	// A failing first page is an error
+	if _, err := bp.FetchPages(context.Background(), "test", Pagination{
+		URL: server.URL + "/broken",
+	}, parseCards); err == nil {
+		t.Errorf("FetchPages() should return error when the first page fails")
+	}
+
+	// A failing later page keeps what was already collected, as incomplete
+	properties, err := bp.FetchPages(context.Background(), "test", Pagination{
+		URL:          server.URL + "/first",
+		NextSelector: "a.next",
+	}, parseCards)
+	var incomplete *IncompleteError
+	if !errors.As(err, &incomplete) || incomplete.Page != 1 || incomplete.Err == nil {
+		t.Fatalf("FetchPages() error = %v, want IncompleteError after page 1", err)
+	}
+	if len(properties) != 2 {
+		t.Errorf("FetchPages() returned %d properties, want 2 from the first page", len(properties))
+	}
+}
+
+func TestFetchPages_EmptyPage(t *testing.T) {
+	paginationConfig(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch page, _ := strconv.Atoi(r.URL.Query().Get("page")); {
+		case page <= 1:
+			w.Write([]byte(listingPage("", 1, 2)))
+		case page == 2:
+			w.Write([]byte(listingPage("", 3, 4)))
+		default:
+			// A captcha or block page has no listing cards
+			w.Write([]byte("<html><body><form id=\"captcha\"></form></body></html>"))
+		}
+	}))
+	defer server.Close()
+
+	bp := NewBaseParser(server.URL)
+	url := server.URL + "/search"
+	properties, err := bp.FetchPages(context.Background(), "test", Pagination{
+		URL:     url,
+		PageURL: NumberedPageURL(url, "page"),
+	}, parseCards)
+	var incomplete *IncompleteError
+	if !errors.As(err, &incomplete) || incomplete.Page != 2 || !errors.Is(err, errNoListings) {
+		t.Fatalf("FetchPages() error = %v, want IncompleteError after page 2", err)
+	}
+	if got := fmt.Sprint(externalIDs(properties)); got != "[1 2 3 4]" {
+		t.Errorf("FetchPages() ids = %s, want [1 2 3 4]", got)
+	}
+
+	coverage := NewCoverage()
+	coverage.Record("London", "sale", err)
+	if coverage.Complete("London", "sale") {
+		t.Errorf("Complete() = true for a search that ended at a page without listings")
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	coverage := NewCoverage()
+	ctx := WithCoverage(context.Background(), coverage)
+
+	coverageFrom(ctx).Record("London", "sale", nil)
+	coverageFrom(ctx).Record("London", "rent", nil)
+	coverageFrom(ctx).Record("London", "rent", &IncompleteError{Page: 5})
+	coverageFrom(ctx).Record("Leeds", "sale", errors.New("blocked"))
+
+	if !coverage.Complete("London", "sale") {
+		t.Errorf("London/sale should be complete")
+	}
+	// One search of a slice cut short leaves the whole slice incomplete
+	if coverage.Complete("London", "rent") {
+		t.Errorf("London/rent should not be complete")
+	}
+	if coverage.Complete("Leeds", "sale") || coverage.Complete("Leeds", "rent") {
+		t.Errorf("failed and unsearched slices should not be complete")
+	}
+
+	// Parsing without a collector records nothing
+	coverageFrom(context.Background()).Record("London", "sale", nil)
+	var none *Coverage
+	if none.Complete("London", "sale") {
+		t.Errorf("nil Coverage should report nothing complete")
+	}
+}
+
+func TestPageURLBuilders(t *testing.T) {
+	first := "https://www.rightmove.co.uk/property-for-sale/find.html?keywords=London"
+
+	offset := OffsetPageURL(first, "index", 24)
+	if got := offset(1); got != first {
+		t.Errorf("OffsetPageURL(1) = %s, want first URL", got)
+	}
+	if got := offset(3); got != first+"&index=48" {
+		t.Errorf("OffsetPageURL(3) = %s", got)
+	}
+
+	numbered := NumberedPageURL("https://example.com/listings", "page")
+	if got := numbered(2); got != "https://example.com/listings?page=2" {
+		t.Errorf("NumberedPageURL(2) = %s", got)
+	}
+}
@@ -18,6 +18,16 @@ type ParisOpenDataParser struct {
 	geocoding *utils.GeocodingService
 }
 
+func init() {
+	Register(SourceInfo{
+		Name:    "paris_opendata",
+		Kind:    KindOpenData,
+		Country: "France",
+		Cities:  []string{"Paris"},
+		New:     func() Parser { return NewParisOpenDataParser() },
+	})
+}
+
 func NewParisOpenDataParser() *ParisOpenDataParser {
 	return &ParisOpenDataParser{
 		BaseParser: NewBaseParser("https://opendata.paris.fr"),
@@ -93,7 +103,7 @@ func (par *ParisOpenDataParser) Parse(ctx context.Context) ([]models.Property, e
 				property.Latitude = lat
 				property.Longitude = lng
 			}
-			time.Sleep(1 * time.Second)
+			geocodePause()
 		}
 
 		properties = append(properties, *property)
@@ -0,0 +1,169 @@
+package parsers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Source kinds
+const (
+	KindOpenData   = "opendata"   // official datasets, rarely blocked
+	KindCommercial = "commercial" // listing sites, may block scrapers
+)
+
+// SourceInfo describes a parser that can be selected by name
+type SourceInfo struct {
+	Name    string
+	Kind    string
+	Country string
+	Cities  []string
+
+	// Definition is set for sources described by a site definition file
+	Definition bool
+
+	// New creates a parser for a run
+	New func() Parser
+}
+
+// CityParser is implemented by parsers that scrape a list of cities and can be
+// limited to some of them
+type CityParser interface {
+	Parser
+	SetCities(cities []string)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]SourceInfo)
+)
+
+// Register makes a parser available by name. It is called from init functions
+// and panics on duplicate names, like database/sql.Register.
+func Register(info SourceInfo) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if info.Name == "" || info.New == nil {
+		panic("parsers: Register requires a name and a constructor")
+	}
+	if _, exists := registry[info.Name]; exists {
+		panic("parsers: Register called twice for " + info.Name)
+	}
+	registry[info.Name] = info
+}
+
+// Registered returns all registered sources, open data first, then by name
+func Registered() []SourceInfo {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	sources := make([]SourceInfo, 0, len(registry))
+	for _, info := range registry {
+		sources = append(sources, info)
+	}
+	sortSources(sources)
+	return sources
+}
+
+// Sources returns the registered sources merged with the enabled site
+// definitions in dir. A definition replaces the registered source of the same name.
+func Sources(definitionsDir string) ([]SourceInfo, error) {
+	sources := Registered()
+
+	definitions, err := LoadDefinitions(definitionsDir)
+	if err != nil {
+		return sources, err
+	}
+
+	for _, def := range definitions {
+		if !def.IsEnabled() {
+			continue
+		}
+
+		info := def.SourceInfo()
+		replaced := false
+		for i := range sources {
+			if sources[i].Name == info.Name {
+				sources[i] = info
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			sources = append(sources, info)
+		}
+	}
+
+	sortSources(sources)
+	return sources, nil
+}
+
+// Select narrows sources to the given names (all when empty). Unknown names are an error.
+func Select(sources []SourceInfo, names []string) ([]SourceInfo, error) {
+	if len(names) == 0 {
+		return sources, nil
+	}
+
+	byName := make(map[string]SourceInfo, len(sources))
+	for _, info := range sources {
+		byName[info.Name] = info
+	}
+
+	selected := make([]SourceInfo, 0, len(names))
+	for _, name := range names {
+		info, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown source %q", name)
+		}
+		selected = append(selected, info)
+	}
+	return selected, nil
+}
+
+// MatchCities returns the cities of a source that are among the requested ones.
+// All cities are returned when none are requested.
+func (info SourceInfo) MatchCities(requested []string) []string {
+	if len(requested) == 0 {
+		return info.Cities
+	}
+	return filterCities(info.Cities, requested)
+}
+
+// CityMatches compares city names case-insensitively, ignoring a state or
+// country suffix such as "New York, NY"
+func CityMatches(city, requested string) bool {
+	name, _, _ := strings.Cut(city, ",")
+	want, _, _ := strings.Cut(requested, ",")
+	return strings.EqualFold(strings.TrimSpace(name), strings.TrimSpace(want))
+}
+
+// filterCities keeps the cities that match one of the requested names
+func filterCities(cities, requested []string) []string {
+	var filtered []string
+	for _, city := range cities {
+		if matchesAnyCity(city, requested) {
+			filtered = append(filtered, city)
+		}
+	}
+	return filtered
+}
+
+func matchesAnyCity(city string, requested []string) bool {
+	for _, want := range requested {
+		if CityMatches(city, want) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortSources(sources []SourceInfo) {
+	sort.SliceStable(sources, func(i, j int) bool {
+		if sources[i].Kind != sources[j].Kind {
+			return sources[i].Kind == KindOpenData
+		}
+		return sources[i].Name < sources[j].Name
+	})
+}
@@ -0,0 +1,131 @@
+package parsers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRegistered(t *testing.T) {
+	sources := Registered()
+
+	names := make(map[string]SourceInfo)
+	for _, info := range sources {
+		names[info.Name] = info
+		if parser := info.New(); parser.Name() != info.Name {
+			t.Errorf("source %s creates parser named %s", info.Name, parser.Name())
+		}
+	}
+
+	for _, want := range []string{
+		"nyc_opendata", "london_opendata", "berlin_opendata", "paris_opendata",
+		"tokyo_opendata", "sydney_opendata", "moscow_opendata",
+		"cian", "rightmove", "zillow", "idealista",
+	} {
+		if _, ok := names[want]; !ok {
+			t.Errorf("Registered() missing %s", want)
+		}
+	}
+
+	// Open data sources come first
+	seenCommercial := false
+	for _, info := range sources {
+		if info.Kind == KindCommercial {
+			seenCommercial = true
+		} else if seenCommercial {
+			t.Errorf("open data source %s listed after commercial sources", info.Name)
+		}
+	}
+}
+
+func TestCityMatches(t *testing.T) {
+	tests := []struct {
+		city, requested string
+		want            bool
+	}{
+		{"Moscow", "moscow", true},
+		{"New York, NY", "New York", true},
+		{"New York, NY", "new york, ny", true},
+		{"New York", "New York, NY", true},
+		{"York", "New York", false},
+		{"Madrid", "Barcelona", false},
+	}
+
+	for _, tt := range tests {
+		if got := CityMatches(tt.city, tt.requested); got != tt.want {
+			t.Errorf("CityMatches(%q, %q) = %v, want %v", tt.city, tt.requested, got, tt.want)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	sources := Registered()
+
+	selected, err := Select(sources, []string{"zillow", "cian"})
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+	if len(selected) != 2 || selected[0].Name != "zillow" || selected[1].Name != "cian" {
+		t.Errorf("Select() = %v, want zillow and cian", selected)
+	}
+
+	if _, err := Select(sources, []string{"cian", "nope"}); err == nil {
+		t.Errorf("Select() should fail for an unknown source")
+	}
+
+	if all, _ := Select(sources, nil); len(all) != len(sources) {
+		t.Errorf("Select() without names = %d sources, want %d", len(all), len(sources))
+	}
+}
+
+func TestSetCities(t *testing.T) {
+	zillow := NewZillowParser()
+	zillow.SetCities([]string{"new york", "Boston"})
+	if want := []string{"New York, NY", "Boston, MA"}; !reflect.DeepEqual(zillow.cities, want) {
+		t.Errorf("ZillowParser.SetCities() cities = %v, want %v", zillow.cities, want)
+	}
+
+	def, err := LoadDefinition("../definitions/idealista.yaml")
+	if err != nil {
+		t.Fatalf("LoadDefinition() error = %v", err)
+	}
+	dp := NewDefinitionParser(*def)
+	dp.SetCities([]string{"Madrid"})
+	if len(dp.def.Cities) != 1 || dp.def.Cities[0].Name != "Madrid" {
+		t.Errorf("DefinitionParser.SetCities() cities = %v, want Madrid only", dp.def.Cities)
+	}
+	if len(def.Cities) < 2 {
+		t.Errorf("SetCities() modified the shared definition")
+	}
+}
+
+func TestSources_Definitions(t *testing.T) {
+	dir := t.TempDir()
+	content := `{"name": "cian", "base_url": "https://www.cian.ru", "search_url": "{{.BaseURL}}/{{.Query}}",
+		"country": "Russia", "cities": [{"name": "Kazan"}], "cards": [".card"],
+		"fields": {"price": {"selector": ".p"}, "external_id": {"selector": ".id"}}}`
+	if err := os.WriteFile(filepath.Join(dir, "cian.json"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sources, err := Sources(dir)
+	if err != nil {
+		t.Fatalf("Sources() error = %v", err)
+	}
+	if len(sources) != len(Registered()) {
+		t.Errorf("Sources() = %d sources, want the definition to replace cian", len(sources))
+	}
+
+	for _, info := range sources {
+		if info.Name != "cian" {
+			continue
+		}
+		if !info.Definition || !reflect.DeepEqual(info.Cities, []string{"Kazan"}) {
+			t.Errorf("cian source = %+v, want the Kazan definition", info)
+		}
+		if _, ok := info.New().(*DefinitionParser); !ok {
+			t.Errorf("cian source should create a DefinitionParser")
+		}
+	}
+}
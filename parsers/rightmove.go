@@ -18,12 +18,33 @@ import (
 type RightmoveParser struct {
 	*BaseParser
 	geocoding *utils.GeocodingService
+	cities    []string
+}
+
+// rightmoveCities are the UK cities to parse
+var rightmoveCities = []string{
+	"London", "Manchester", "Birmingham", "Liverpool", "Leeds",
+	"Glasgow", "Edinburgh", "Bristol", "Cardiff", "Belfast",
+	"Newcastle", "Sheffield", "Leicester", "Coventry", "Nottingham",
+	"Southampton", "Portsmouth", "Brighton", "Reading", "Oxford",
+	"Cambridge", "York", "Bath", "Norwich", "Exeter",
+}
+
+func init() {
+	Register(SourceInfo{
+		Name:    "rightmove",
+		Kind:    KindCommercial,
+		Country: "United Kingdom",
+		Cities:  rightmoveCities,
+		New:     func() Parser { return NewRightmoveParser() },
+	})
 }
 
 func NewRightmoveParser() *RightmoveParser {
 	return &RightmoveParser{
 		BaseParser: NewBaseParser("https://www.rightmove.co.uk"),
 		geocoding:  utils.NewGeocodingService(),
+		cities:     rightmoveCities,
 	}
 }
 
@@ -31,18 +52,19 @@ func (rp *RightmoveParser) Name() string {
 	return "rightmove"
 }
 
+// TracksListings reports that Rightmove results are live listings
+func (rp *RightmoveParser) TracksListings() bool {
+	return true
+}
+
+// SetCities limits the parser to the given cities
+func (rp *RightmoveParser) SetCities(cities []string) {
+	rp.cities = filterCities(rightmoveCities, cities)
+}
+
 func (rp *RightmoveParser) Parse(ctx context.Context) ([]models.Property, error) {
 	var allProperties []models.Property
 	
-	// UK cities to parse
-	cities := []string{
-		"London", "Manchester", "Birmingham", "Liverpool", "Leeds",
-		"Glasgow", "Edinburgh", "Bristol", "Cardiff", "Belfast",
-		"Newcastle", "Sheffield", "Leicester", "Coventry", "Nottingham",
-		"Southampton", "Portsmouth", "Brighton", "Reading", "Oxford",
-		"Cambridge", "York", "Bath", "Norwich", "Exeter",
-	}
-	
 	// Parse for both sale and rent
 	dealTypes := []struct {
 		path string
@@ -52,10 +74,12 @@ func (rp *RightmoveParser) Parse(ctx context.Context) ([]models.Property, error)
 		{"property-to-rent", "rent"},
 	}
 	
-	for _, city := range cities {
+	for _, city := range rp.cities {
 		for _, dealType := range dealTypes {
 			properties, err := rp.parseCity(ctx, city, dealType.path, dealType.name)
-			if err != nil {
+			coverageFrom(ctx).Record(city, dealType.name, err)
+			// A search cut short still returns the listings it found
+			if err != nil && !IsIncomplete(err) {
 				log.Printf("Error parsing %s/%s from Rightmove: %v", city, dealType.name, err)
 				continue
 			}
@@ -70,9 +94,7 @@ func (rp *RightmoveParser) Parse(ctx context.Context) ([]models.Property, error)
 	return allProperties, nil
 }
 
-func (rp *RightmoveParser) parseCity(ctx context.Context, city, path string, _ string) ([]models.Property, error) {
-	var properties []models.Property
-	
+func (rp *RightmoveParser) parseCity(ctx context.Context, city, path, dealType string) ([]models.Property, error) {
 	// Rightmove search URL - using location search
 	// Note: Location identifiers need to be mapped for each city
 	url := fmt.Sprintf("%s/%s/find.html?locationIdentifier=&minBedrooms=&maxBedrooms=&minPrice=&maxPrice=&propertyTypes=&mustHave=&dontShow=&furnishTypes=&keywords=%s",
@@ -81,17 +103,27 @@ func (rp *RightmoveParser) parseCity(ctx context.Context, city, path string, _ s
 		city,
 	)
 	
-	body, err := rp.Fetch(ctx, url)
-	if err != nil {
+	// Rightmove paginates by result offset, 24 listings per page
+	properties, err := rp.FetchPages(ctx, rp.Name(), Pagination{
+		URL:     url,
+		PageURL: OffsetPageURL(url, "index", 24),
+	}, func(doc *goquery.Document) ([]models.Property, error) {
+		return rp.parsePage(doc, city), nil
+	})
+	if err != nil && !IsIncomplete(err) {
 		return nil, fmt.Errorf("failed to fetch Rightmove listings: %w", err)
 	}
-	defer body.Close()
 	
-	doc, err := goquery.NewDocumentFromReader(body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	for i := range properties {
+		properties[i].DealType = dealType
 	}
 	
+	return properties, err
+}
+
+func (rp *RightmoveParser) parsePage(doc *goquery.Document, city string) []models.Property {
+	var properties []models.Property
+	
 	// Rightmove property card selector
 	doc.Find(".l-searchResults .propertyCard").Each(func(i int, s *goquery.Selection) {
 		property := rp.parseProperty(s)
@@ -101,7 +133,7 @@ func (rp *RightmoveParser) parseCity(ctx context.Context, city, path string, _ s
 		}
 	})
 	
-	return properties, nil
+	return properties
 }
 
 func (rp *RightmoveParser) parseProperty(s *goquery.Selection) *models.Property {
@@ -233,3 +265,40 @@ func (rp *RightmoveParser) extractIDFromURL(url string) string {
 	return url
 }
 
+
+// HasDetailPages reports that Rightmove listings have their own pages
+func (rp *RightmoveParser) HasDetailPages() bool {
+	return true
+}
+
+// FetchDetails visits the listing page and adds bathroom, floor and description details
+func (rp *RightmoveParser) FetchDetails(ctx context.Context, property *models.Property) error {
+	doc, err := rp.fetchDetailPage(ctx, property)
+	if err != nil {
+		return fmt.Errorf("failed to fetch Rightmove listing: %w", err)
+	}
+	
+	details := rp.parseDetails(doc)
+	details.Apply(property)
+	return nil
+}
+
+func (rp *RightmoveParser) parseDetails(doc *goquery.Document) ListingDetails {
+	var details ListingDetails
+	
+	// The info reel lists "PROPERTY TYPE", "BEDROOMS", "BATHROOMS" and "SIZE" tiles
+	reel := detailText(doc, "[data-testid='info-reel'] > div")
+	details.Bathrooms = matchInt(regexp.MustCompile(`(?i)bathrooms?\s*(\d+)`), reel, 1)
+	details.Bedrooms = matchInt(regexp.MustCompile(`(?i)bedrooms?\s*(\d+)`), reel, 1)
+	details.Area = rp.extractArea(reel)
+	
+	features := detailText(doc, "[data-testid='key-features'] li")
+	details.Floor = matchInt(regexp.MustCompile(`(?i)(\d+)(?:st|nd|rd|th)\s+floor`), features, 1)
+	details.YearBuilt = matchInt(regexp.MustCompile(`(?i)built in\s*(\d{4})`), features, 1)
+	
+	details.Description = strings.TrimSpace(doc.Find("[data-testid='description'], [itemprop='description']").First().Text())
+	details.Images = collectImages(doc, "[data-testid='gallery'] img, [data-testid='primary-image'] img", "src", "data-src")
+	
+	details.fillFrom(structuredDetails(doc))
+	return details
+}
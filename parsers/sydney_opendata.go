@@ -18,6 +18,16 @@ type SydneyOpenDataParser struct {
 	geocoding *utils.GeocodingService
 }
 
+func init() {
+	Register(SourceInfo{
+		Name:    "sydney_opendata",
+		Kind:    KindOpenData,
+		Country: "Australia",
+		Cities:  []string{"Sydney"},
+		New:     func() Parser { return NewSydneyOpenDataParser() },
+	})
+}
+
 func NewSydneyOpenDataParser() *SydneyOpenDataParser {
 	return &SydneyOpenDataParser{
 		BaseParser: NewBaseParser("https://data.nsw.gov.au"),
@@ -95,7 +105,7 @@ func (syd *SydneyOpenDataParser) Parse(ctx context.Context) ([]models.Property,
 				property.Latitude = lat
 				property.Longitude = lng
 			}
-			time.Sleep(1 * time.Second)
+			geocodePause()
 		}
 
 		properties = append(properties, *property)
@@ -18,6 +18,16 @@ type TokyoOpenDataParser struct {
 	geocoding *utils.GeocodingService
 }
 
+func init() {
+	Register(SourceInfo{
+		Name:    "tokyo_opendata",
+		Kind:    KindOpenData,
+		Country: "Japan",
+		Cities:  []string{"Tokyo"},
+		New:     func() Parser { return NewTokyoOpenDataParser() },
+	})
+}
+
 func NewTokyoOpenDataParser() *TokyoOpenDataParser {
 	return &TokyoOpenDataParser{
 		BaseParser: NewBaseParser("https://portal.data.metro.tokyo.lg.jp"),
@@ -94,7 +104,7 @@ func (tok *TokyoOpenDataParser) Parse(ctx context.Context) ([]models.Property, e
 				property.Latitude = lat
 				property.Longitude = lng
 			}
-			time.Sleep(1 * time.Second)
+			geocodePause()
 		}
 
 		properties = append(properties, *property)
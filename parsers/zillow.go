@@ -18,12 +18,34 @@ import (
 type ZillowParser struct {
 	*BaseParser
 	geocoding *utils.GeocodingService
+	cities    []string
+}
+
+// zillowCities are the USA cities to parse
+var zillowCities = []string{
+	"New York, NY", "Los Angeles, CA", "Chicago, IL", "Houston, TX", "Phoenix, AZ",
+	"Philadelphia, PA", "San Antonio, TX", "San Diego, CA", "Dallas, TX", "San Jose, CA",
+	"Austin, TX", "Jacksonville, FL", "Fort Worth, TX", "Columbus, OH", "Charlotte, NC",
+	"San Francisco, CA", "Indianapolis, IN", "Seattle, WA", "Denver, CO", "Washington, DC",
+	"Boston, MA", "El Paso, TX", "Nashville, TN", "Detroit, MI", "Oklahoma City, OK",
+	"Portland, OR", "Las Vegas, NV", "Memphis, TN", "Louisville, KY", "Baltimore, MD",
+}
+
+func init() {
+	Register(SourceInfo{
+		Name:    "zillow",
+		Kind:    KindCommercial,
+		Country: "United States",
+		Cities:  zillowCities,
+		New:     func() Parser { return NewZillowParser() },
+	})
 }
 
 func NewZillowParser() *ZillowParser {
 	return &ZillowParser{
 		BaseParser: NewBaseParser("https://www.zillow.com"),
 		geocoding:  utils.NewGeocodingService(),
+		cities:     zillowCities,
 	}
 }
 
@@ -31,19 +53,19 @@ func (zp *ZillowParser) Name() string {
 	return "zillow"
 }
 
+// TracksListings reports that Zillow results are live listings
+func (zp *ZillowParser) TracksListings() bool {
+	return true
+}
+
+// SetCities limits the parser to the given cities
+func (zp *ZillowParser) SetCities(cities []string) {
+	zp.cities = filterCities(zillowCities, cities)
+}
+
 func (zp *ZillowParser) Parse(ctx context.Context) ([]models.Property, error) {
 	var allProperties []models.Property
 	
-	// USA cities to parse
-	cities := []string{
-		"New York, NY", "Los Angeles, CA", "Chicago, IL", "Houston, TX", "Phoenix, AZ",
-		"Philadelphia, PA", "San Antonio, TX", "San Diego, CA", "Dallas, TX", "San Jose, CA",
-		"Austin, TX", "Jacksonville, FL", "Fort Worth, TX", "Columbus, OH", "Charlotte, NC",
-		"San Francisco, CA", "Indianapolis, IN", "Seattle, WA", "Denver, CO", "Washington, DC",
-		"Boston, MA", "El Paso, TX", "Nashville, TN", "Detroit, MI", "Oklahoma City, OK",
-		"Portland, OR", "Las Vegas, NV", "Memphis, TN", "Louisville, KY", "Baltimore, MD",
-	}
-	
 	// Parse for both sale and rent
 	dealTypes := []struct {
 		path string
@@ -53,10 +75,12 @@ func (zp *ZillowParser) Parse(ctx context.Context) ([]models.Property, error) {
 		{"apartments", "rent"},
 	}
 	
-	for _, city := range cities {
+	for _, city := range zp.cities {
 		for _, dealType := range dealTypes {
 			properties, err := zp.parseCity(ctx, city, dealType.path, dealType.name)
-			if err != nil {
+			coverageFrom(ctx).Record(city, dealType.name, err)
+			// A search cut short still returns the listings it found
+			if err != nil && !IsIncomplete(err) {
 				log.Printf("Error parsing %s/%s from Zillow: %v", city, dealType.name, err)
 				continue
 			}
@@ -71,23 +95,37 @@ func (zp *ZillowParser) Parse(ctx context.Context) ([]models.Property, error) {
 	return allProperties, nil
 }
 
-func (zp *ZillowParser) parseCity(ctx context.Context, city, path string, _ string) ([]models.Property, error) {
-	var properties []models.Property
-	
+func (zp *ZillowParser) parseCity(ctx context.Context, city, path, dealType string) ([]models.Property, error) {
 	// Zillow search URL
 	url := fmt.Sprintf("%s/%s/%s/", zp.baseURL, path, strings.ReplaceAll(city, " ", "-"))
 	
-	body, err := zp.Fetch(ctx, url)
-	if err != nil {
+	// Zillow links the next page; page N is also reachable as .../N_p/
+	properties, err := zp.FetchPages(ctx, zp.Name(), Pagination{
+		URL:          url,
+		NextSelector: "a[title='Next page']",
+		PageURL: func(page int) string {
+			if page <= 1 {
+				return url
+			}
+			return fmt.Sprintf("%s%d_p/", url, page)
+		},
+	}, func(doc *goquery.Document) ([]models.Property, error) {
+		return zp.parsePage(doc, city), nil
+	})
+	if err != nil && !IsIncomplete(err) {
 		return nil, fmt.Errorf("failed to fetch Zillow listings: %w", err)
 	}
-	defer body.Close()
 	
-	doc, err := goquery.NewDocumentFromReader(body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	for i := range properties {
+		properties[i].DealType = dealType
 	}
 	
+	return properties, err
+}
+
+func (zp *ZillowParser) parsePage(doc *goquery.Document, city string) []models.Property {
+	var properties []models.Property
+	
 	// Zillow property card selectors
 	doc.Find("[data-test='property-card']").Each(func(i int, s *goquery.Selection) {
 		property := zp.parseProperty(s, city)
@@ -106,7 +144,7 @@ func (zp *ZillowParser) parseCity(ctx context.Context, city, path string, _ stri
 		})
 	}
 	
-	return properties, nil
+	return properties
 }
 
 func (zp *ZillowParser) parseProperty(s *goquery.Selection, city string) *models.Property {
@@ -241,3 +279,40 @@ func (zp *ZillowParser) extractIDFromURL(url string) string {
 	return url
 }
 
+
+// HasDetailPages reports that Zillow listings have their own pages
+func (zp *ZillowParser) HasDetailPages() bool {
+	return true
+}
+
+// FetchDetails visits the listing page and adds building facts and the description
+func (zp *ZillowParser) FetchDetails(ctx context.Context, property *models.Property) error {
+	doc, err := zp.fetchDetailPage(ctx, property)
+	if err != nil {
+		return fmt.Errorf("failed to fetch Zillow listing: %w", err)
+	}
+	
+	details := zp.parseDetails(doc)
+	details.Apply(property)
+	return nil
+}
+
+func (zp *ZillowParser) parseDetails(doc *goquery.Document) ListingDetails {
+	var details ListingDetails
+	
+	summary := detailText(doc, "[data-testid='bed-bath-item']")
+	details.Bedrooms = zp.extractBedrooms(summary)
+	details.Bathrooms = zp.extractBathrooms(summary)
+	details.Area = zp.extractArea(summary)
+	
+	facts := detailText(doc, "[data-testid='facts-list'] li, .hdp-fact-ul li")
+	details.YearBuilt = matchInt(regexp.MustCompile(`(?i)built in\s*(\d{4})`), facts, 1)
+	details.Floor = matchInt(regexp.MustCompile(`(?i)unit (?:floor|level)[^\d]*(\d+)`), facts, 1)
+	details.TotalFloors = matchInt(regexp.MustCompile(`(?i)stories[^\d]*(\d+)`), facts, 1)
+	
+	details.Description = strings.TrimSpace(doc.Find("[data-testid='description']").First().Text())
+	details.Images = collectImages(doc, "[data-testid='media-stream'] img", "src")
+	
+	details.fillFrom(structuredDetails(doc))
+	return details
+}
@@ -3,6 +3,8 @@ package services
 import (
 	"sync"
 	"time"
+
+	"pricemap-go/metrics"
 )
 
 // CacheService provides in-memory caching
@@ -35,14 +37,12 @@ func (cs *CacheService) Get(key string) (interface{}, bool) {
 	defer cs.mu.RUnlock()
 	
 	entry, exists := cs.cache[key]
-	if !exists {
-		return nil, false
-	}
-	
-	if time.Now().After(entry.ExpiresAt) {
+	if !exists || time.Now().After(entry.ExpiresAt) {
+		metrics.CacheMisses.Inc()
 		return nil, false
 	}
 	
+	metrics.CacheHits.Inc()
 	return entry.Data, true
 }
 
@@ -1,148 +1,189 @@
 package services
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
-	"pricemap-go/config"
+	"math"
+	"sort"
+	"time"
+
+	"pricemap-go/database"
 	"pricemap-go/models"
+	"pricemap-go/utils"
+)
+
+const (
+	crimeRadius = 0.5                  // Kilometres around a property whose incidents count
+	crimeWindow = 365 * 24 * time.Hour // Incidents counted, back from now
+
+	// defaultCrimeScore is the score of cities without imported incidents
+	defaultCrimeScore = 70.0
 )
 
-type CrimeService struct {
-	client *http.Client
+// crimeCellKm is the side of the grid cells the typical density of a city is
+// measured in, as large in area as the circle around a property
+var crimeCellKm = crimeRadius * math.Sqrt(math.Pi)
+
+// crimeSeverities weigh incidents by category, a violent crime counting most
+var crimeSeverities = map[string]float64{
+	models.CrimeCategoryViolent:   1.0,
+	models.CrimeCategoryWeapons:   0.8,
+	models.CrimeCategoryBurglary:  0.7,
+	models.CrimeCategoryVehicle:   0.5,
+	models.CrimeCategoryTheft:     0.4,
+	models.CrimeCategoryVandalism: 0.3,
+	models.CrimeCategoryDrugs:     0.3,
+	models.CrimeCategoryDisorder:  0.15,
+	models.CrimeCategoryOther:     0.2,
 }
 
+type CrimeService struct{}
+
 func NewCrimeService() *CrimeService {
-	return &CrimeService{
-		client: &http.Client{},
-	}
+	return &CrimeService{}
 }
 
-// GetCrimeData fetches crime data for a location
-func (cs *CrimeService) GetCrimeData(lat, lng float64, country, city string) (*CrimeData, error) {
-	// Try different sources based on country
-	switch country {
-	case "United States":
-		return cs.getUSCrimeData(city)
-	case "United Kingdom":
-		return cs.getUKCrimeData(lat, lng)
-	default:
-		// Generic fallback - use OpenStreetMap or other sources
-		return cs.getGenericCrimeData(lat, lng, city)
-	}
+// CrimeIndex holds the incidents of a city within crimeWindow of now, and
+// their typical density
+type CrimeIndex struct {
+	incidents   *utils.SpatialIndex[models.CrimeIncident]
+	cityDensity float64 // Median severity per km² of the grid cells with incidents
+	from, to    time.Time
 }
 
-// CrimeData represents crime statistics for an area
-type CrimeData struct {
-	Score      float64 `json:"score"`      // 0-100, where 100 is safest
-	CrimeRate  float64 `json:"crime_rate"` // Crimes per 1000 people
-	ViolentCrime float64 `json:"violent_crime"`
-	PropertyCrime float64 `json:"property_crime"`
-	Source     string  `json:"source"`
-	LastUpdated string `json:"last_updated"`
+// NewCrimeIndex indexes the incidents of a city within crimeWindow of now and
+// measures the typical density of the city on a grid. A city whose data
+// stopped being updated counts fewer incidents rather than its last year of
+// data. Incidents dated in the future are mistyped and left out.
+func NewCrimeIndex(incidents []models.CrimeIncident) *CrimeIndex {
+	return newCrimeIndex(incidents, time.Now())
 }
 
-// getUSCrimeData fetches crime data from US sources
-func (cs *CrimeService) getUSCrimeData(city string) (*CrimeData, error) {
-	// Try city open data portals
-	// Example: Chicago, NYC, LA have open data APIs
-	
-	// For now, return placeholder - can be extended with actual API calls
-	return &CrimeData{
-		Score:      70.0, // Default safe score
-		CrimeRate:  30.0, // Default crime rate
-		Source:     "placeholder",
-		LastUpdated: "2024-01-01",
-	}, nil
+func newCrimeIndex(incidents []models.CrimeIncident, now time.Time) *CrimeIndex {
+	index := &CrimeIndex{
+		incidents: utils.NewSpatialIndex[models.CrimeIncident](utils.DefaultSpatialCellKm),
+		from:      now.Add(-crimeWindow),
+		to:        now,
+	}
+	latest := now.Add(crimeDateLeeway)
+
+	var recent []models.CrimeIncident
+	meanLat := 0.0
+	for _, incident := range incidents {
+		if incident.OccurredAt.After(index.from) && !incident.OccurredAt.After(latest) {
+			recent = append(recent, incident)
+			meanLat += incident.Latitude
+		}
+	}
+	if len(recent) == 0 {
+		return index
+	}
+	meanLat /= float64(len(recent))
+
+	// Cells of equal area around the city's latitude
+	dLat, dLng := utils.BoundingBox(meanLat, crimeCellKm/2)
+	cells := make(map[[2]int]float64)
+	for _, incident := range recent {
+		index.incidents.Insert(incident.Latitude, incident.Longitude, incident)
+		cell := [2]int{int(math.Floor(incident.Latitude / (2 * dLat))), int(math.Floor(incident.Longitude / (2 * dLng)))}
+		cells[cell] += crimeSeverity(incident.Category)
+	}
+
+	densities := make([]float64, 0, len(cells))
+	for _, severity := range cells {
+		densities = append(densities, severity/(crimeCellKm*crimeCellKm))
+	}
+	index.cityDensity = median(densities)
+	return index
 }
 
-// getUKCrimeData fetches crime data from UK Police API
-func (cs *CrimeService) getUKCrimeData(lat, lng float64) (*CrimeData, error) {
-	// UK Police Data API
-	url := fmt.Sprintf("https://data.police.uk/api/crimes-street/all-crime?lat=%.6f&lng=%.6f",
-		lat, lng)
-	
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
+// LoadCrimeIndex indexes the stored recent incidents of a city
+func LoadCrimeIndex(city string) (*CrimeIndex, error) {
+	if database.DB == nil {
+		return NewCrimeIndex(nil), nil // No incidents without a database
 	}
-	
-	req.Header.Set("User-Agent", config.AppConfig.UserAgent)
-	
-	resp, err := cs.client.Do(req)
+
+	now := time.Now()
+	var incidents []models.CrimeIncident
+	err := database.DB.Select("category", "occurred_at", "latitude", "longitude").
+		Where("city = ? AND occurred_at > ? AND occurred_at <= ?", city, now.Add(-crimeWindow), now.Add(crimeDateLeeway)).
+		Find(&incidents).Error
 	if err != nil {
-		// Fallback to default
-		return &CrimeData{Score: 70.0, Source: "fallback"}, nil
+		return nil, fmt.Errorf("failed to load crime incidents of %s: %w", city, err)
 	}
-	defer resp.Body.Close()
-	
-	if resp.StatusCode != http.StatusOK {
-		return &CrimeData{Score: 70.0, Source: "fallback"}, nil
+	return newCrimeIndex(incidents, now), nil
+}
+
+// Len returns the number of indexed incidents
+func (ci *CrimeIndex) Len() int {
+	return ci.incidents.Len()
+}
+
+// CrimeAssessment is a crime score with the incidents it is based on, stored
+// as the CrimeData of the property factors
+type CrimeAssessment struct {
+	Score       float64        `json:"score"`
+	Incidents   int            `json:"incidents"` // Within radius
+	Radius      int            `json:"radius_m"`
+	Density     float64        `json:"density"`      // Severity-weighted incidents per km² within radius
+	CityDensity float64        `json:"city_density"` // The same, typical of the city
+	Categories  map[string]int `json:"categories"`   // Incidents within radius by category
+	From        string         `json:"from,omitempty"`
+	To          string         `json:"to,omitempty"`
+	Note        string         `json:"note,omitempty"`
+}
+
+// AssessCrime scores safety (0-100, 100 the safest) from the incidents of
+// the last year around a property. Incidents within crimeRadius are
+// weighted by severity and compared, per km², with the typical density of
+// the city: a property as dangerous as the median part of the city with
+// crime scores 50, one with no crime around it 100.
+func (cs *CrimeService) AssessCrime(property *models.Property, incidents *CrimeIndex) CrimeAssessment {
+	if incidents.Len() == 0 {
+		return CrimeAssessment{
+			Score:      defaultCrimeScore,
+			Radius:     int(crimeRadius * 1000),
+			Categories: map[string]int{},
+			Note:       "no crime incidents known for the city in the last year",
+		}
 	}
-	
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return &CrimeData{Score: 70.0, Source: "fallback"}, nil
+
+	assessment := CrimeAssessment{
+		Radius:     int(crimeRadius * 1000),
+		Categories: make(map[string]int),
+		From:       incidents.from.Format("2006-01-02"),
+		To:         incidents.to.Format("2006-01-02"),
 	}
-	
-	var crimes []map[string]interface{}
-	if err := json.Unmarshal(body, &crimes); err != nil {
-		return &CrimeData{Score: 70.0, Source: "fallback"}, nil
+	severity := 0.0
+	for _, neighbor := range incidents.incidents.WithinRadius(property.Latitude, property.Longitude, crimeRadius) {
+		assessment.Incidents++
+		assessment.Categories[neighbor.Value.Category]++
+		severity += crimeSeverity(neighbor.Value.Category)
 	}
-	
-	// Calculate crime score based on number of crimes
-	crimeCount := len(crimes)
-	score := 100.0
-	
-	// Adjust score based on crime count
-	// More crimes = lower score
-	if crimeCount > 100 {
-		score = 30.0
-	} else if crimeCount > 50 {
-		score = 50.0
-	} else if crimeCount > 20 {
-		score = 70.0
-	} else if crimeCount > 10 {
-		score = 85.0
+
+	density := severity / (math.Pi * crimeRadius * crimeRadius)
+	city := incidents.cityDensity
+	assessment.Density = math.Round(density*100) / 100
+	assessment.CityDensity = math.Round(city*100) / 100
+	assessment.Score = math.Round(100*city/(city+density)*100) / 100
+	if density == 0 {
+		assessment.Score = 100
 	}
-	
-	return &CrimeData{
-		Score:      score,
-		CrimeRate:  float64(crimeCount) * 10, // Approximate
-		Source:     "uk-police-api",
-		LastUpdated: "2024-01-01",
-	}, nil
+	return assessment
 }
 
-// getGenericCrimeData gets generic crime data
-func (cs *CrimeService) getGenericCrimeData(lat, lng float64, city string) (*CrimeData, error) {
-	// Placeholder - can integrate with other sources
-	// For example: Numbeo crime index, local police APIs, etc.
-	
-	return &CrimeData{
-		Score:      70.0,
-		CrimeRate:  30.0,
-		Source:     "generic",
-		LastUpdated: "2024-01-01",
-	}, nil
+func crimeSeverity(category string) float64 {
+	if severity, ok := crimeSeverities[category]; ok {
+		return severity
+	}
+	return crimeSeverities[models.CrimeCategoryOther]
 }
 
-// CalculateCrimeScore calculates crime score for a property
-func (cs *CrimeService) CalculateCrimeScore(property *models.Property) (float64, string, error) {
-	crimeData, err := cs.GetCrimeData(
-		property.Latitude,
-		property.Longitude,
-		property.Country,
-		property.City,
-	)
-	
-	if err != nil {
-		return 70.0, "{}", err
+// median returns the median of values, which it sorts
+func median(values []float64) float64 {
+	sort.Float64s(values)
+	middle := len(values) / 2
+	if len(values)%2 == 0 {
+		return (values[middle-1] + values[middle]) / 2
 	}
-	
-	dataJSON, _ := json.Marshal(crimeData)
-	
-	return crimeData.Score, string(dataJSON), nil
+	return values[middle]
 }
-
@@ -0,0 +1,408 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha1"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"pricemap-go/database"
+	"pricemap-go/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Crime incident file formats
+const (
+	CrimeFormatNYC      = "nyc"       // NYPD complaint data (historic or year to date)
+	CrimeFormatChicago  = "chicago"   // City of Chicago crimes, 2001 to present
+	CrimeFormatUKPolice = "uk-police" // data.police.uk street-level crimes, a CSV or an archive of them
+	CrimeFormatCSV      = "csv"       // any CSV with location, category and date columns
+)
+
+// CrimeFormats lists the crime incident file formats
+var CrimeFormats = []string{CrimeFormatNYC, CrimeFormatChicago, CrimeFormatUKPolice, CrimeFormatCSV}
+
+// crimeBatchSize is how many incidents are upserted at once
+const crimeBatchSize = 1000
+
+// crimeDateLeeway is how far past the present an incident date may be:
+// dates are local times read as UTC, so today's incidents of cities east of
+// Greenwich can look a few hours ahead. Later dates are typos.
+const crimeDateLeeway = 24 * time.Hour
+
+// socrataLayout is the date format of CSV exports of Socrata open-data
+// portals, which NYC and Chicago use
+const socrataLayout = "2006-01-02T15:04:05.000"
+
+// CrimeColumns maps the columns of an incident CSV file. Each field lists
+// the columns to read, the first non-empty one of a row winning. Column
+// names match case-insensitively, with spaces and underscores alike, so
+// "Primary Type" also finds primary_type.
+type CrimeColumns struct {
+	ID        []string // optional; rows without one are identified by a hash
+	Category  []string // the offence, classified by CrimeCategory
+	Date      []string
+	Latitude  []string
+	Longitude []string
+
+	DateLayouts []string // time.Parse layouts tried in order
+}
+
+var crimeFormatColumns = map[string]CrimeColumns{
+	CrimeFormatNYC: {
+		ID:          []string{"CMPLNT_NUM"},
+		Category:    []string{"OFNS_DESC", "PD_DESC"},
+		Date:        []string{"CMPLNT_FR_DT", "RPT_DT"},
+		Latitude:    []string{"Latitude"},
+		Longitude:   []string{"Longitude"},
+		DateLayouts: []string{"01/02/2006", socrataLayout},
+	},
+	CrimeFormatChicago: {
+		ID:          []string{"ID"},
+		Category:    []string{"Primary Type"},
+		Date:        []string{"Date"},
+		Latitude:    []string{"Latitude"},
+		Longitude:   []string{"Longitude"},
+		DateLayouts: []string{"01/02/2006 03:04:05 PM", socrataLayout},
+	},
+	CrimeFormatUKPolice: {
+		ID:          []string{"Crime ID"},
+		Category:    []string{"Crime type"},
+		Date:        []string{"Month"},
+		Latitude:    []string{"Latitude"},
+		Longitude:   []string{"Longitude"},
+		DateLayouts: []string{"2006-01"},
+	},
+	CrimeFormatCSV: {
+		ID:        []string{"id"},
+		Category:  []string{"category", "type", "offense"},
+		Date:      []string{"date", "occurred_at"},
+		Latitude:  []string{"latitude", "lat"},
+		Longitude: []string{"longitude", "lng", "lon"},
+		DateLayouts: []string{time.RFC3339, "2006-01-02T15:04:05", socrataLayout, "2006-01-02 15:04:05",
+			"2006-01-02", "01/02/2006 03:04:05 PM", "01/02/2006", "2006-01"},
+	},
+}
+
+// CrimeColumnsFor returns the column mapping of a format
+func CrimeColumnsFor(format string) (CrimeColumns, error) {
+	columns, ok := crimeFormatColumns[format]
+	if !ok {
+		return CrimeColumns{}, fmt.Errorf("unknown crime format %q (available: %s)", format, strings.Join(CrimeFormats, ", "))
+	}
+	return columns, nil
+}
+
+// ImportCrimes reads the incidents of a crime file in format, a CSV file or
+// a zip archive of them, and stores them for city. Incidents already stored
+// from the same source are updated, so overlapping files, e.g. monthly
+// archives, can be imported one after another. It returns how many incidents
+// were read.
+func ImportCrimes(ctx context.Context, city, format string, columns CrimeColumns, location string) (int, error) {
+	reader := NewCrimeReader(city, format, columns)
+
+	var batch []models.CrimeIncident
+	count := 0
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		emit := func(incident models.CrimeIncident) error {
+			batch = append(batch, incident)
+			if len(batch) < crimeBatchSize {
+				return nil
+			}
+			count += len(batch)
+			err := saveCrimeIncidents(tx, batch)
+			batch = batch[:0]
+			return err
+		}
+
+		if err := readCrimeFile(ctx, reader, location, emit); err != nil {
+			return err
+		}
+		count += len(batch)
+		return saveCrimeIncidents(tx, batch)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if reader.Skipped > 0 {
+		log.Printf("Skipped %d rows of %s without a valid location or date, or dated in the future", reader.Skipped, location)
+	}
+	if count == 0 {
+		return 0, fmt.Errorf("crime file %s has no incidents with a location and date", location)
+	}
+	return count, nil
+}
+
+// readCrimeFile reads a CSV file, or every CSV file of a zip archive in name
+// order; of data.police.uk archives only the street-level files
+func readCrimeFile(ctx context.Context, reader *CrimeReader, location string, emit func(models.CrimeIncident) error) error {
+	if !strings.HasSuffix(strings.ToLower(location), ".zip") {
+		file, err := os.Open(location)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return reader.Read(ctx, file, emit)
+	}
+
+	archive, err := zip.OpenReader(location)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", location, err)
+	}
+	defer archive.Close()
+
+	var files []*zip.File
+	for _, file := range archive.File {
+		name := strings.ToLower(path.Base(file.Name))
+		if !strings.HasSuffix(name, ".csv") {
+			continue
+		}
+		if reader.source == CrimeFormatUKPolice && !strings.HasSuffix(name, "-street.csv") {
+			continue // outcomes and stop and search
+		}
+		files = append(files, file)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("%s has no crime CSV files", location)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	for _, file := range files {
+		r, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("%s: %w", file.Name, err)
+		}
+		err = reader.Read(ctx, r, emit)
+		r.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", file.Name, err)
+		}
+	}
+	return nil
+}
+
+// CrimeReader turns the rows of incident CSV files into incidents of a city.
+// Rows without an ID get one from a hash of the row, numbered when the same
+// row repeats, so that importing a file again finds the same incidents.
+type CrimeReader struct {
+	city    string
+	source  string
+	columns CrimeColumns
+	hashes  map[string]int
+	latest  time.Time // incidents dated later are skipped
+
+	Skipped int // rows without a valid location or date, or dated in the future
+}
+
+// NewCrimeReader creates a reader of the files of a format, mapped by columns
+func NewCrimeReader(city, format string, columns CrimeColumns) *CrimeReader {
+	return &CrimeReader{
+		city:    city,
+		source:  format,
+		columns: columns,
+		hashes:  make(map[string]int),
+		latest:  time.Now().Add(crimeDateLeeway),
+	}
+}
+
+// Read calls emit for every incident of a CSV file
+func (cr *CrimeReader) Read(ctx context.Context, r io.Reader, emit func(models.CrimeIncident) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("invalid crime file: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, column := range header {
+		columns[crimeColumnKey(strings.TrimPrefix(column, "\ufeff"))] = i
+	}
+	for _, required := range []struct {
+		name    string
+		columns []string
+	}{
+		{"location", cr.columns.Latitude},
+		{"location", cr.columns.Longitude},
+		{"date", cr.columns.Date},
+	} {
+		if !hasCrimeColumn(columns, required.columns) {
+			return fmt.Errorf("invalid crime file: no %s column (%s)", required.name, strings.Join(required.columns, ", "))
+		}
+	}
+
+	for rows := 0; ; rows++ {
+		if rows%crimeBatchSize == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid crime file: %w", err)
+		}
+
+		field := func(names []string) string {
+			for _, name := range names {
+				if i, ok := columns[crimeColumnKey(name)]; ok && i < len(record) {
+					if value := strings.TrimSpace(record[i]); value != "" {
+						return value
+					}
+				}
+			}
+			return ""
+		}
+
+		incident, ok := cr.incident(record, field)
+		if !ok {
+			cr.Skipped++
+			continue
+		}
+		if err := emit(incident); err != nil {
+			return err
+		}
+	}
+}
+
+func (cr *CrimeReader) incident(record []string, field func([]string) string) (models.CrimeIncident, bool) {
+	lat, errLat := strconv.ParseFloat(field(cr.columns.Latitude), 64)
+	lng, errLng := strconv.ParseFloat(field(cr.columns.Longitude), 64)
+	if errLat != nil || errLng != nil || math.Abs(lat) > 90 || math.Abs(lng) > 180 || (lat == 0 && lng == 0) {
+		return models.CrimeIncident{}, false
+	}
+
+	occurred, ok := parseCrimeDate(field(cr.columns.Date), cr.columns.DateLayouts)
+	if !ok || occurred.After(cr.latest) {
+		return models.CrimeIncident{}, false
+	}
+
+	offense := field(cr.columns.Category)
+	id := field(cr.columns.ID)
+	if id == "" {
+		sum := sha1.Sum([]byte(strings.Join(record, "\x1f")))
+		hash := hex.EncodeToString(sum[:8])
+		cr.hashes[hash]++
+		id = "row-" + hash
+		if n := cr.hashes[hash]; n > 1 {
+			id += "-" + strconv.Itoa(n)
+		}
+	}
+
+	return models.CrimeIncident{
+		City:       cr.city,
+		Source:     cr.source,
+		IncidentID: id,
+		Category:   CrimeCategory(offense),
+		Offense:    offense,
+		OccurredAt: occurred,
+		Latitude:   lat,
+		Longitude:  lng,
+	}, true
+}
+
+// crimeColumnKey is the form column names are matched in
+func crimeColumnKey(name string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), " ", "_")
+}
+
+func hasCrimeColumn(columns map[string]int, names []string) bool {
+	for _, name := range names {
+		if _, ok := columns[crimeColumnKey(name)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func parseCrimeDate(value string, layouts []string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// crimeCategoryKeywords classify offence names, the first match winning, so
+// that "motor vehicle theft" is a vehicle crime rather than a theft
+var crimeCategoryKeywords = []struct {
+	category string
+	keywords []string
+}{
+	{models.CrimeCategoryVehicle, []string{"vehicle", "motor", "auto", "carjack"}},
+	{models.CrimeCategoryWeapons, []string{"weapon", "firearm"}},
+	{models.CrimeCategoryViolent, []string{"homicide", "murder", "manslaughter", "assault", "battery", "robbery",
+		"rape", "sex", "violence", "kidnap"}},
+	{models.CrimeCategoryBurglary, []string{"burglary", "breaking"}},
+	{models.CrimeCategoryTheft, []string{"theft", "larceny", "shoplifting", "stolen", "pickpocket"}},
+	{models.CrimeCategoryVandalism, []string{"criminal damage", "criminal mischief", "arson", "vandal", "graffiti"}},
+	{models.CrimeCategoryDrugs, []string{"drug", "narcotic", "cannabis", "controlled substance"}},
+	{models.CrimeCategoryDisorder, []string{"anti-social", "antisocial", "public order", "public peace", "disorderly",
+		"trespass", "intoxicat", "liquor"}},
+}
+
+// CrimeCategory classifies an offence name of any source, e.g. "FELONY
+// ASSAULT", "MOTOR VEHICLE THEFT" or "Criminal damage and arson". Names that
+// already are categories are kept; unknown ones are other.
+func CrimeCategory(offense string) string {
+	name := strings.ToLower(strings.TrimSpace(offense))
+	for _, category := range models.CrimeCategories {
+		if name == category {
+			return category
+		}
+	}
+	for _, rule := range crimeCategoryKeywords {
+		for _, keyword := range rule.keywords {
+			if strings.Contains(name, keyword) {
+				return rule.category
+			}
+		}
+	}
+	return models.CrimeCategoryOther
+}
+
+// saveCrimeIncidents upserts incidents by city, source and incident ID. An
+// ID repeated within the batch keeps its last row, as one statement cannot
+// update a row twice.
+func saveCrimeIncidents(tx *gorm.DB, incidents []models.CrimeIncident) error {
+	last := make(map[string]int, len(incidents))
+	for i, incident := range incidents {
+		last[incident.IncidentID] = i
+	}
+	unique := make([]models.CrimeIncident, 0, len(last))
+	for i, incident := range incidents {
+		if last[incident.IncidentID] == i {
+			unique = append(unique, incident)
+		}
+	}
+	if len(unique) == 0 {
+		return nil
+	}
+
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "city"}, {Name: "source"}, {Name: "incident_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"category", "offense", "occurred_at", "latitude", "longitude", "updated_at"}),
+	}).Create(&unique).Error
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"pricemap-go/models"
+)
+
+func TestCrimeCategory(t *testing.T) {
+	tests := []struct {
+		offense  string
+		category string
+	}{
+		{"FELONY ASSAULT", models.CrimeCategoryViolent},
+		{"ROBBERY", models.CrimeCategoryViolent},
+		{"Violence and sexual offences", models.CrimeCategoryViolent},
+		{"MOTOR VEHICLE THEFT", models.CrimeCategoryVehicle},
+		{"Vehicle crime", models.CrimeCategoryVehicle},
+		{"DANGEROUS WEAPONS", models.CrimeCategoryWeapons},
+		{"Possession of weapons", models.CrimeCategoryWeapons},
+		{"BURGLARY", models.CrimeCategoryBurglary},
+		{"PETIT LARCENY", models.CrimeCategoryTheft},
+		{"Other theft", models.CrimeCategoryTheft},
+		{"Criminal damage and arson", models.CrimeCategoryVandalism},
+		{"CRIMINAL MISCHIEF & RELATED OF", models.CrimeCategoryVandalism},
+		{"NARCOTICS", models.CrimeCategoryDrugs},
+		{"Anti-social behaviour", models.CrimeCategoryDisorder},
+		{"CRIMINAL TRESPASS", models.CrimeCategoryDisorder},
+		{" Burglary ", models.CrimeCategoryBurglary},
+		{"theft", models.CrimeCategoryTheft},
+		{"DECEPTIVE PRACTICE", models.CrimeCategoryOther},
+		{"", models.CrimeCategoryOther},
+	}
+	for _, tt := range tests {
+		if got := CrimeCategory(tt.offense); got != tt.category {
+			t.Errorf("CrimeCategory(%q) = %q, want %q", tt.offense, got, tt.category)
+		}
+	}
+}
+
+// readCrimes reads a CSV file of format with its default columns
+func readCrimes(t *testing.T, format, data string) ([]models.CrimeIncident, *CrimeReader) {
+	t.Helper()
+	columns, err := CrimeColumnsFor(format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := NewCrimeReader("Test", format, columns)
+	var incidents []models.CrimeIncident
+	err = reader.Read(context.Background(), strings.NewReader(data), func(incident models.CrimeIncident) error {
+		incidents = append(incidents, incident)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Read(%s) error = %v", format, err)
+	}
+	return incidents, reader
+}
+
+func TestCrimeReader_NYC(t *testing.T) {
+	data := "CMPLNT_NUM,CMPLNT_FR_DT,CMPLNT_FR_TM,RPT_DT,OFNS_DESC,PD_DESC,Latitude,Longitude\n" +
+		"100001,03/14/2024,21:30:00,03/15/2024,FELONY ASSAULT,ASSAULT 2,40.7484,-73.9857\n" +
+		"100002,,,03/16/2024,,LARCENY PETIT FROM STORE,40.7500,-73.9900\n" +
+		"100003,03/17/2024,10:00:00,03/17/2024,BURGLARY,,,\n" +
+		"100004,not a date,,,ROBBERY,,40.7510,-73.9910\n" +
+		"100005,03/14/2204,21:30:00,03/15/2024,FELONY ASSAULT,ASSAULT 2,40.7484,-73.9857\n"
+	incidents, reader := readCrimes(t, CrimeFormatNYC, data)
+
+	// Rows without a location or date, and the mistyped date in the future, are skipped
+	if len(incidents) != 2 || reader.Skipped != 3 {
+		t.Fatalf("Read() = %d incidents, %d skipped, want 2, 3", len(incidents), reader.Skipped)
+	}
+	got := incidents[0]
+	if got.IncidentID != "100001" || got.Source != CrimeFormatNYC || got.City != "Test" ||
+		got.Category != models.CrimeCategoryViolent || got.Offense != "FELONY ASSAULT" ||
+		!got.OccurredAt.Equal(time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC)) || got.Latitude != 40.7484 {
+		t.Errorf("incident = %+v", got)
+	}
+	// Without a complaint date or offence the report date and description stand in
+	got = incidents[1]
+	if got.Category != models.CrimeCategoryTheft || !got.OccurredAt.Equal(time.Date(2024, 3, 16, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("incident = %+v", got)
+	}
+}
+
+func TestCrimeReader_Chicago(t *testing.T) {
+	data := "ID,Case Number,Date,Block,IUCR,Primary Type,Description,Latitude,Longitude,Location\n" +
+		`12345678,JG100001,01/05/2024 11:45:00 PM,001XX N STATE ST,0820,THEFT,$500 AND UNDER,41.883,-87.628,"(41.883, -87.628)"` + "\n" +
+		`12345679,JG100002,2024-01-06T08:00:00.000,001XX N STATE ST,0910,MOTOR VEHICLE THEFT,AUTOMOBILE,41.884,-87.627,"(41.884, -87.627)"` + "\n"
+	incidents, _ := readCrimes(t, CrimeFormatChicago, data)
+
+	if len(incidents) != 2 {
+		t.Fatalf("Read() = %d incidents, want 2", len(incidents))
+	}
+	if got := incidents[0]; got.Category != models.CrimeCategoryTheft ||
+		!got.OccurredAt.Equal(time.Date(2024, 1, 5, 23, 45, 0, 0, time.UTC)) {
+		t.Errorf("incident = %+v", got)
+	}
+	if got := incidents[1]; got.IncidentID != "12345679" || got.Category != models.CrimeCategoryVehicle {
+		t.Errorf("incident = %+v", got)
+	}
+}
+
+func TestCrimeReader_UKPolice(t *testing.T) {
+	data := "\ufeffCrime ID,Month,Reported by,Falls within,Longitude,Latitude,Location,LSOA code,LSOA name,Crime type,Last outcome category,Context\n" +
+		"abc123,2024-02,Metropolitan Police Service,Metropolitan Police Service,-0.1276,51.5072,On or near Parliament Street,E01004736,Westminster 018A,Burglary,Under investigation,\n" +
+		",2024-02,Metropolitan Police Service,Metropolitan Police Service,-0.1280,51.5075,On or near Whitehall,E01004736,Westminster 018A,Anti-social behaviour,,\n" +
+		",2024-02,Metropolitan Police Service,Metropolitan Police Service,-0.1280,51.5075,On or near Whitehall,E01004736,Westminster 018A,Anti-social behaviour,,\n" +
+		",2024-02,Metropolitan Police Service,Metropolitan Police Service,,,No location,,,Other crime,,\n"
+	incidents, reader := readCrimes(t, CrimeFormatUKPolice, data)
+
+	if len(incidents) != 3 || reader.Skipped != 1 {
+		t.Fatalf("Read() = %d incidents, %d skipped, want 3, 1", len(incidents), reader.Skipped)
+	}
+	if got := incidents[0]; got.IncidentID != "abc123" || got.Category != models.CrimeCategoryBurglary ||
+		!got.OccurredAt.Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)) || got.Longitude != -0.1276 {
+		t.Errorf("incident = %+v", got)
+	}
+
+	// Anti-social behaviour has no ID: repeated rows are told apart
+	first, second := incidents[1].IncidentID, incidents[2].IncidentID
+	if !strings.HasPrefix(first, "row-") || second != first+"-2" || incidents[1].Category != models.CrimeCategoryDisorder {
+		t.Errorf("IDs = %q, %q", first, second)
+	}
+
+	// Reading the file again gives the same IDs
+	again, _ := readCrimes(t, CrimeFormatUKPolice, data)
+	if again[1].IncidentID != first || again[2].IncidentID != second {
+		t.Errorf("IDs on a second read = %q, %q, want %q, %q", again[1].IncidentID, again[2].IncidentID, first, second)
+	}
+}
+
+func TestCrimeReader_Columns(t *testing.T) {
+	data := "Datum,Delikt,Breite,Länge\n"
+	columns := CrimeColumns{
+		Category:    []string{"delikt"},
+		Date:        []string{"Datum"},
+		Latitude:    []string{"Breite"},
+		Longitude:   []string{"Länge"},
+		DateLayouts: []string{"02.01.2006"},
+	}
+
+	// The generic format guesses common column names and date formats
+	incidents, _ := readCrimes(t, CrimeFormatCSV, "lat,lng,type,occurred_at\n"+
+		"52.52,13.405,Robbery,2024-05-01T22:10:00Z\n"+
+		"52.53,13.41,vandalism,2024-05-02\n")
+	if len(incidents) != 2 || incidents[0].Category != models.CrimeCategoryViolent ||
+		incidents[1].Category != models.CrimeCategoryVandalism || incidents[1].OccurredAt.Day() != 2 {
+		t.Errorf("incidents = %+v", incidents)
+	}
+
+	// Mapped columns, with a custom date format
+	reader := NewCrimeReader("Berlin", CrimeFormatCSV, columns)
+	var got []models.CrimeIncident
+	err := reader.Read(context.Background(), strings.NewReader(data+"01.06.2024,Diebstahl,52.52,13.405\n"),
+		func(incident models.CrimeIncident) error {
+			got = append(got, incident)
+			return nil
+		})
+	if err != nil || len(got) != 1 || got[0].OccurredAt.Month() != time.June || got[0].Offense != "Diebstahl" {
+		t.Errorf("Read() = %+v, %v", got, err)
+	}
+
+	// A file without a location is not a crime file
+	err = reader.Read(context.Background(), strings.NewReader("Datum,Delikt\n01.06.2024,Diebstahl\n"),
+		func(models.CrimeIncident) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "no location column") {
+		t.Errorf("Read() without location error = %v", err)
+	}
+
+	if _, err := CrimeColumnsFor("berlin"); err == nil {
+		t.Error("CrimeColumnsFor(berlin) should fail")
+	}
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"pricemap-go/models"
+)
+
+func TestAssessCrime(t *testing.T) {
+	service := NewCrimeService()
+	property := &models.Property{Latitude: 40.7484, Longitude: -73.9857}
+
+	// Without incidents the city keeps the default score
+	got := service.AssessCrime(property, NewCrimeIndex(nil))
+	if got.Score != defaultCrimeScore || got.Incidents != 0 || got.Note == "" {
+		t.Errorf("AssessCrime() without data = %+v", got)
+	}
+
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	newest := now.Add(-time.Hour)
+	at := func(lat, lng float64, category string, occurred time.Time) models.CrimeIncident {
+		return models.CrimeIncident{Category: category, Latitude: lat, Longitude: lng, OccurredAt: occurred}
+	}
+	// One theft in each of three spots far apart, and two more at the property
+	incidents := []models.CrimeIncident{
+		at(40.7484, -73.9857, models.CrimeCategoryTheft, newest),
+		at(40.7485, -73.9856, models.CrimeCategoryTheft, newest),
+		at(40.7486, -73.9858, models.CrimeCategoryTheft, newest),
+		at(40.8000, -73.9500, models.CrimeCategoryTheft, newest),
+		at(40.7000, -74.0000, models.CrimeCategoryTheft, newest),
+		// Older than a year
+		at(40.7484, -73.9857, models.CrimeCategoryViolent, now.AddDate(-2, 0, 0)),
+		// A mistyped date in the future
+		at(40.7484, -73.9857, models.CrimeCategoryViolent, now.AddDate(180, 0, 0)),
+	}
+	index := newCrimeIndex(incidents, now)
+	if index.Len() != 5 {
+		t.Fatalf("Len() = %d, want 5", index.Len())
+	}
+
+	// Three thefts where the median part of the city has one: a third as safe
+	got = service.AssessCrime(property, index)
+	if got.Incidents != 3 || got.Categories[models.CrimeCategoryTheft] != 3 || got.Categories[models.CrimeCategoryViolent] != 0 {
+		t.Errorf("AssessCrime() = %+v", got)
+	}
+	if got.Score < 24 || got.Score > 26 {
+		t.Errorf("Score = %v, want 25", got.Score)
+	}
+	if got.From != "2023-06-02" || got.To != "2024-06-01" || got.Radius != 500 {
+		t.Errorf("window = %s to %s, radius %d", got.From, got.To, got.Radius)
+	}
+
+	// As much crime as the median part of the city scores 50
+	got = service.AssessCrime(&models.Property{Latitude: 40.8000, Longitude: -73.9500}, index)
+	if got.Incidents != 1 || got.Score < 49 || got.Score > 51 {
+		t.Errorf("AssessCrime() at the median = %+v", got)
+	}
+
+	// No crime around a property is the safest
+	got = service.AssessCrime(&models.Property{Latitude: 40.7700, Longitude: -73.9700}, index)
+	if got.Incidents != 0 || got.Score != 100 {
+		t.Errorf("AssessCrime() without incidents around = %+v", got)
+	}
+
+	// The window ends now, not at the newest incident: data that stopped two
+	// years ago counts nothing
+	stale := newCrimeIndex(incidents, now.AddDate(2, 0, 0))
+	if stale.Len() != 0 {
+		t.Errorf("Len() of stale data = %d, want 0", stale.Len())
+	}
+	if got := service.AssessCrime(property, stale); got.Score != defaultCrimeScore || got.Note == "" {
+		t.Errorf("AssessCrime() with stale data = %+v", got)
+	}
+}
+
+func TestCrimeSeverity(t *testing.T) {
+	for _, category := range models.CrimeCategories {
+		if _, ok := crimeSeverities[category]; !ok {
+			t.Errorf("category %s has no severity", category)
+		}
+	}
+	if crimeSeverity("arson") != crimeSeverities[models.CrimeCategoryOther] {
+		t.Error("unknown categories should weigh as other")
+	}
+	if crimeSeverity(models.CrimeCategoryViolent) <= crimeSeverity(models.CrimeCategoryTheft) {
+		t.Error("violent crime should weigh more than theft")
+	}
+}
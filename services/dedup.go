@@ -0,0 +1,259 @@
+package services
+
+import (
+	"log"
+	"math"
+
+	"pricemap-go/database"
+	"pricemap-go/models"
+	"pricemap-go/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// dedupMaxDistanceKm is how far apart two listings of the same flat may be geocoded
+	dedupMaxDistanceKm = 0.1
+	// dedupMaxAreaDiff is the largest relative area difference still treated as the same flat
+	dedupMaxAreaDiff = 0.1
+	// dedupMaxPriceDiff is the largest relative price difference of two listings of one deal
+	dedupMaxPriceDiff = 0.3
+	// dedupMinConfidence is the match confidence needed to join a cluster
+	dedupMinConfidence = 0.75
+	// dedupBuildingConfidence caps pairs without floor or flat number evidence,
+	// which may be two flats of one building, below dedupMinConfidence
+	dedupBuildingConfidence = 0.7
+	// dedupLockName names the advisory lock that serializes cluster assignment
+	// across scraper workers
+	dedupLockName = "pricemap:canonical_properties"
+)
+
+// DedupService links listings from different sources that describe the same
+// physical property to a shared CanonicalProperty
+type DedupService struct{}
+
+func NewDedupService() *DedupService {
+	return &DedupService{}
+}
+
+// MatchConfidence scores how likely two listings describe the same property (0-1).
+// Location and deal type are required; area, rooms, floor, price and address
+// contribute only when both listings know them, and a clear mismatch on any of
+// them rules the pair out. Without the same floor or flat number the pair stays
+// below the merge threshold.
+func (ds *DedupService) MatchConfidence(a, b *models.Property) float64 {
+	if a.Latitude == 0 && a.Longitude == 0 || b.Latitude == 0 && b.Longitude == 0 {
+		return 0
+	}
+
+	// A sale and a rental of the same flat are different offers
+	if a.DealType != b.DealType {
+		return 0
+	}
+
+	if a.Price > 0 && b.Price > 0 && a.Currency == b.Currency {
+		if 1-math.Min(a.Price, b.Price)/math.Max(a.Price, b.Price) > dedupMaxPriceDiff {
+			return 0
+		}
+	}
+
+	distance := utils.HaversineDistance(a.Latitude, a.Longitude, b.Latitude, b.Longitude)
+	if distance > dedupMaxDistanceKm {
+		return 0
+	}
+
+	score := 0.35 * (1 - distance/dedupMaxDistanceKm)
+	weight := 0.35
+
+	if a.Area > 0 && b.Area > 0 {
+		diff := math.Abs(a.Area-b.Area) / math.Max(a.Area, b.Area)
+		if diff > dedupMaxAreaDiff {
+			return 0
+		}
+		score += 0.25 * (1 - diff/dedupMaxAreaDiff)
+		weight += 0.25
+	}
+
+	if a.Rooms > 0 && b.Rooms > 0 {
+		if a.Rooms != b.Rooms {
+			return 0
+		}
+		score += 0.15
+		weight += 0.15
+	}
+
+	sameFloor := false
+	if a.Floor > 0 && b.Floor > 0 {
+		if a.Floor != b.Floor {
+			return 0
+		}
+		score += 0.1
+		weight += 0.1
+		sameFloor = true
+	}
+
+	sameUnit := false
+	if unitA, unitB := utils.AddressUnit(a.Address), utils.AddressUnit(b.Address); unitA != "" && unitB != "" {
+		if unitA != unitB {
+			return 0
+		}
+		sameUnit = true
+	}
+
+	if a.Address != "" && b.Address != "" {
+		score += 0.15 * utils.AddressSimilarity(a.Address, b.Address)
+		weight += 0.15
+	}
+
+	// Location alone is not enough evidence to merge two listings
+	if weight < 0.6 {
+		return 0
+	}
+
+	confidence := math.Round(score/weight*100) / 100
+
+	// Flats of one building share location, size and layout; only the floor
+	// or flat number tells them apart
+	if !sameFloor && !sameUnit {
+		confidence = math.Min(confidence, dedupBuildingConfidence)
+	}
+
+	return confidence
+}
+
+// Deduplicate assigns each saved property to a canonical property
+func (ds *DedupService) Deduplicate(properties []models.Property) (matched int) {
+	for i := range properties {
+		if properties[i].ID == 0 {
+			continue
+		}
+		joined, err := ds.AssignCanonical(&properties[i])
+		if err != nil {
+			log.Printf("Error deduplicating property %d: %v", properties[i].ID, err)
+			continue
+		}
+		if joined {
+			matched++
+		}
+	}
+	return matched
+}
+
+// AssignCanonical links a property to the best matching cluster from another
+// source, or starts a new cluster. It reports whether an existing cluster was joined.
+// Lookup and creation run in one transaction under a lock, so workers saving
+// two listings of the same flat at once do not each start a cluster.
+func (ds *DedupService) AssignCanonical(property *models.Property) (bool, error) {
+	if property.CanonicalID != nil {
+		return false, nil
+	}
+
+	joined := false
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", dedupLockName).Error; err != nil {
+			return err
+		}
+
+		// Another worker may have linked the property as its own best match
+		var current models.Property
+		if err := tx.Select("canonical_id", "match_confidence").First(&current, property.ID).Error; err != nil {
+			return err
+		}
+		if current.CanonicalID != nil {
+			property.CanonicalID = current.CanonicalID
+			property.MatchConfidence = current.MatchConfidence
+			return nil
+		}
+
+		best, confidence, err := ds.findBestMatch(tx, property)
+		if err != nil {
+			return err
+		}
+
+		if best == nil {
+			canonical := newCanonicalProperty(property)
+			if err := tx.Create(canonical).Error; err != nil {
+				return err
+			}
+			return ds.link(tx, property, canonical.ID, 1)
+		}
+
+		if best.CanonicalID == nil {
+			canonical := newCanonicalProperty(best)
+			if err := tx.Create(canonical).Error; err != nil {
+				return err
+			}
+			if err := ds.link(tx, best, canonical.ID, 1); err != nil {
+				return err
+			}
+		}
+
+		if err := ds.link(tx, property, *best.CanonicalID, confidence); err != nil {
+			return err
+		}
+
+		joined = true
+		return tx.Model(&models.CanonicalProperty{}).
+			Where("id = ?", *best.CanonicalID).
+			Update("member_count", tx.Model(&models.Property{}).
+				Select("COUNT(*)").
+				Where("canonical_id = ?", *best.CanonicalID)).Error
+	})
+	if err != nil {
+		property.CanonicalID = nil
+		return false, err
+	}
+	return joined, nil
+}
+
+// findBestMatch looks for the closest listing from a different source nearby
+func (ds *DedupService) findBestMatch(tx *gorm.DB, property *models.Property) (*models.Property, float64, error) {
+	if property.Latitude == 0 && property.Longitude == 0 {
+		return nil, 0, nil
+	}
+
+	dLat, dLng := utils.BoundingBox(property.Latitude, dedupMaxDistanceKm)
+
+	var candidates []models.Property
+	if err := tx.
+		Where("source <> ? AND id <> ?", property.Source, property.ID).
+		Where("latitude BETWEEN ? AND ?", property.Latitude-dLat, property.Latitude+dLat).
+		Where("longitude BETWEEN ? AND ?", property.Longitude-dLng, property.Longitude+dLng).
+		Find(&candidates).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var best *models.Property
+	bestConfidence := 0.0
+	for i := range candidates {
+		confidence := ds.MatchConfidence(property, &candidates[i])
+		if confidence >= dedupMinConfidence && confidence > bestConfidence {
+			best = &candidates[i]
+			bestConfidence = confidence
+		}
+	}
+
+	return best, bestConfidence, nil
+}
+
+func (ds *DedupService) link(tx *gorm.DB, property *models.Property, canonicalID uint, confidence float64) error {
+	property.CanonicalID = &canonicalID
+	property.MatchConfidence = confidence
+	return tx.Model(&models.Property{}).
+		Where("id = ?", property.ID).
+		Updates(map[string]interface{}{"canonical_id": canonicalID, "match_confidence": confidence}).Error
+}
+
+func newCanonicalProperty(property *models.Property) *models.CanonicalProperty {
+	return &models.CanonicalProperty{
+		Country:           property.Country,
+		City:              property.City,
+		NormalizedAddress: utils.NormalizeAddress(property.Address),
+		Latitude:          property.Latitude,
+		Longitude:         property.Longitude,
+		Area:              property.Area,
+		Rooms:             property.Rooms,
+		Floor:             property.Floor,
+		MemberCount:       1,
+	}
+}
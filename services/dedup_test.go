@@ -0,0 +1,166 @@
+package services
+
+import (
+	"testing"
+
+	"pricemap-go/models"
+)
+
+func TestDedupService_MatchConfidence(t *testing.T) {
+	ds := NewDedupService()
+
+	cian := models.Property{
+		Source:    "cian",
+		DealType:  "sale",
+		Price:     25000000,
+		Currency:  "RUB",
+		Latitude:  55.7558,
+		Longitude: 37.6173,
+		Area:      54,
+		Rooms:     2,
+		Floor:     5,
+		Address:   "Москва, ул. Тверская, д. 7",
+	}
+
+	tests := []struct {
+		name      string
+		other     models.Property
+		wantMatch bool
+	}{
+		{
+			name: "same flat on another site",
+			other: models.Property{
+				Source:    "avito",
+				DealType:  "sale",
+				Price:     24500000,
+				Currency:  "RUB",
+				Latitude:  55.7559,
+				Longitude: 37.6174,
+				Area:      54.5,
+				Rooms:     2,
+				Floor:     5,
+				Address:   "Тверская улица, 7",
+			},
+			wantMatch: true,
+		},
+		{
+			name: "flat number on one side only",
+			other: models.Property{
+				DealType:  "sale",
+				Latitude:  55.7558,
+				Longitude: 37.6173,
+				Area:      54,
+				Rooms:     2,
+				Address:   "Тверская улица, 7, кв. 12",
+			},
+			wantMatch: false,
+		},
+		{
+			name: "flat of the same layout in the same building",
+			other: models.Property{
+				DealType:  "sale",
+				Latitude:  55.7558,
+				Longitude: 37.6173,
+				Area:      54,
+				Rooms:     2,
+				Address:   "Москва, ул. Тверская, д. 7",
+			},
+			wantMatch: false,
+		},
+		{
+			name: "rental of the same flat",
+			other: models.Property{
+				DealType:  "rent",
+				Price:     120000,
+				Currency:  "RUB",
+				Latitude:  55.7558,
+				Longitude: 37.6173,
+				Area:      54,
+				Rooms:     2,
+				Floor:     5,
+			},
+			wantMatch: false,
+		},
+		{
+			name: "far apart in price",
+			other: models.Property{
+				DealType:  "sale",
+				Price:     40000000,
+				Currency:  "RUB",
+				Latitude:  55.7558,
+				Longitude: 37.6173,
+				Area:      54,
+				Rooms:     2,
+				Floor:     5,
+			},
+			wantMatch: false,
+		},
+		{
+			name: "different floor",
+			other: models.Property{
+				Latitude:  55.7558,
+				Longitude: 37.6173,
+				Area:      54,
+				Rooms:     2,
+				Floor:     9,
+			},
+			wantMatch: false,
+		},
+		{
+			name: "different size",
+			other: models.Property{
+				Latitude:  55.7558,
+				Longitude: 37.6173,
+				Area:      80,
+				Rooms:     2,
+			},
+			wantMatch: false,
+		},
+		{
+			name: "too far away",
+			other: models.Property{
+				Latitude:  55.7658,
+				Longitude: 37.6173,
+				Area:      54,
+				Rooms:     2,
+			},
+			wantMatch: false,
+		},
+		{
+			name: "location only",
+			other: models.Property{
+				Latitude:  55.7558,
+				Longitude: 37.6173,
+			},
+			wantMatch: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			confidence := ds.MatchConfidence(&cian, &tt.other)
+			if confidence < 0 || confidence > 1 {
+				t.Fatalf("MatchConfidence() = %v, want value in [0, 1]", confidence)
+			}
+			if got := confidence >= dedupMinConfidence; got != tt.wantMatch {
+				t.Errorf("MatchConfidence() = %v, want match = %v", confidence, tt.wantMatch)
+			}
+		})
+	}
+}
+
+func TestDedupService_MatchConfidence_FlatNumber(t *testing.T) {
+	ds := NewDedupService()
+
+	flat := func(address string) *models.Property {
+		return &models.Property{DealType: "sale", Latitude: 55.7558, Longitude: 37.6173, Area: 54, Rooms: 2, Address: address}
+	}
+
+	// Without a floor the flat number tells listings of one building apart
+	if got := ds.MatchConfidence(flat("Тверская ул., 7, кв. 12"), flat("ул. Тверская, д. 7, кв. 12")); got < dedupMinConfidence {
+		t.Errorf("MatchConfidence() for the same flat number = %v, want a match", got)
+	}
+	if got := ds.MatchConfidence(flat("Тверская ул., 7, кв. 12"), flat("ул. Тверская, д. 7, кв. 14")); got != 0 {
+		t.Errorf("MatchConfidence() for different flat numbers = %v, want 0", got)
+	}
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"pricemap-go/config"
+	"pricemap-go/database"
+	"pricemap-go/models"
+	"pricemap-go/parsers"
+)
+
+// coverageSlice identifies the part of a source that a run actually scraped
+type coverageSlice struct {
+	City     string
+	DealType string
+}
+
+// coveredSlices groups the external IDs seen in a run by city and deal type,
+// keeping only the slices whose searches all reached the last results page.
+// A slice cut short at the page limit or by a failed page is never covered.
+func coveredSlices(properties []models.Property, coverage *parsers.Coverage) map[coverageSlice]map[string]bool {
+	slices := make(map[coverageSlice]map[string]bool)
+	for _, p := range properties {
+		if p.ExternalID == "" || !coverage.Complete(p.City, p.DealType) {
+			continue
+		}
+		key := coverageSlice{City: p.City, DealType: p.DealType}
+		if slices[key] == nil {
+			slices[key] = make(map[string]bool)
+		}
+		slices[key][p.ExternalID] = true
+	}
+	return slices
+}
+
+// enoughCoverage reports whether a run saw a large enough share of the active
+// listings in a slice for the missing ones to be trusted as delisted. It backs
+// up the coverage check for pages that render listings the parser misses.
+func enoughCoverage(seen, active, minSeenPercent int) bool {
+	if active == 0 {
+		return true
+	}
+	return seen*100 >= active*minSeenPercent
+}
+
+// markDelisted flags listings of a source that were not returned by a run that
+// enumerated their city and deal type completely and saw enough of its listings
+func (ss *ScraperService) markDelisted(source string, properties []models.Property, coverage *parsers.Coverage) (int, error) {
+	now := time.Now()
+	delisted := 0
+
+	for slice, seen := range coveredSlices(properties, coverage) {
+		var active []models.Property
+		if err := database.DB.Select("id", "external_id", "price", "currency").
+			Where("source = ? AND city = ? AND deal_type = ? AND is_active = ?", source, slice.City, slice.DealType, true).
+			Find(&active).Error; err != nil {
+			return delisted, err
+		}
+
+		if !enoughCoverage(len(seen), len(active), config.AppConfig.DelistMinSeenPercent) {
+			log.Printf("%s: skipping delisting for %s/%s, run saw %d of %d active listings",
+				source, slice.City, slice.DealType, len(seen), len(active))
+			continue
+		}
+
+		var ids []uint
+		var observations []models.PriceObservation
+		for _, p := range active {
+			if seen[p.ExternalID] {
+				continue
+			}
+			ids = append(ids, p.ID)
+			observations = append(observations, models.PriceObservation{
+				PropertyID: p.ID,
+				Price:      p.Price,
+				Currency:   p.Currency,
+				IsActive:   false,
+				ObservedAt: now,
+			})
+		}
+
+		if len(ids) == 0 {
+			continue
+		}
+
+		if err := database.DB.Model(&models.Property{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{"is_active": false, "delisted_at": now}).Error; err != nil {
+			return delisted, err
+		}
+		if err := savePriceObservations(observations); err != nil {
+			log.Printf("Error saving price history: %v", err)
+		}
+
+		delisted += len(ids)
+	}
+
+	return delisted, nil
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"pricemap-go/models"
+	"pricemap-go/parsers"
+)
+
+func TestCoveredSlices(t *testing.T) {
+	properties := []models.Property{
+		{ExternalID: "1", City: "London", DealType: "sale"},
+		{ExternalID: "2", City: "London", DealType: "sale"},
+		{ExternalID: "3", City: "London", DealType: "rent"},
+		{ExternalID: "4", City: "Leeds", DealType: "sale"},
+		{ExternalID: "", City: "Leeds", DealType: "rent"},
+	}
+	coverage := parsers.NewCoverage()
+	for _, p := range properties {
+		coverage.Record(p.City, p.DealType, nil)
+	}
+
+	slices := coveredSlices(properties, coverage)
+
+	if len(slices) != 3 {
+		t.Fatalf("coveredSlices() returned %d slices, want 3", len(slices))
+	}
+
+	londonSale := slices[coverageSlice{City: "London", DealType: "sale"}]
+	if len(londonSale) != 2 || !londonSale["1"] || !londonSale["2"] {
+		t.Errorf("London/sale slice = %v, want IDs 1 and 2", londonSale)
+	}
+
+	if _, ok := slices[coverageSlice{City: "Leeds", DealType: "rent"}]; ok {
+		t.Errorf("slice without external IDs should not be covered")
+	}
+}
+
+func TestCoveredSlices_Incomplete(t *testing.T) {
+	properties := []models.Property{
+		{ExternalID: "1", City: "London", DealType: "sale"},
+		{ExternalID: "2", City: "London", DealType: "rent"},
+		{ExternalID: "3", City: "Leeds", DealType: "sale"},
+		{ExternalID: "4", City: "Leeds", DealType: "rent"},
+	}
+	coverage := parsers.NewCoverage()
+	coverage.Record("London", "sale", nil)
+	// The search stopped at the page limit with pages left
+	coverage.Record("London", "rent", &parsers.IncompleteError{Page: 3})
+	// A later page failed
+	coverage.Record("Leeds", "sale", &parsers.IncompleteError{Page: 1, Err: errors.New("status 500")})
+	// Leeds/rent was never recorded as searched
+
+	slices := coveredSlices(properties, coverage)
+
+	// Nothing is delisted from slices that were not listed to the last page
+	if len(slices) != 1 || slices[coverageSlice{City: "London", DealType: "sale"}] == nil {
+		t.Errorf("coveredSlices() = %v, want only London/sale", slices)
+	}
+
+	// Without a collector no slice is known to be complete
+	if slices := coveredSlices(properties, nil); len(slices) != 0 {
+		t.Errorf("coveredSlices() without coverage = %v, want none", slices)
+	}
+}
+
+func TestEnoughCoverage(t *testing.T) {
+	tests := []struct {
+		name   string
+		seen   int
+		active int
+		want   bool
+	}{
+		{"nothing stored yet", 20, 0, true},
+		{"full run", 95, 100, true},
+		{"exactly at threshold", 50, 100, true},
+		{"first page only", 20, 500, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := enoughCoverage(tt.seen, tt.active, 50); got != tt.want {
+				t.Errorf("enoughCoverage(%d, %d) = %v, want %v", tt.seen, tt.active, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"pricemap-go/models"
+	"pricemap-go/utils"
+)
+
+// DryRunWriter validates scraped properties and streams them as
+// newline-delimited JSON instead of saving them
+type DryRunWriter struct {
+	mu       sync.Mutex
+	encoder  *json.Encoder
+	sources  map[string]*DryRunSourceSummary
+	failures map[string]int
+}
+
+// DryRunSourceSummary counts the properties one source produced
+type DryRunSourceSummary struct {
+	Source  string `json:"source"`
+	Total   int    `json:"total"`
+	Invalid int    `json:"invalid"`
+}
+
+// DryRunSummary sums up a dry run
+type DryRunSummary struct {
+	Total    int                   `json:"total"`
+	Invalid  int                   `json:"invalid"`
+	Sources  []DryRunSourceSummary `json:"sources"`
+	Failures map[string]int        `json:"failures"` // failed validations per field
+}
+
+// dryRunRecord is one NDJSON line: the property as scraped plus the
+// validation rules it breaks
+type dryRunRecord struct {
+	models.Property
+	ValidationErrors []string `json:"validation_errors,omitempty"`
+}
+
+// NewDryRunWriter creates a writer that encodes properties to w
+func NewDryRunWriter(w io.Writer) *DryRunWriter {
+	return &DryRunWriter{
+		encoder:  json.NewEncoder(w),
+		sources:  make(map[string]*DryRunSourceSummary),
+		failures: make(map[string]int),
+	}
+}
+
+// Write validates and encodes properties. It is safe for concurrent use, so
+// parallel workers never interleave lines.
+func (d *DryRunWriter) Write(properties []models.Property) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i := range properties {
+		record := dryRunRecord{Property: properties[i]}
+		// Show the price per m² that saving would store
+		record.PricePerSqm = models.PricePerSqm(record.Price, record.Area)
+
+		summary, ok := d.sources[record.Source]
+		if !ok {
+			summary = &DryRunSourceSummary{Source: record.Source}
+			d.sources[record.Source] = summary
+		}
+		summary.Total++
+
+		if errs := utils.PropertyValidationErrors(&record.Property); len(errs) > 0 {
+			summary.Invalid++
+			for _, err := range errs {
+				d.failures[err.Field]++
+				record.ValidationErrors = append(record.ValidationErrors, err.Error())
+			}
+		}
+
+		if err := d.encoder.Encode(record); err != nil {
+			return fmt.Errorf("failed to write property %s/%s: %w", record.Source, record.ExternalID, err)
+		}
+	}
+
+	return nil
+}
+
+// Summary returns the counts so far, sources ordered by name
+func (d *DryRunWriter) Summary() DryRunSummary {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	summary := DryRunSummary{
+		Sources:  make([]DryRunSourceSummary, 0, len(d.sources)),
+		Failures: make(map[string]int, len(d.failures)),
+	}
+	for _, source := range d.sources {
+		summary.Total += source.Total
+		summary.Invalid += source.Invalid
+		summary.Sources = append(summary.Sources, *source)
+	}
+	sort.Slice(summary.Sources, func(i, j int) bool {
+		return summary.Sources[i].Source < summary.Sources[j].Source
+	})
+	for field, count := range d.failures {
+		summary.Failures[field] = count
+	}
+
+	return summary
+}
+
+// String formats the summary for logs, most frequent failures first
+func (s DryRunSummary) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d properties, %d invalid", s.Total, s.Invalid)
+
+	for _, source := range s.Sources {
+		fmt.Fprintf(&b, "\n  %s: %d properties, %d invalid", source.Source, source.Total, source.Invalid)
+	}
+
+	fields := make([]string, 0, len(s.Failures))
+	for field := range s.Failures {
+		fields = append(fields, field)
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		if s.Failures[fields[i]] != s.Failures[fields[j]] {
+			return s.Failures[fields[i]] > s.Failures[fields[j]]
+		}
+		return fields[i] < fields[j]
+	})
+	for _, field := range fields {
+		fmt.Fprintf(&b, "\n  %s failed: %d", field, s.Failures[field])
+	}
+
+	return b.String()
+}
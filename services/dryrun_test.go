@@ -0,0 +1,67 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"pricemap-go/models"
+)
+
+func TestDryRunWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewDryRunWriter(&buf)
+
+	err := writer.Write([]models.Property{
+		{Source: "cian", ExternalID: "1", Price: 100, Latitude: 55.7, Longitude: 37.6},
+		{Source: "cian", ExternalID: "2"},
+	})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := writer.Write([]models.Property{{Source: "zillow", Price: 200, Address: "1 Main St"}}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	var records []map[string]interface{}
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var record map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("line %q is not JSON: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 3 {
+		t.Fatalf("wrote %d lines, want 3", len(records))
+	}
+	if records[0]["source"] != "cian" || records[0]["external_id"] != "1" {
+		t.Errorf("first record = %v, want the cian property", records[0])
+	}
+	if _, ok := records[0]["validation_errors"]; ok {
+		t.Errorf("valid property should have no validation_errors")
+	}
+	if errs, _ := records[1]["validation_errors"].([]interface{}); len(errs) != 2 {
+		t.Errorf("validation_errors = %v, want price and location", records[1]["validation_errors"])
+	}
+
+	summary := writer.Summary()
+	if summary.Total != 3 || summary.Invalid != 2 {
+		t.Errorf("Summary() total = %d, invalid = %d, want 3 and 2", summary.Total, summary.Invalid)
+	}
+	if len(summary.Sources) != 2 || summary.Sources[0].Source != "cian" || summary.Sources[0].Invalid != 1 {
+		t.Errorf("Summary() sources = %+v", summary.Sources)
+	}
+	want := map[string]int{"price": 1, "location": 1, "external_id": 1}
+	for field, count := range want {
+		if summary.Failures[field] != count {
+			t.Errorf("Summary() failures[%s] = %d, want %d", field, summary.Failures[field], count)
+		}
+	}
+
+	if text := summary.String(); !strings.Contains(text, "3 properties, 2 invalid") || !strings.Contains(text, "price failed: 1") {
+		t.Errorf("String() = %q", text)
+	}
+}
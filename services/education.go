@@ -1,115 +1,93 @@
 package services
 
 import (
-	"encoding/json"
-	"net/http"
+	"math"
+	"slices"
+
 	"pricemap-go/models"
+	"pricemap-go/utils"
 )
 
-type EducationService struct {
-	client *http.Client
-}
+// defaultEducationScore is the score of cities without imported schools
+const defaultEducationScore = 70.0
 
-func NewEducationService() *EducationService {
-	return &EducationService{
-		client: &http.Client{},
-	}
+// educationLevel is how the schools of a level count towards the education
+// score, by the OSM kinds of the schools category
+type educationLevel struct {
+	name   string
+	kinds  []string
+	weight float64 // Share of the education score
+	radius float64 // Metres of walk within which its schools are counted
+	enough int     // Schools within radius that give full density points
 }
 
-// GetEducationData fetches education/school data for a location
-func (es *EducationService) GetEducationData(lat, lng float64, country, city string) (*EducationData, error) {
-	switch country {
-	case "United States":
-		return es.getUSEducationData(lat, lng, city)
-	case "United Kingdom":
-		return es.getUKEducationData(lat, lng, city)
-	default:
-		return es.getGenericEducationData(lat, lng, city)
-	}
+var educationLevels = []educationLevel{
+	{"school", []string{"school"}, 0.5, 1500, 3},
+	{"kindergarten", []string{"kindergarten"}, 0.3, 1000, 2},
+	{"higher", []string{"college", "university"}, 0.2, 3000, 1},
 }
 
-// EducationData represents education statistics
-type EducationData struct {
-	Score         float64 `json:"score"`          // 0-100
-	AverageRating float64 `json:"average_rating"` // Average school rating
-	SchoolsCount  int     `json:"schools_count"`  // Number of schools nearby
-	TopSchoolRating float64 `json:"top_school_rating"`
-	Source        string  `json:"source"`
-}
-
-// getUSEducationData fetches from GreatSchools or NCES
-func (es *EducationService) getUSEducationData(lat, lng float64, city string) (*EducationData, error) {
-	// GreatSchools API or NCES data
-	// For now, placeholder
-	
-	return &EducationData{
-		Score:         65.0,
-		AverageRating: 7.0,
-		SchoolsCount:  5,
-		Source:        "placeholder",
-	}, nil
-}
+type EducationService struct{}
 
-// getUKEducationData fetches from Ofsted
-func (es *EducationService) getUKEducationData(lat, lng float64, city string) (*EducationData, error) {
-	// UK Ofsted data or Department for Education
-	// For now, placeholder
-	
-	return &EducationData{
-		Score:         70.0,
-		AverageRating: 3.5, // Out of 4 for UK
-		SchoolsCount:  5,
-		Source:        "placeholder",
-	}, nil
+func NewEducationService() *EducationService {
+	return &EducationService{}
 }
 
-// getGenericEducationData gets generic education data
-func (es *EducationService) getGenericEducationData(lat, lng float64, city string) (*EducationData, error) {
-	// Can use OpenStreetMap to find schools nearby
-	// Or other international education databases
-	
-	return &EducationData{
-		Score:         65.0,
-		AverageRating: 7.0,
-		SchoolsCount:  3,
-		Source:        "generic",
-	}, nil
+// EducationAssessment is an education score with the schools it is based
+// on, stored as the EducationData of the property factors
+type EducationAssessment struct {
+	Score  float64                       `json:"score"`
+	Levels map[string]CategoryAssessment `json:"levels"`
+	Note   string                        `json:"note,omitempty"`
 }
 
-// CalculateEducationScore calculates education score for a property
-func (es *EducationService) CalculateEducationScore(property *models.Property) (float64, string, error) {
-	eduData, err := es.GetEducationData(
-		property.Latitude,
-		property.Longitude,
-		property.Country,
-		property.City,
-	)
-	
-	if err != nil {
-		return 60.0, "{}", err
+// AssessEducation scores education (0-100) from the schools among the
+// points of interest of the property's city. Schools, kindergartens and
+// colleges or universities each score half on the walk to the nearest one
+// (full within 400 m, none at the level's radius) and half on how many lie
+// within its radius, and are weighted by their share.
+func (es *EducationService) AssessEducation(property *models.Property, pois POIIndex) EducationAssessment {
+	schools := pois[models.POICategorySchools]
+	if schools == nil || schools.Len() == 0 {
+		return EducationAssessment{
+			Score:  defaultEducationScore,
+			Levels: map[string]CategoryAssessment{},
+			Note:   "no schools known for the city",
+		}
 	}
-	
-	// Calculate score based on data
-	score := eduData.AverageRating * 10 // Convert rating to 0-100 scale
-	if score > 100 {
-		score = 100
-	}
-	
-	// Adjust based on number of schools
-	if eduData.SchoolsCount > 5 {
-		score += 10
-	} else if eduData.SchoolsCount > 2 {
-		score += 5
-	}
-	
-	if score > 100 {
-		score = 100
+
+	assessment := EducationAssessment{Levels: make(map[string]CategoryAssessment, len(educationLevels))}
+	score := 0.0
+	for _, level := range educationLevels {
+		category := CategoryAssessment{Radius: int(level.radius)}
+
+		var nearest *utils.Neighbor[models.PointOfInterest]
+		for _, neighbor := range schools.WithinRadius(property.Latitude, property.Longitude, level.radius/walkingDetour/1000) {
+			if !slices.Contains(level.kinds, neighbor.Value.Kind) {
+				continue
+			}
+			category.Count++
+			if nearest == nil {
+				nearest = &neighbor
+			}
+		}
+
+		if nearest != nil {
+			distance := nearest.Distance * 1000 * walkingDetour
+			category.Nearest = &NearbyPOI{
+				Name:            nearest.Value.Name,
+				Kind:            nearest.Value.Kind,
+				WalkingDistance: int(math.Round(distance)),
+				WalkingMinutes:  int(math.Ceil(distance / walkingSpeed)),
+			}
+			density := math.Min(float64(category.Count)/float64(level.enough), 1)
+			category.Score = math.Round((walkCredit(distance, level.radius)+density)*50*100) / 100
+		}
+
+		score += level.weight * category.Score
+		assessment.Levels[level.name] = category
 	}
-	
-	eduData.Score = score
-	
-	dataJSON, _ := json.Marshal(eduData)
-	
-	return score, string(dataJSON), nil
-}
 
+	assessment.Score = math.Round(score*100) / 100
+	return assessment
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"testing"
+
+	"pricemap-go/models"
+)
+
+func TestAssessEducation(t *testing.T) {
+	es := NewEducationService()
+	property := &models.Property{Latitude: 55.7558, Longitude: 37.6173}
+
+	// Without schools the city keeps the default score
+	got := es.AssessEducation(property, NewPOIIndex([]models.PointOfInterest{
+		{Category: models.POICategoryGroceries, Kind: "supermarket", Latitude: 55.7560, Longitude: 37.6173},
+	}))
+	if got.Score != defaultEducationScore || got.Note == "" {
+		t.Errorf("AssessEducation() without schools = %+v", got)
+	}
+
+	pois := []models.PointOfInterest{
+		{Category: models.POICategorySchools, Kind: "school", Name: "Near", Latitude: 55.7580, Longitude: 37.6173}, // ~320 m walk
+		{Category: models.POICategorySchools, Kind: "school", Latitude: 55.7620, Longitude: 37.6173},               // ~900 m
+		{Category: models.POICategorySchools, Kind: "school", Latitude: 55.7560, Longitude: 37.6400},               // out of reach
+		{Category: models.POICategorySchools, Kind: "kindergarten", Latitude: 55.7590, Longitude: 37.6173},         // ~460 m
+		{Category: models.POICategoryGroceries, Kind: "supermarket", Latitude: 55.7559, Longitude: 37.6173},        // not a school
+	}
+	got = es.AssessEducation(property, NewPOIIndex(pois))
+
+	school := got.Levels["school"]
+	if school.Count != 2 || school.Nearest == nil || school.Nearest.Name != "Near" {
+		t.Fatalf("school = %+v", school)
+	}
+	// Full points for the walk, two of three schools wanted
+	if school.Score < 83 || school.Score > 84 {
+		t.Errorf("school score = %v, want 83.33", school.Score)
+	}
+
+	kindergarten := got.Levels["kindergarten"]
+	if kindergarten.Count != 1 || kindergarten.Nearest == nil || kindergarten.Score < 65 || kindergarten.Score > 75 {
+		t.Errorf("kindergarten = %+v", kindergarten)
+	}
+	if higher := got.Levels["higher"]; higher.Count != 0 || higher.Nearest != nil || higher.Score != 0 {
+		t.Errorf("higher = %+v", higher)
+	}
+
+	if want := 0.5*school.Score + 0.3*kindergarten.Score; got.Score < want-0.01 || got.Score > want+0.01 {
+		t.Errorf("Score = %v, want %v", got.Score, want)
+	}
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"pricemap-go/config"
+	"pricemap-go/database"
+	"pricemap-go/models"
+	"pricemap-go/parsers"
+
+	"gorm.io/gorm"
+)
+
+// detailMaxAttempts is how often a detail page may fail before the listing is
+// no longer queued
+const detailMaxAttempts = 3
+
+// keepStoredDetails carries fields filled from the detail page over to a freshly
+// scraped card, which never has them. A listing whose price or state changed is
+// queued for another detail page visit.
+func keepStoredDetails(existing, incoming *models.Property, changed bool) {
+	if incoming.Floor == 0 {
+		incoming.Floor = existing.Floor
+	}
+	if incoming.TotalFloors == 0 {
+		incoming.TotalFloors = existing.TotalFloors
+	}
+	if incoming.YearBuilt == 0 {
+		incoming.YearBuilt = existing.YearBuilt
+	}
+	if incoming.Bathrooms == 0 {
+		incoming.Bathrooms = existing.Bathrooms
+	}
+	if incoming.Description == "" {
+		incoming.Description = existing.Description
+	}
+	if len(incoming.Images) == 0 {
+		incoming.Images = existing.Images
+	}
+	if incoming.Area == 0 {
+		incoming.Area = existing.Area
+	}
+	if incoming.Rooms == 0 {
+		incoming.Rooms = existing.Rooms
+	}
+	if incoming.Bedrooms == 0 {
+		incoming.Bedrooms = existing.Bedrooms
+	}
+	if incoming.Latitude == 0 && incoming.Longitude == 0 {
+		incoming.Latitude = existing.Latitude
+		incoming.Longitude = existing.Longitude
+	}
+
+	if !changed {
+		incoming.DetailsFetchedAt = existing.DetailsFetchedAt
+		incoming.DetailAttempts = existing.DetailAttempts
+	}
+}
+
+// enrichDetails visits the detail pages of a source's listings that are new or
+// changed since their last visit. The queue lives in the database, so a run that
+// is interrupted or hits DETAIL_MAX_PER_RUN continues where it stopped next time.
+func (ss *ScraperService) enrichDetails(ctx context.Context, parser parsers.DetailParser) (int, error) {
+	var pending []models.Property
+	if err := database.DB.
+		Select("id", "source", "external_id", "url", "price", "area", "rooms", "bedrooms", "latitude", "longitude").
+		Where("source = ? AND is_active = ? AND url <> '' AND details_fetched_at IS NULL AND detail_attempts < ?",
+			parser.Name(), true, detailMaxAttempts).
+		Order("detail_attempts, id").
+		Limit(config.AppConfig.DetailMaxPerRun).
+		Find(&pending).Error; err != nil {
+		return 0, err
+	}
+
+	enriched := 0
+	for i := range pending {
+		if ctx.Err() != nil {
+			return enriched, ctx.Err()
+		}
+
+		property := &pending[i]
+		if err := parser.FetchDetails(ctx, property); err != nil {
+			// An interrupted fetch says nothing about the listing
+			if ctx.Err() != nil {
+				return enriched, ctx.Err()
+			}
+			log.Printf("Error fetching details of %s listing %s: %v", parser.Name(), property.ExternalID, err)
+			if err := database.DB.Model(&models.Property{}).
+				Where("id = ?", property.ID).
+				UpdateColumn("detail_attempts", gorm.Expr("detail_attempts + 1")).Error; err != nil {
+				return enriched, err
+			}
+			continue
+		}
+
+		if err := saveDetails(property); err != nil {
+			return enriched, err
+		}
+		enriched++
+	}
+
+	return enriched, nil
+}
+
+// saveDetails writes only the enriched columns, leaving card data and
+// deduplication state untouched
+func saveDetails(property *models.Property) error {
+	now := time.Now()
+	property.DetailsFetchedAt = &now
+	property.DetailAttempts = 0
+
+	return database.DB.Model(&models.Property{}).
+		Where("id = ?", property.ID).
+		Updates(map[string]interface{}{
+			"floor":              property.Floor,
+			"total_floors":       property.TotalFloors,
+			"year_built":         property.YearBuilt,
+			"bathrooms":          property.Bathrooms,
+			"description":        property.Description,
+			"images":             property.Images,
+			"area":               property.Area,
+			"price_per_sqm":      models.PricePerSqm(property.Price, property.Area),
+			"rooms":              property.Rooms,
+			"bedrooms":           property.Bedrooms,
+			"latitude":           property.Latitude,
+			"longitude":          property.Longitude,
+			"details_fetched_at": now,
+			"detail_attempts":    0,
+		}).Error
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"pricemap-go/models"
+)
+
+func TestKeepStoredDetails(t *testing.T) {
+	fetched := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	existing := &models.Property{
+		Floor:            7,
+		TotalFloors:      12,
+		YearBuilt:        1955,
+		Bathrooms:        1,
+		Description:      "Bright flat",
+		Images:           models.StringArray{"https://img.example.com/1.jpg"},
+		Area:             54,
+		Latitude:         55.76,
+		Longitude:        37.61,
+		DetailsFetchedAt: &fetched,
+		DetailAttempts:   1,
+	}
+
+	// A fresh search card only has card fields
+	incoming := &models.Property{Price: 100, Area: 55}
+	keepStoredDetails(existing, incoming, false)
+
+	if incoming.Floor != 7 || incoming.TotalFloors != 12 || incoming.YearBuilt != 1955 || incoming.Bathrooms != 1 {
+		t.Errorf("keepStoredDetails() should carry detail fields over, got %+v", incoming)
+	}
+	if incoming.Description != "Bright flat" || len(incoming.Images) != 1 {
+		t.Errorf("keepStoredDetails() should carry description and images over")
+	}
+	if incoming.Area != 55 {
+		t.Errorf("keepStoredDetails() area = %v, want the card's 55", incoming.Area)
+	}
+	if incoming.Latitude != 55.76 || incoming.Longitude != 37.61 {
+		t.Errorf("keepStoredDetails() should keep stored coordinates when the card has none")
+	}
+	if incoming.DetailsFetchedAt == nil || incoming.DetailAttempts != 1 {
+		t.Errorf("unchanged listing should keep its detail visit state")
+	}
+
+	// A changed listing is queued for another visit
+	changed := &models.Property{Price: 90}
+	keepStoredDetails(existing, changed, true)
+	if changed.DetailsFetchedAt != nil || changed.DetailAttempts != 0 {
+		t.Errorf("changed listing should be queued for a new detail visit")
+	}
+	if changed.Floor != 7 {
+		t.Errorf("changed listing should keep stored details until the next visit")
+	}
+}
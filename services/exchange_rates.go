@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"pricemap-go/config"
+	"pricemap-go/database"
+	"pricemap-go/models"
+	"pricemap-go/utils"
+
+	"golang.org/x/text/encoding/charmap"
+	"gorm.io/gorm/clause"
+)
+
+// RateSources lists the importable exchange rate sources
+var RateSources = []string{models.RateSourceECB, models.RateSourceCBR}
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// ExchangeRatesURL returns the configured URL of a rate source
+func ExchangeRatesURL(source string) string {
+	switch source {
+	case models.RateSourceECB:
+		return config.AppConfig.ECBRatesURL
+	case models.RateSourceCBR:
+		return config.AppConfig.CBRRatesURL
+	default:
+		return ""
+	}
+}
+
+// CBRRatesURL asks the daily rates endpoint of the Central Bank of Russia for
+// the rates of one day instead of the latest ones
+func CBRRatesURL(base string, day time.Time) string {
+	u, err := url.Parse(base)
+	if err != nil {
+		return base
+	}
+	query := u.Query()
+	query.Set("date_req", day.Format("02/01/2006"))
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// ImportExchangeRates reads the rates of source from a file or an http(s) URL
+// and stores them, replacing stored rates of the same day and currency. It
+// returns how many rates were stored.
+func ImportExchangeRates(ctx context.Context, source, location string) (int, error) {
+	rates, err := FetchExchangeRates(ctx, source, location)
+	if err != nil {
+		return 0, err
+	}
+	if err := SaveExchangeRates(rates); err != nil {
+		return 0, fmt.Errorf("failed to save %s rates: %w", source, err)
+	}
+	return len(rates), nil
+}
+
+// FetchExchangeRates reads the rates of source from a file or an http(s) URL
+func FetchExchangeRates(ctx context.Context, source, location string) ([]models.ExchangeRate, error) {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		file, err := os.Open(location)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		return ParseExchangeRates(source, file)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", config.AppConfig.UserAgent)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s rates: unexpected status %d from %s", source, resp.StatusCode, location)
+	}
+	return ParseExchangeRates(source, resp.Body)
+}
+
+// ParseExchangeRates reads a rate file in the format of source
+func ParseExchangeRates(source string, r io.Reader) ([]models.ExchangeRate, error) {
+	switch source {
+	case models.RateSourceECB:
+		return ParseECBRates(r)
+	case models.RateSourceCBR:
+		return ParseCBRRates(r)
+	default:
+		return nil, fmt.Errorf("unknown rate source %q (available: %s)", source, strings.Join(RateSources, ", "))
+	}
+}
+
+// ecbEnvelope is the euro foreign exchange reference rates file of the ECB,
+// either the daily one or the full history (eurofxref-hist.xml)
+type ecbEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string  `xml:"currency,attr"`
+			Rate     float64 `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+// ParseECBRates reads ECB euro reference rates. The rates are quoted per
+// euro; they are stored per US dollar through the day's USD rate, and the
+// euro gets a rate of its own.
+func ParseECBRates(r io.Reader) ([]models.ExchangeRate, error) {
+	var envelope ecbEnvelope
+	if err := xml.NewDecoder(r).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("invalid ECB rates: %w", err)
+	}
+
+	var rates []models.ExchangeRate
+	for _, day := range envelope.Days {
+		date, err := time.Parse("2006-01-02", day.Time)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ECB rates: day %q", day.Time)
+		}
+
+		var usd float64
+		for _, rate := range day.Rates {
+			if rate.Currency == utils.BaseCurrency {
+				usd = rate.Rate
+			}
+		}
+		if usd <= 0 {
+			return nil, fmt.Errorf("invalid ECB rates: no USD rate on %s", day.Time)
+		}
+
+		rates = append(rates, models.ExchangeRate{Currency: "EUR", Date: date, Rate: 1 / usd, Source: models.RateSourceECB})
+		for _, rate := range day.Rates {
+			if rate.Currency != utils.BaseCurrency && currencyCode.MatchString(rate.Currency) && rate.Rate > 0 {
+				rates = append(rates, models.ExchangeRate{Currency: rate.Currency, Date: date, Rate: rate.Rate / usd, Source: models.RateSourceECB})
+			}
+		}
+	}
+	return rates, nil
+}
+
+// cbrRates is the daily rates file of the Central Bank of Russia
+// (XML_daily.asp), encoded in windows-1251
+type cbrRates struct {
+	Date    string `xml:"Date,attr"`
+	Valutes []struct {
+		CharCode string `xml:"CharCode"`
+		Nominal  string `xml:"Nominal"`
+		Value    string `xml:"Value"`
+	} `xml:"Valute"`
+}
+
+// ParseCBRRates reads Central Bank of Russia daily rates. The rates are
+// roubles per Nominal units of a currency, with a decimal comma; they are
+// stored per US dollar through the day's USD rate, and the rouble gets a rate
+// of its own.
+func ParseCBRRates(r io.Reader) ([]models.ExchangeRate, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		if strings.EqualFold(charset, "windows-1251") {
+			return charmap.Windows1251.NewDecoder().Reader(input), nil
+		}
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+
+	var file cbrRates
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid CBR rates: %w", err)
+	}
+	date, err := time.Parse("02.01.2006", file.Date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CBR rates: date %q", file.Date)
+	}
+
+	var codes []string
+	perUnit := make(map[string]float64, len(file.Valutes))
+	for _, valute := range file.Valutes {
+		nominal, err := strconv.ParseFloat(strings.TrimSpace(valute.Nominal), 64)
+		if err != nil || nominal <= 0 {
+			return nil, fmt.Errorf("invalid CBR rates: nominal %q of %s", valute.Nominal, valute.CharCode)
+		}
+		value, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(valute.Value), ",", "."), 64)
+		if err != nil || value <= 0 {
+			return nil, fmt.Errorf("invalid CBR rates: value %q of %s", valute.Value, valute.CharCode)
+		}
+		if currencyCode.MatchString(valute.CharCode) {
+			codes = append(codes, valute.CharCode)
+			perUnit[valute.CharCode] = value / nominal
+		}
+	}
+	usd, ok := perUnit[utils.BaseCurrency]
+	if !ok {
+		return nil, fmt.Errorf("invalid CBR rates: no USD rate on %s", file.Date)
+	}
+
+	rates := []models.ExchangeRate{{Currency: "RUB", Date: date, Rate: usd, Source: models.RateSourceCBR}}
+	for _, currency := range codes {
+		if currency != utils.BaseCurrency {
+			rates = append(rates, models.ExchangeRate{Currency: currency, Date: date, Rate: usd / perUnit[currency], Source: models.RateSourceCBR})
+		}
+	}
+	return rates, nil
+}
+
+// SaveExchangeRates stores rates, replacing stored rates of the same day and
+// currency, so the last source imported wins where sources overlap
+func SaveExchangeRates(rates []models.ExchangeRate) error {
+	if len(rates) == 0 {
+		return nil
+	}
+	return database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "currency"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "source", "updated_at"}),
+	}).CreateInBatches(&rates, 500).Error
+}
+
+// LoadCurrencyConverter returns a converter that knows every stored rate and
+// falls back to the built-in rates for currencies without any
+func LoadCurrencyConverter() (*utils.CurrencyConverter, error) {
+	var rates []models.ExchangeRate
+	if err := database.DB.Select("currency", "date", "rate").Order("currency, date").Find(&rates).Error; err != nil {
+		return nil, fmt.Errorf("failed to load exchange rates: %w", err)
+	}
+
+	converter := utils.NewCurrencyConverter()
+	for _, rate := range rates {
+		converter.AddRate(rate.Currency, rate.Date, rate.Rate)
+	}
+	return converter, nil
+}
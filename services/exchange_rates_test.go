@@ -0,0 +1,138 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"pricemap-go/models"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+const ecbRatesXML = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<gesmes:Sender>
+		<gesmes:name>European Central Bank</gesmes:name>
+	</gesmes:Sender>
+	<Cube>
+		<Cube time="2024-03-01">
+			<Cube currency="USD" rate="1.0830"/>
+			<Cube currency="JPY" rate="162.45"/>
+			<Cube currency="GBP" rate="0.85765"/>
+		</Cube>
+		<Cube time="2024-02-29">
+			<Cube currency="USD" rate="1.0813"/>
+			<Cube currency="JPY" rate="162.24"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`
+
+const cbrRatesXML = `<?xml version="1.0" encoding="windows-1251"?>
+<ValCurs Date="02.03.2024" name="Foreign Currency Market">
+	<Valute ID="R01235">
+		<NumCode>840</NumCode>
+		<CharCode>USD</CharCode>
+		<Nominal>1</Nominal>
+		<Name>Доллар США</Name>
+		<Value>91,3336</Value>
+	</Valute>
+	<Valute ID="R01820">
+		<NumCode>392</NumCode>
+		<CharCode>JPY</CharCode>
+		<Nominal>100</Nominal>
+		<Name>Японских иен</Name>
+		<Value>60,9142</Value>
+	</Valute>
+</ValCurs>`
+
+func windows1251(t *testing.T, s string) []byte {
+	t.Helper()
+	encoded, err := charmap.Windows1251.NewEncoder().String(s)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	return []byte(encoded)
+}
+
+func assertRate(t *testing.T, got models.ExchangeRate, currency, date string, rate float64) {
+	t.Helper()
+	if got.Currency != currency || got.Date.Format("2006-01-02") != date || math.Abs(got.Rate-rate) > 1e-6 {
+		t.Errorf("rate = %s %s %v, want %s %s %v", got.Currency, got.Date.Format("2006-01-02"), got.Rate, currency, date, rate)
+	}
+}
+
+func TestParseECBRates(t *testing.T) {
+	rates, err := ParseECBRates(strings.NewReader(ecbRatesXML))
+	if err != nil {
+		t.Fatalf("ParseECBRates() error = %v", err)
+	}
+	if len(rates) != 5 {
+		t.Fatalf("ParseECBRates() returned %d rates, want 5", len(rates))
+	}
+
+	// Quoted per euro, stored per dollar
+	assertRate(t, rates[0], "EUR", "2024-03-01", 1/1.0830)
+	assertRate(t, rates[1], "JPY", "2024-03-01", 162.45/1.0830)
+	assertRate(t, rates[2], "GBP", "2024-03-01", 0.85765/1.0830)
+	assertRate(t, rates[3], "EUR", "2024-02-29", 1/1.0813)
+	assertRate(t, rates[4], "JPY", "2024-02-29", 162.24/1.0813)
+	for _, rate := range rates {
+		if rate.Source != models.RateSourceECB {
+			t.Errorf("Source = %q, want %q", rate.Source, models.RateSourceECB)
+		}
+	}
+
+	_, err = ParseECBRates(strings.NewReader(`<Envelope><Cube><Cube time="2024-03-01"><Cube currency="JPY" rate="162.45"/></Cube></Cube></Envelope>`))
+	if err == nil || !strings.Contains(err.Error(), "no USD rate on 2024-03-01") {
+		t.Errorf("ParseECBRates() without USD error = %v", err)
+	}
+}
+
+func TestParseCBRRates(t *testing.T) {
+	rates, err := ParseCBRRates(bytes.NewReader(windows1251(t, cbrRatesXML)))
+	if err != nil {
+		t.Fatalf("ParseCBRRates() error = %v", err)
+	}
+	if len(rates) != 2 {
+		t.Fatalf("ParseCBRRates() returned %d rates, want 2", len(rates))
+	}
+
+	// Roubles per nominal units, stored per dollar
+	assertRate(t, rates[0], "RUB", "2024-03-02", 91.3336)
+	assertRate(t, rates[1], "JPY", "2024-03-02", 91.3336/(60.9142/100))
+
+	bad := strings.Replace(cbrRatesXML, "91,3336", "n/a", 1)
+	if _, err := ParseCBRRates(bytes.NewReader(windows1251(t, bad))); err == nil {
+		t.Error("ParseCBRRates() should reject a malformed value")
+	}
+}
+
+func TestFetchExchangeRates_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "eurofxref-hist.xml")
+	if err := os.WriteFile(path, []byte(ecbRatesXML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rates, err := FetchExchangeRates(context.Background(), models.RateSourceECB, path)
+	if err != nil || len(rates) != 5 {
+		t.Errorf("FetchExchangeRates() = %d rates, %v", len(rates), err)
+	}
+
+	if _, err := FetchExchangeRates(context.Background(), "fed", path); err == nil || !strings.Contains(err.Error(), `unknown rate source "fed"`) {
+		t.Errorf("FetchExchangeRates() with an unknown source error = %v", err)
+	}
+}
+
+func TestCBRRatesURL(t *testing.T) {
+	got := CBRRatesURL("https://www.cbr.ru/scripts/XML_daily.asp", time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC))
+	if want := "https://www.cbr.ru/scripts/XML_daily.asp?date_req=02%2F03%2F2024"; got != want {
+		t.Errorf("CBRRatesURL() = %q, want %q", got, want)
+	}
+}
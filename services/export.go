@@ -0,0 +1,363 @@
+package services
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"pricemap-go/models"
+
+	"github.com/parquet-go/parquet-go"
+	"gorm.io/gorm"
+)
+
+// Export formats
+const (
+	ExportCSV     = "csv"
+	ExportNDJSON  = "ndjson"
+	ExportParquet = "parquet"
+)
+
+// ExportFormats lists the supported formats
+var ExportFormats = []string{ExportCSV, ExportNDJSON, ExportParquet}
+
+const (
+	// exportBatchSize is how many properties are loaded from the database at once
+	exportBatchSize = 1000
+
+	// exportRowGroupSize is how many rows a Parquet row group holds; the writer
+	// keeps one row group in memory
+	exportRowGroupSize = 50000
+)
+
+type exportKind int
+
+const (
+	exportString exportKind = iota
+	exportInt
+	exportFloat
+	exportTime
+)
+
+// ExportColumn is one column of an export. Factor scores are flattened into
+// their own columns.
+type ExportColumn struct {
+	Name  string
+	kind  exportKind
+	value func(p *models.Property) any // nil is written as an empty value
+}
+
+var exportColumns = []ExportColumn{
+	{"id", exportInt, func(p *models.Property) any { return int64(p.ID) }},
+	{"source", exportString, func(p *models.Property) any { return p.Source }},
+	{"external_id", exportString, func(p *models.Property) any { return p.ExternalID }},
+	{"url", exportString, func(p *models.Property) any { return p.URL }},
+	{"country", exportString, func(p *models.Property) any { return p.Country }},
+	{"city", exportString, func(p *models.Property) any { return p.City }},
+	{"district", exportString, func(p *models.Property) any { return p.District }},
+	{"address", exportString, func(p *models.Property) any { return p.Address }},
+	{"latitude", exportFloat, func(p *models.Property) any { return p.Latitude }},
+	{"longitude", exportFloat, func(p *models.Property) any { return p.Longitude }},
+	{"type", exportString, func(p *models.Property) any { return p.Type }},
+	{"deal_type", exportString, func(p *models.Property) any { return p.DealType }},
+	{"price", exportFloat, func(p *models.Property) any { return p.Price }},
+	{"currency", exportString, func(p *models.Property) any { return p.Currency }},
+	{"native_price", exportFloat, func(p *models.Property) any {
+		if p.NativeCurrency == "" {
+			return p.Price
+		}
+		return p.NativePrice
+	}},
+	{"native_currency", exportString, func(p *models.Property) any {
+		if p.NativeCurrency == "" {
+			return p.Currency
+		}
+		return p.NativeCurrency
+	}},
+	{"area", exportFloat, func(p *models.Property) any { return p.Area }},
+	{"price_per_sqm", exportFloat, func(p *models.Property) any {
+		if p.PricePerSqm == nil {
+			return nil
+		}
+		return *p.PricePerSqm
+	}},
+	{"rooms", exportInt, func(p *models.Property) any { return int64(p.Rooms) }},
+	{"bedrooms", exportInt, func(p *models.Property) any { return int64(p.Bedrooms) }},
+	{"bathrooms", exportInt, func(p *models.Property) any { return int64(p.Bathrooms) }},
+	{"floor", exportInt, func(p *models.Property) any { return int64(p.Floor) }},
+	{"total_floors", exportInt, func(p *models.Property) any { return int64(p.TotalFloors) }},
+	{"year_built", exportInt, func(p *models.Property) any { return int64(p.YearBuilt) }},
+	{"description", exportString, func(p *models.Property) any { return p.Description }},
+	{"first_seen_at", exportTime, func(p *models.Property) any { return optionalTime(p.FirstSeenAt) }},
+	{"scraped_at", exportTime, func(p *models.Property) any { return optionalTime(p.ScrapedAt) }},
+	{"delisted_at", exportTime, func(p *models.Property) any {
+		if p.DelistedAt == nil {
+			return nil
+		}
+		return *p.DelistedAt
+	}},
+	{"days_on_market", exportInt, func(p *models.Property) any { return int64(p.DaysOnMarket) }},
+	{"canonical_id", exportInt, func(p *models.Property) any {
+		if p.CanonicalID == nil {
+			return nil
+		}
+		return int64(*p.CanonicalID)
+	}},
+	{"crime_score", exportFloat, func(p *models.Property) any { return p.Factors.CrimeScore }},
+	{"transport_score", exportFloat, func(p *models.Property) any { return p.Factors.TransportScore }},
+	{"education_score", exportFloat, func(p *models.Property) any { return p.Factors.EducationScore }},
+	{"infrastructure_score", exportFloat, func(p *models.Property) any { return p.Factors.InfrastructureScore }},
+	{"overall_score", exportFloat, func(p *models.Property) any { return p.Factors.OverallScore }},
+	{"air_quality", exportFloat, func(p *models.Property) any { return p.Factors.AirQuality }},
+	{"noise_level", exportFloat, func(p *models.Property) any { return p.Factors.NoiseLevel }},
+	{"walkability", exportFloat, func(p *models.Property) any { return p.Factors.Walkability }},
+}
+
+func optionalTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+// ExportColumnNames lists every column that can be exported, in default order
+func ExportColumnNames() []string {
+	names := make([]string, len(exportColumns))
+	for i, column := range exportColumns {
+		names[i] = column.Name
+	}
+	return names
+}
+
+// ParseExportColumns resolves a comma-separated column selection; an empty
+// selection means all columns
+func ParseExportColumns(selection string) ([]ExportColumn, error) {
+	if strings.TrimSpace(selection) == "" {
+		return exportColumns, nil
+	}
+
+	byName := make(map[string]ExportColumn, len(exportColumns))
+	for _, column := range exportColumns {
+		byName[column.Name] = column
+	}
+
+	var columns []ExportColumn
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(selection, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		column, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q (available: %s)", name, strings.Join(ExportColumnNames(), ", "))
+		}
+		seen[name] = true
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// ExportContentType returns the MIME type of an export format
+func ExportContentType(format string) string {
+	switch format {
+	case ExportNDJSON:
+		return "application/x-ndjson"
+	case ExportParquet:
+		return "application/vnd.apache.parquet"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+// PropertyExporter writes properties in one export format. Close must be
+// called to complete the output.
+type PropertyExporter interface {
+	Write(p *models.Property) error
+	Close() error
+}
+
+// NewPropertyExporter creates an exporter of the given columns to w
+func NewPropertyExporter(w io.Writer, format string, columns []ExportColumn) (PropertyExporter, error) {
+	switch format {
+	case ExportCSV:
+		return newCSVExporter(w, columns)
+	case ExportNDJSON:
+		return &ndjsonExporter{w: bufio.NewWriter(w), columns: columns}, nil
+	case ExportParquet:
+		return newParquetExporter(w, columns), nil
+	default:
+		return nil, fmt.Errorf("unknown export format %q (available: %s)", format, strings.Join(ExportFormats, ", "))
+	}
+}
+
+// ExportProperties streams the properties selected by query to the exporter
+// in batches, so memory use does not grow with the result. It returns how
+// many properties were written; the exporter is not closed.
+func ExportProperties(query *gorm.DB, exporter PropertyExporter) (int, error) {
+	var batch []models.Property
+	exported := 0
+
+	result := query.Preload("Factors").FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+		for i := range batch {
+			if err := exporter.Write(&batch[i]); err != nil {
+				return err
+			}
+			exported++
+		}
+		return nil
+	})
+	return exported, result.Error
+}
+
+type csvExporter struct {
+	w       *csv.Writer
+	columns []ExportColumn
+	record  []string
+}
+
+func newCSVExporter(w io.Writer, columns []ExportColumn) (*csvExporter, error) {
+	e := &csvExporter{w: csv.NewWriter(w), columns: columns, record: make([]string, len(columns))}
+	for i, column := range columns {
+		e.record[i] = column.Name
+	}
+	if err := e.w.Write(e.record); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *csvExporter) Write(p *models.Property) error {
+	for i, column := range e.columns {
+		e.record[i] = formatExportValue(column.value(p))
+	}
+	return e.w.Write(e.record)
+}
+
+func (e *csvExporter) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func formatExportValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// ndjsonExporter writes one JSON object per line, keys in column order
+type ndjsonExporter struct {
+	w       *bufio.Writer
+	columns []ExportColumn
+}
+
+func (e *ndjsonExporter) Write(p *models.Property) error {
+	e.w.WriteByte('{')
+	for i, column := range e.columns {
+		if i > 0 {
+			e.w.WriteByte(',')
+		}
+		key, _ := json.Marshal(column.Name)
+		value, err := json.Marshal(column.value(p))
+		if err != nil {
+			return err
+		}
+		e.w.Write(key)
+		e.w.WriteByte(':')
+		e.w.Write(value)
+	}
+	e.w.WriteString("}\n")
+	return nil
+}
+
+func (e *ndjsonExporter) Close() error {
+	return e.w.Flush()
+}
+
+// parquetExporter writes a flat schema of optional columns
+type parquetExporter struct {
+	w        *parquet.Writer
+	columns  []ExportColumn
+	indexes  []int // parquet column index of each export column
+	buffered int
+}
+
+func newParquetExporter(w io.Writer, columns []ExportColumn) *parquetExporter {
+	group := parquet.Group{}
+	for _, column := range columns {
+		group[column.Name] = parquet.Optional(parquetNode(column.kind))
+	}
+	schema := parquet.NewSchema("property", group)
+
+	// Fields of a group are stored in name order, not selection order
+	indexes := make([]int, len(columns))
+	for i, column := range columns {
+		leaf, _ := schema.Lookup(column.Name)
+		indexes[i] = leaf.ColumnIndex
+	}
+
+	return &parquetExporter{
+		w:       parquet.NewWriter(w, schema),
+		columns: columns,
+		indexes: indexes,
+	}
+}
+
+func parquetNode(kind exportKind) parquet.Node {
+	switch kind {
+	case exportInt:
+		return parquet.Int(64)
+	case exportFloat:
+		return parquet.Leaf(parquet.DoubleType)
+	case exportTime:
+		return parquet.Timestamp(parquet.Millisecond)
+	default:
+		return parquet.String()
+	}
+}
+
+func (e *parquetExporter) Write(p *models.Property) error {
+	row := make(parquet.Row, len(e.columns))
+	for i, column := range e.columns {
+		index := e.indexes[i]
+		switch v := column.value(p).(type) {
+		case nil:
+			row[index] = parquet.Value{}.Level(0, 0, index)
+		case time.Time:
+			row[index] = parquet.ValueOf(v.UnixMilli()).Level(0, 1, index)
+		default:
+			row[index] = parquet.ValueOf(v).Level(0, 1, index)
+		}
+	}
+
+	if _, err := e.w.WriteRows([]parquet.Row{row}); err != nil {
+		return err
+	}
+
+	e.buffered++
+	if e.buffered >= exportRowGroupSize {
+		e.buffered = 0
+		return e.w.Flush()
+	}
+	return nil
+}
+
+func (e *parquetExporter) Close() error {
+	return e.w.Close()
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"pricemap-go/models"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func exportTestProperties() []models.Property {
+	scraped := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	canonical := uint(3)
+	return []models.Property{
+		{
+			ID: 1, Source: "cian", City: "Moscow", Price: 12500000, Area: 54.5, Rooms: 2,
+			Address: `Tverskaya 1, "Block A"`, ScrapedAt: scraped, CanonicalID: &canonical,
+			Factors: models.PropertyFactors{OverallScore: 81.5, CrimeScore: 70},
+		},
+		{ID: 2, Source: "rightmove", City: "London", Price: 450000, Rooms: 3, ScrapedAt: scraped},
+	}
+}
+
+func writeExport(t *testing.T, format, selection string) []byte {
+	t.Helper()
+
+	columns, err := ParseExportColumns(selection)
+	if err != nil {
+		t.Fatalf("ParseExportColumns() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	exporter, err := NewPropertyExporter(&buf, format, columns)
+	if err != nil {
+		t.Fatalf("NewPropertyExporter() error = %v", err)
+	}
+	properties := exportTestProperties()
+	for i := range properties {
+		if err := exporter.Write(&properties[i]); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := exporter.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestParseExportColumns(t *testing.T) {
+	all, err := ParseExportColumns("")
+	if err != nil || len(all) != len(ExportColumnNames()) {
+		t.Fatalf("empty selection = %d columns, %v; want all", len(all), err)
+	}
+
+	columns, err := ParseExportColumns(" price, id ,price,overall_score")
+	if err != nil {
+		t.Fatalf("ParseExportColumns() error = %v", err)
+	}
+	var names []string
+	for _, column := range columns {
+		names = append(names, column.Name)
+	}
+	if strings.Join(names, ",") != "price,id,overall_score" {
+		t.Errorf("columns = %v, want price,id,overall_score", names)
+	}
+
+	if _, err := ParseExportColumns("id,factors"); err == nil || !strings.Contains(err.Error(), `"factors"`) {
+		t.Errorf("unknown column error = %v", err)
+	}
+}
+
+func TestNewPropertyExporter_UnknownFormat(t *testing.T) {
+	if _, err := NewPropertyExporter(&bytes.Buffer{}, "xlsx", exportColumns); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestExport_CSV(t *testing.T) {
+	data := writeExport(t, ExportCSV, "id,address,price,area,scraped_at,canonical_id,overall_score")
+
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	want := [][]string{
+		{"id", "address", "price", "area", "scraped_at", "canonical_id", "overall_score"},
+		{"1", `Tverskaya 1, "Block A"`, "12500000", "54.5", "2024-03-01T12:00:00Z", "3", "81.5"},
+		{"2", "", "450000", "0", "2024-03-01T12:00:00Z", "", "0"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("got %d records, want %d", len(records), len(want))
+	}
+	for i := range want {
+		if strings.Join(records[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("record %d = %v, want %v", i, records[i], want[i])
+		}
+	}
+}
+
+func TestExport_NDJSON(t *testing.T) {
+	data := writeExport(t, ExportNDJSON, "source,id,canonical_id,crime_score")
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	// Keys keep the selected order
+	if lines[0] != `{"source":"cian","id":1,"canonical_id":3,"crime_score":70}` {
+		t.Errorf("line 1 = %s", lines[0])
+	}
+
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &record); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if record["canonical_id"] != nil || record["source"] != "rightmove" {
+		t.Errorf("line 2 = %v", record)
+	}
+}
+
+func TestExport_Parquet(t *testing.T) {
+	data := writeExport(t, ExportParquet, "price,id,city,scraped_at,canonical_id")
+
+	file, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("invalid Parquet: %v", err)
+	}
+	if file.NumRows() != 2 {
+		t.Fatalf("NumRows() = %d, want 2", file.NumRows())
+	}
+
+	type row struct {
+		ID          *int64   `parquet:"id,optional"`
+		City        *string  `parquet:"city,optional"`
+		Price       *float64 `parquet:"price,optional"`
+		ScrapedAt   *int64   `parquet:"scraped_at,optional"` // milliseconds
+		CanonicalID *int64   `parquet:"canonical_id,optional"`
+	}
+	rows := make([]row, 2)
+	reader := parquet.NewGenericReader[row](file)
+	if n, _ := reader.Read(rows); n != 2 {
+		t.Fatalf("read %d rows, want 2", n)
+	}
+
+	first := rows[0]
+	if *first.ID != 1 || *first.City != "Moscow" || *first.Price != 12500000 || *first.CanonicalID != 3 {
+		t.Errorf("row 1 = id %v city %v price %v canonical %v", *first.ID, *first.City, *first.Price, *first.CanonicalID)
+	}
+	if *first.ScrapedAt != time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC).UnixMilli() {
+		t.Errorf("scraped_at = %v", *first.ScrapedAt)
+	}
+	if rows[1].CanonicalID != nil {
+		t.Errorf("row 2 canonical_id = %v, want null", *rows[1].CanonicalID)
+	}
+}
@@ -3,7 +3,6 @@ package services
 import (
 	"encoding/json"
 	"log"
-	"math"
 	"pricemap-go/database"
 	"pricemap-go/models"
 )
@@ -16,12 +15,18 @@ func NewFactorsService() *FactorsService {
 
 // CalculateFactors calculates all factors for a property
 func (fs *FactorsService) CalculateFactors(property *models.Property) (*models.PropertyFactors, error) {
+	return fs.CalculateFactorsWith(property, NewSpatialIndexes())
+}
+
+// CalculateFactorsWith calculates all factors for a property with the
+// spatial indexes of a scoring batch, shared by its properties
+func (fs *FactorsService) CalculateFactorsWith(property *models.Property, indexes *SpatialIndexes) (*models.PropertyFactors, error) {
 	factors := &models.PropertyFactors{
 		PropertyID: property.ID,
 	}
 	
 	// Calculate crime score
-	crimeScore, crimeData, err := fs.calculateCrimeScore(property)
+	crimeScore, crimeData, err := fs.calculateCrimeScore(property, indexes)
 	if err != nil {
 		log.Printf("Error calculating crime score: %v", err)
 	} else {
@@ -30,7 +35,7 @@ func (fs *FactorsService) CalculateFactors(property *models.Property) (*models.P
 	}
 	
 	// Calculate transportation accessibility
-	transportScore, transportData, err := fs.calculateTransportScore(property)
+	transportScore, transportData, err := fs.calculateTransportScore(property, indexes)
 	if err != nil {
 		log.Printf("Error calculating transport score: %v", err)
 	} else {
@@ -39,7 +44,7 @@ func (fs *FactorsService) CalculateFactors(property *models.Property) (*models.P
 	}
 	
 	// Calculate education score
-	educationScore, educationData, err := fs.calculateEducationScore(property)
+	educationScore, educationData, err := fs.calculateEducationScore(property, indexes)
 	if err != nil {
 		log.Printf("Error calculating education score: %v", err)
 	} else {
@@ -47,13 +52,14 @@ func (fs *FactorsService) CalculateFactors(property *models.Property) (*models.P
 		factors.EducationData = educationData
 	}
 	
-	// Calculate infrastructure score
-	infraScore, infraData, err := fs.calculateInfrastructureScore(property)
+	// Calculate infrastructure score and walkability
+	infrastructure, infraData, err := fs.calculateInfrastructureScore(property, indexes)
 	if err != nil {
 		log.Printf("Error calculating infrastructure score: %v", err)
 	} else {
-		factors.InfrastructureScore = infraScore
+		factors.InfrastructureScore = infrastructure.Score
 		factors.InfrastructureData = infraData
+		factors.Walkability = infrastructure.Walkability
 	}
 	
 	// Calculate overall rating (weighted sum)
@@ -62,85 +68,73 @@ func (fs *FactorsService) CalculateFactors(property *models.Property) (*models.P
 	return factors, nil
 }
 
-// calculateCrimeScore calculates safety rating (0-100)
-func (fs *FactorsService) calculateCrimeScore(property *models.Property) (float64, string, error) {
+// calculateCrimeScore calculates safety rating (0-100) from the imported
+// crime incidents of the property's city
+func (fs *FactorsService) calculateCrimeScore(property *models.Property, indexes *SpatialIndexes) (float64, string, error) {
 	crimeService := NewCrimeService()
-	return crimeService.CalculateCrimeScore(property)
+	
+	incidents, err := indexes.Crime(property.City)
+	if err != nil {
+		return 0, "", err
+	}
+	
+	assessment := crimeService.AssessCrime(property, incidents)
+	dataJSON, _ := json.Marshal(assessment)
+	
+	return assessment.Score, string(dataJSON), nil
 }
 
 // calculateTransportScore calculates transportation accessibility (0-100)
-func (fs *FactorsService) calculateTransportScore(property *models.Property) (float64, string, error) {
-	// TODO: Integration with Google Maps API, OpenStreetMap, GTFS
-	// For now, use a basic calculation based on location
-	
+// from the imported GTFS stops of the property's city
+func (fs *FactorsService) calculateTransportScore(property *models.Property, indexes *SpatialIndexes) (float64, string, error) {
 	transportService := NewTransportService()
 	
-	// In a real implementation, you would:
-	// 1. Load GTFS data for the city
-	// 2. Find nearest transit stops
-	// 3. Calculate score based on distance and availability
-	
-	// Placeholder: calculate based on city center distance (if coordinates available)
-	score := 65.0 // Default
-	
-	// If we have coordinates, we could calculate distance to known transit hubs
-	// For now, return default score
-	
-	transportData := map[string]interface{}{
-		"metro_stations": []map[string]interface{}{},
-		"bus_stops": []map[string]interface{}{},
-		"walking_time_to_transit": 0,
-		"note": "Full integration with GTFS data pending",
+	stops, err := indexes.Transit(property.City)
+	if err != nil {
+		return 0, "", err
 	}
 	
-	dataJSON, _ := json.Marshal(transportData)
+	assessment := transportService.AssessTransport(property, stops)
+	dataJSON, _ := json.Marshal(assessment)
 	
-	// Use transport service for calculation (when transit data is available)
-	_ = transportService // Will be used when GTFS data is loaded
-	
-	return score, string(dataJSON), nil
+	return assessment.Score, string(dataJSON), nil
 }
 
-// calculateEducationScore calculates education rating (0-100)
-func (fs *FactorsService) calculateEducationScore(property *models.Property) (float64, string, error) {
+// calculateEducationScore calculates education rating (0-100) from the
+// schools among the imported points of interest of the property's city
+func (fs *FactorsService) calculateEducationScore(property *models.Property, indexes *SpatialIndexes) (float64, string, error) {
 	educationService := NewEducationService()
-	return educationService.CalculateEducationScore(property)
-}
-
-// calculateInfrastructureScore calculates infrastructure rating (0-100)
-func (fs *FactorsService) calculateInfrastructureScore(property *models.Property) (float64, string, error) {
-	// TODO: Integration with POI data (Points of Interest)
-	
-	infraData := map[string]interface{}{
-		"shops": 0,
-		"parks": 0,
-		"hospitals": 0,
-		"restaurants": 0,
-	}
 	
-	dataJSON, _ := json.Marshal(infraData)
+	pois, err := indexes.POIs(property.City)
+	if err != nil {
+		return 0, "", err
+	}
 	
-	score := 70.0
+	assessment := educationService.AssessEducation(property, pois)
+	dataJSON, _ := json.Marshal(assessment)
 	
-	return score, string(dataJSON), nil
+	return assessment.Score, string(dataJSON), nil
 }
 
-// calculateOverallScore calculates overall rating
-func (fs *FactorsService) calculateOverallScore(factors *models.PropertyFactors) float64 {
-	// Weighted sum of all factors
-	weights := map[string]float64{
-		"crime":        0.25,
-		"transport":    0.25,
-		"education":    0.20,
-		"infrastructure": 0.30,
+// calculateInfrastructureScore calculates infrastructure rating (0-100) and
+// walkability from the imported points of interest of the property's city
+func (fs *FactorsService) calculateInfrastructureScore(property *models.Property, indexes *SpatialIndexes) (InfrastructureAssessment, string, error) {
+	pois, err := indexes.POIs(property.City)
+	if err != nil {
+		return InfrastructureAssessment{}, "", err
 	}
 	
-	overall := factors.CrimeScore*weights["crime"] +
-		factors.TransportScore*weights["transport"] +
-		factors.EducationScore*weights["education"] +
-		factors.InfrastructureScore*weights["infrastructure"]
+	assessment := AssessInfrastructure(property, pois)
+	dataJSON, _ := json.Marshal(assessment)
 	
-	return math.Round(overall*100) / 100
+	return assessment, string(dataJSON), nil
+}
+
+// calculateOverallScore calculates overall rating, the weighted sum of the
+// factor scores with the default weights. The API recomputes it with the
+// weights of other profiles.
+func (fs *FactorsService) calculateOverallScore(factors *models.PropertyFactors) float64 {
+	return models.DefaultFactorWeights.Score(factors)
 }
 
 // SaveFactors saves factors to database
@@ -0,0 +1,350 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"pricemap-go/config"
+	"pricemap-go/database"
+	"pricemap-go/models"
+
+	"gorm.io/gorm"
+)
+
+// gtfsWeekdays are the day columns of calendar.txt
+var gtfsWeekdays = []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}
+
+// ImportGTFS reads a GTFS feed from a zip file or an http(s) URL and replaces
+// the stored transit stops of city with the stops of the feed. It returns how
+// many stops were stored.
+func ImportGTFS(ctx context.Context, city, location string) (int, error) {
+	archive, err := OpenGTFS(ctx, location)
+	if err != nil {
+		return 0, err
+	}
+	defer archive.Close()
+
+	stops, err := ParseGTFS(&archive.Reader, city)
+	if err != nil {
+		return 0, err
+	}
+	if len(stops) == 0 {
+		return 0, fmt.Errorf("GTFS feed %s has no served stops", location)
+	}
+	if err := SaveTransitStops(city, stops); err != nil {
+		return 0, fmt.Errorf("failed to save transit stops of %s: %w", city, err)
+	}
+	return len(stops), nil
+}
+
+// GTFSArchive is an opened GTFS feed; Close removes the download of a feed
+// fetched from a URL
+type GTFSArchive struct {
+	*zip.ReadCloser
+	temp string
+}
+
+// Close closes the feed and removes its download
+func (a *GTFSArchive) Close() error {
+	err := a.ReadCloser.Close()
+	if a.temp != "" {
+		os.Remove(a.temp)
+	}
+	return err
+}
+
+// OpenGTFS opens a GTFS zip file, or downloads one from an http(s) URL into a
+// temporary file first, as zip archives need random access
+func OpenGTFS(ctx context.Context, location string) (*GTFSArchive, error) {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		archive, err := zip.OpenReader(location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GTFS feed %s: %w", location, err)
+		}
+		return &GTFSArchive{ReadCloser: archive}, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", config.AppConfig.UserAgent)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GTFS feed: unexpected status %d from %s", resp.StatusCode, location)
+	}
+
+	file, err := os.CreateTemp("", "gtfs-*.zip")
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.Copy(file, resp.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return nil, fmt.Errorf("failed to download GTFS feed %s: %w", location, err)
+	}
+
+	archive, err := zip.OpenReader(file.Name())
+	if err != nil {
+		os.Remove(file.Name())
+		return nil, fmt.Errorf("invalid GTFS feed %s: %w", location, err)
+	}
+	return &GTFSArchive{ReadCloser: archive, temp: file.Name()}, nil
+}
+
+// gtfsTrip is what the stop times of a trip contribute to their stops
+type gtfsTrip struct {
+	mode   string
+	weight float64 // service days per day, 1 without calendar.txt
+}
+
+// ParseGTFS reads the stops of a GTFS feed with the modes of the routes
+// calling at them and their departures per day. Route modes come from
+// routes.txt route_type; departures are the stop_times.txt calls of each
+// trip, weighted by the share of weekdays its service runs on in
+// calendar.txt, so they average over the week (trips of services missing
+// from calendar.txt, or of feeds without one, count every day). Platforms
+// are merged into their parent station, and stops no route of a known mode
+// calls at are left out.
+func ParseGTFS(archive *zip.Reader, city string) ([]models.TransitStop, error) {
+	routeModes := make(map[string]string)
+	err := readGTFSTable(archive, "routes.txt", []string{"route_id", "route_type"}, func(field func(string) string) error {
+		routeType, err := strconv.Atoi(field("route_type"))
+		if err != nil {
+			return fmt.Errorf("invalid GTFS feed: routes.txt: invalid route_type %q of route %s", field("route_type"), field("route_id"))
+		}
+		if mode := TransitMode(routeType); mode != "" {
+			routeModes[field("route_id")] = mode
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	serviceWeights := make(map[string]float64)
+	if findGTFSFile(archive, "calendar.txt") != nil {
+		err := readGTFSTable(archive, "calendar.txt", append([]string{"service_id"}, gtfsWeekdays...), func(field func(string) string) error {
+			days := 0
+			for _, day := range gtfsWeekdays {
+				if field(day) == "1" {
+					days++
+				}
+			}
+			serviceWeights[field("service_id")] = float64(days) / float64(len(gtfsWeekdays))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	trips := make(map[string]gtfsTrip)
+	err = readGTFSTable(archive, "trips.txt", []string{"route_id", "service_id", "trip_id"}, func(field func(string) string) error {
+		mode, ok := routeModes[field("route_id")]
+		if !ok {
+			return nil
+		}
+		weight, ok := serviceWeights[field("service_id")]
+		if !ok {
+			weight = 1
+		}
+		trips[field("trip_id")] = gtfsTrip{mode: mode, weight: weight}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	departures := make(map[string]float64)
+	modes := make(map[string]map[string]bool)
+	err = readGTFSTable(archive, "stop_times.txt", []string{"trip_id", "stop_id"}, func(field func(string) string) error {
+		trip, ok := trips[field("trip_id")]
+		if !ok {
+			return nil
+		}
+		stopID := field("stop_id")
+		departures[stopID] += trip.weight
+		if modes[stopID] == nil {
+			modes[stopID] = make(map[string]bool)
+		}
+		modes[stopID][trip.mode] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var order []string
+	stops := make(map[string]*models.TransitStop)
+	parents := make(map[string]string)
+	err = readGTFSTable(archive, "stops.txt", []string{"stop_id", "stop_lat", "stop_lon"}, func(field func(string) string) error {
+		lat, latErr := strconv.ParseFloat(field("stop_lat"), 64)
+		lng, lngErr := strconv.ParseFloat(field("stop_lon"), 64)
+		if latErr != nil || lngErr != nil {
+			return nil // entrances and nodes may come without coordinates
+		}
+		stopID := field("stop_id")
+		order = append(order, stopID)
+		stops[stopID] = &models.TransitStop{City: city, StopID: stopID, Name: field("stop_name"), Latitude: lat, Longitude: lng}
+		if parent := field("parent_station"); parent != "" {
+			parents[stopID] = parent
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Merge platforms into their station
+	stationModes := make(map[string]map[string]bool)
+	for stopID, count := range departures {
+		stationID := stopID
+		if parent, ok := parents[stopID]; ok && stops[parent] != nil {
+			stationID = parent
+		}
+		station, ok := stops[stationID]
+		if !ok {
+			continue // stop_times of a stop missing from stops.txt
+		}
+		station.DeparturesPerDay += count
+		if stationModes[stationID] == nil {
+			stationModes[stationID] = make(map[string]bool)
+		}
+		for mode := range modes[stopID] {
+			stationModes[stationID][mode] = true
+		}
+	}
+
+	var served []models.TransitStop
+	for _, stopID := range order {
+		stop := stops[stopID]
+		if stop.DeparturesPerDay == 0 {
+			continue
+		}
+		var stopModes []string
+		for _, mode := range models.TransitModes {
+			if stationModes[stopID][mode] {
+				stopModes = append(stopModes, mode)
+			}
+		}
+		stop.Modes = strings.Join(stopModes, ",")
+		stop.DeparturesPerDay = math.Round(stop.DeparturesPerDay*100) / 100
+		served = append(served, *stop)
+	}
+	return served, nil
+}
+
+// TransitMode maps a GTFS route_type, basic or extended, to a transit mode.
+// Ferries, cable cars, funiculars and other modes return "".
+func TransitMode(routeType int) string {
+	switch {
+	case routeType == 1 || routeType == 12: // subway, monorail
+		return models.TransitModeMetro
+	case routeType >= 400 && routeType < 500: // urban railway
+		return models.TransitModeMetro
+	case routeType == 2 || (routeType >= 100 && routeType < 200): // rail
+		return models.TransitModeRail
+	case routeType == 0 || routeType == 5: // tram, cable tram
+		return models.TransitModeTram
+	case routeType >= 900 && routeType < 1000: // tram
+		return models.TransitModeTram
+	case routeType == 3 || routeType == 11: // bus, trolleybus
+		return models.TransitModeBus
+	case routeType >= 200 && routeType < 300, routeType >= 700 && routeType < 900: // coach, bus, trolleybus
+		return models.TransitModeBus
+	default:
+		return ""
+	}
+}
+
+// findGTFSFile finds a file of a feed, also when the feed is zipped with its
+// enclosing directory
+func findGTFSFile(archive *zip.Reader, name string) *zip.File {
+	for _, file := range archive.File {
+		if path.Base(file.Name) == name {
+			return file
+		}
+	}
+	return nil
+}
+
+// readGTFSTable calls row for every record of a CSV file of a feed; field
+// returns the trimmed value of a column, "" for absent optional columns
+func readGTFSTable(archive *zip.Reader, name string, required []string, row func(field func(string) string) error) error {
+	file := findGTFSFile(archive, name)
+	if file == nil {
+		return fmt.Errorf("invalid GTFS feed: %s not found", name)
+	}
+	r, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("invalid GTFS feed: %s: %w", name, err)
+	}
+	defer r.Close()
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("invalid GTFS feed: %s: %w", name, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, column := range header {
+		columns[strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))] = i
+	}
+	for _, column := range required {
+		if _, ok := columns[column]; !ok {
+			return fmt.Errorf("invalid GTFS feed: %s has no %s column", name, column)
+		}
+	}
+
+	var record []string
+	field := func(column string) string {
+		if i, ok := columns[column]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	for {
+		record, err = reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid GTFS feed: %s: %w", name, err)
+		}
+		if err := row(field); err != nil {
+			return err
+		}
+	}
+}
+
+// SaveTransitStops replaces the stored transit stops of city
+func SaveTransitStops(city string, stops []models.TransitStop) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("city = ?", city).Delete(&models.TransitStop{}).Error; err != nil {
+			return err
+		}
+		return tx.CreateInBatches(&stops, 500).Error
+	})
+}
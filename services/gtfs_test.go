@@ -0,0 +1,175 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pricemap-go/models"
+)
+
+// gtfsFeed is a small feed: a metro station with two platforms, a tram and
+// bus stop, a stop only a ferry calls at, and a stop no trip calls at
+var gtfsFeed = map[string]string{
+	"routes.txt": `route_id,route_short_name,route_type
+M1,1,1
+T3,3,0
+B12,12,3
+B40,40,700
+F1,F,4
+`,
+	"calendar.txt": `service_id,monday,tuesday,wednesday,thursday,friday,saturday,sunday,start_date,end_date
+WD,1,1,1,1,1,0,0,20240101,20241231
+WE,0,0,0,0,0,1,1,20240101,20241231
+`,
+	"trips.txt": `route_id,service_id,trip_id
+M1,WD,m1
+M1,WE,m2
+T3,WD,t1
+B12,DAILY,b1
+B40,WD,b2
+F1,WD,f1
+`,
+	"stop_times.txt": `trip_id,arrival_time,departure_time,stop_id,stop_sequence
+m1,08:00:00,08:00:00,P1,1
+m1,08:03:00,08:03:00,P2,2
+m2,09:00:00,09:00:00,P1,1
+t1,08:10:00,08:10:00,S2,1
+b1,08:20:00,08:20:00,S2,2
+b2,08:30:00,08:30:00,P2,1
+f1,08:40:00,08:40:00,S3,1
+`,
+	"stops.txt": "\ufeff" + `stop_id,stop_name,stop_lat,stop_lon,location_type,parent_station
+ST1,Okhotny Ryad,55.7576,37.6156,1,
+P1,Okhotny Ryad 1,55.7577,37.6155,0,ST1
+P2,Okhotny Ryad 2,55.7575,37.6157,0,ST1
+E1,Entrance,,,2,ST1
+S2,Teatralnaya,55.7587,37.6190,0,
+S3,Pier,55.7500,37.6200,0,
+S4,Unserved,55.7600,37.6200,0,
+`,
+}
+
+func gtfsZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func parseGTFSFeed(t *testing.T, files map[string]string) ([]models.TransitStop, error) {
+	t.Helper()
+	data := gtfsZip(t, files)
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ParseGTFS(archive, "Moscow")
+}
+
+func TestParseGTFS(t *testing.T) {
+	stops, err := parseGTFSFeed(t, gtfsFeed)
+	if err != nil {
+		t.Fatalf("ParseGTFS() error = %v", err)
+	}
+	if len(stops) != 2 {
+		t.Fatalf("ParseGTFS() returned %d stops, want 2: %+v", len(stops), stops)
+	}
+
+	// Platforms merged into the station: m1 twice on weekdays (5/7 each),
+	// m2 at weekends (2/7), b2 on weekdays (5/7)
+	station := stops[0]
+	if station.StopID != "ST1" || station.Name != "Okhotny Ryad" || station.City != "Moscow" || station.Latitude != 55.7576 {
+		t.Errorf("station = %+v", station)
+	}
+	if station.Modes != "metro,bus" {
+		t.Errorf("station Modes = %q, want %q", station.Modes, "metro,bus")
+	}
+	if want := 2.43; station.DeparturesPerDay != want {
+		t.Errorf("station DeparturesPerDay = %v, want %v", station.DeparturesPerDay, want)
+	}
+
+	// t1 on weekdays, b1 of a service missing from calendar.txt every day
+	stop := stops[1]
+	if stop.StopID != "S2" || stop.Modes != "tram,bus" || stop.DeparturesPerDay != 1.71 {
+		t.Errorf("stop = %+v", stop)
+	}
+}
+
+func TestParseGTFS_Invalid(t *testing.T) {
+	files := make(map[string]string)
+	for name, content := range gtfsFeed {
+		if name != "stop_times.txt" {
+			files[name] = content
+		}
+	}
+	if _, err := parseGTFSFeed(t, files); err == nil || !strings.Contains(err.Error(), "stop_times.txt not found") {
+		t.Errorf("ParseGTFS() without stop_times.txt error = %v", err)
+	}
+
+	files["stop_times.txt"] = "trip_id,arrival_time\nm1,08:00:00\n"
+	if _, err := parseGTFSFeed(t, files); err == nil || !strings.Contains(err.Error(), "stop_times.txt has no stop_id column") {
+		t.Errorf("ParseGTFS() without stop_id error = %v", err)
+	}
+}
+
+func TestTransitMode(t *testing.T) {
+	tests := map[int]string{
+		0:    models.TransitModeTram,
+		1:    models.TransitModeMetro,
+		2:    models.TransitModeRail,
+		3:    models.TransitModeBus,
+		4:    "",
+		11:   models.TransitModeBus,
+		12:   models.TransitModeMetro,
+		109:  models.TransitModeRail,
+		401:  models.TransitModeMetro,
+		700:  models.TransitModeBus,
+		800:  models.TransitModeBus,
+		900:  models.TransitModeTram,
+		1000: "",
+	}
+	for routeType, want := range tests {
+		if got := TransitMode(routeType); got != want {
+			t.Errorf("TransitMode(%d) = %q, want %q", routeType, got, want)
+		}
+	}
+}
+
+func TestOpenGTFS_File(t *testing.T) {
+	files := make(map[string]string)
+	for name, content := range gtfsFeed {
+		files["feed/"+name] = content // zipped with its directory
+	}
+	path := filepath.Join(t.TempDir(), "gtfs.zip")
+	if err := os.WriteFile(path, gtfsZip(t, files), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := OpenGTFS(context.Background(), path)
+	if err != nil {
+		t.Fatalf("OpenGTFS() error = %v", err)
+	}
+	defer archive.Close()
+
+	stops, err := ParseGTFS(&archive.Reader, "Moscow")
+	if err != nil || len(stops) != 2 {
+		t.Errorf("ParseGTFS() = %d stops, %v", len(stops), err)
+	}
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"pricemap-go/database"
+	"pricemap-go/models"
+)
+
+// priceChanged reports whether the incoming scrape of a listing differs from
+// the stored version in a way that belongs in the price timeline
+func priceChanged(existing, incoming *models.Property) bool {
+	return existing.Price != incoming.Price ||
+		existing.Currency != incoming.Currency ||
+		existing.IsActive != incoming.IsActive
+}
+
+// newPriceObservation builds a timeline entry from the current state of a property
+func newPriceObservation(property *models.Property) models.PriceObservation {
+	observedAt := property.ScrapedAt
+	if observedAt.IsZero() {
+		observedAt = property.UpdatedAt
+	}
+
+	return models.PriceObservation{
+		PropertyID: property.ID,
+		Price:      property.Price,
+		Currency:   property.Currency,
+		IsActive:   property.IsActive,
+		ObservedAt: observedAt,
+	}
+}
+
+// savePriceObservations stores timeline entries in a single batch
+func savePriceObservations(observations []models.PriceObservation) error {
+	if len(observations) == 0 {
+		return nil
+	}
+	return database.DB.Create(&observations).Error
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"pricemap-go/models"
+)
+
+func TestPriceChanged(t *testing.T) {
+	base := models.Property{Price: 100000, Currency: "USD", IsActive: true}
+
+	tests := []struct {
+		name     string
+		incoming models.Property
+		want     bool
+	}{
+		{
+			name:     "unchanged",
+			incoming: models.Property{Price: 100000, Currency: "USD", IsActive: true},
+			want:     false,
+		},
+		{
+			name:     "price drop",
+			incoming: models.Property{Price: 95000, Currency: "USD", IsActive: true},
+			want:     true,
+		},
+		{
+			name:     "currency change",
+			incoming: models.Property{Price: 100000, Currency: "EUR", IsActive: true},
+			want:     true,
+		},
+		{
+			name:     "relisted",
+			incoming: models.Property{Price: 100000, Currency: "USD", IsActive: false},
+			want:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := priceChanged(&base, &tt.incoming); got != tt.want {
+				t.Errorf("priceChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPriceObservation(t *testing.T) {
+	scrapedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	property := &models.Property{
+		ID:        42,
+		Price:     250000,
+		Currency:  "GBP",
+		IsActive:  true,
+		ScrapedAt: scrapedAt,
+	}
+
+	obs := newPriceObservation(property)
+
+	if obs.PropertyID != 42 {
+		t.Errorf("PropertyID = %v, want 42", obs.PropertyID)
+	}
+	if obs.Price != 250000 || obs.Currency != "GBP" || !obs.IsActive {
+		t.Errorf("newPriceObservation() = %+v, want price/currency/active copied", obs)
+	}
+	if !obs.ObservedAt.Equal(scrapedAt) {
+		t.Errorf("ObservedAt = %v, want %v", obs.ObservedAt, scrapedAt)
+	}
+}
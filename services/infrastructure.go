@@ -0,0 +1,170 @@
+package services
+
+import (
+	"fmt"
+	"math"
+
+	"pricemap-go/database"
+	"pricemap-go/models"
+	"pricemap-go/utils"
+)
+
+const (
+	walkFullCredit = 400.0  // Metres of walk within which an amenity counts in full
+	walkNoCredit   = 1600.0 // Metres of walk from which an amenity no longer counts, about 20 minutes
+
+	// defaultInfrastructureScore is the score of cities without imported
+	// points of interest
+	defaultInfrastructureScore = 70.0
+)
+
+// poiScoring is how a category of points of interest counts towards the
+// infrastructure score and walkability
+type poiScoring struct {
+	category string
+	weight   float64 // Share of the infrastructure score
+	radius   float64 // Metres of walk within which its amenities are counted
+	enough   int     // Amenities within radius that give full density points
+
+	// Walkability points of the nearest amenities, nearest first
+	walkWeights []float64
+}
+
+var poiScorings = []poiScoring{
+	{models.POICategoryGroceries, 0.25, 1000, 3, []float64{3}},
+	{models.POICategoryRestaurants, 0.15, 1000, 10, []float64{0.75, 0.45, 0.25, 0.25, 0.225, 0.225, 0.225, 0.225, 0.2, 0.2}},
+	{models.POICategoryShops, 0.15, 1000, 10, []float64{0.5, 0.45, 0.4, 0.35, 0.3}},
+	{models.POICategoryParks, 0.15, 1000, 2, []float64{1}},
+	{models.POICategoryHealthcare, 0.10, 1500, 3, []float64{1}},
+	{models.POICategorySchools, 0.10, 1500, 2, []float64{1}},
+	{models.POICategoryServices, 0.10, 1000, 3, []float64{1, 1}},
+}
+
+// POIIndex holds a spatial index of points of interest per category
+type POIIndex map[string]*utils.SpatialIndex[models.PointOfInterest]
+
+// LoadPOIIndex indexes the stored points of interest of a city
+func LoadPOIIndex(city string) (POIIndex, error) {
+	index := make(POIIndex)
+	if database.DB == nil {
+		return index, nil // No points of interest without a database
+	}
+
+	var pois []models.PointOfInterest
+	err := database.DB.Select("category", "kind", "name", "latitude", "longitude").
+		Where("city = ?", city).Find(&pois).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load points of interest of %s: %w", city, err)
+	}
+	return NewPOIIndex(pois), nil
+}
+
+// NewPOIIndex indexes points of interest by category
+func NewPOIIndex(pois []models.PointOfInterest) POIIndex {
+	index := make(POIIndex)
+	for _, poi := range pois {
+		if index[poi.Category] == nil {
+			index[poi.Category] = utils.NewSpatialIndex[models.PointOfInterest](utils.DefaultSpatialCellKm)
+		}
+		index[poi.Category].Insert(poi.Latitude, poi.Longitude, poi)
+	}
+	return index
+}
+
+// InfrastructureAssessment is an infrastructure score and walkability with
+// the amenities they are based on, stored as the InfrastructureData of the
+// property factors
+type InfrastructureAssessment struct {
+	Score       float64                       `json:"score"`
+	Walkability float64                       `json:"walkability"` // 0 when unknown
+	Categories  map[string]CategoryAssessment `json:"categories"`
+	Note        string                        `json:"note,omitempty"`
+}
+
+// CategoryAssessment is what a category of points of interest contributed
+type CategoryAssessment struct {
+	Count   int        `json:"count"`    // Within radius
+	Radius  int        `json:"radius_m"` // Walking radius
+	Nearest *NearbyPOI `json:"nearest"`  // Null when none is within walking reach
+	Score   float64    `json:"score"`    // 0-100, before weighting
+}
+
+// NearbyPOI is a point of interest near a property
+type NearbyPOI struct {
+	Name            string `json:"name,omitempty"`
+	Kind            string `json:"kind"`
+	WalkingDistance int    `json:"walking_distance_m"`
+	WalkingMinutes  int    `json:"walking_minutes"`
+}
+
+// AssessInfrastructure scores the infrastructure (0-100) and walkability
+// (0-100) around a property from the points of interest of its city. Each
+// category scores half on the walk to its nearest amenity (full within
+// 400 m, none beyond its radius) and half on how many lie within its radius,
+// and is weighted by its share. Walkability sums the points of the nearest
+// amenities of each category, decaying from a 400 m to a 1600 m walk.
+func AssessInfrastructure(property *models.Property, pois POIIndex) InfrastructureAssessment {
+	if len(pois) == 0 {
+		return InfrastructureAssessment{
+			Score:      defaultInfrastructureScore,
+			Categories: map[string]CategoryAssessment{},
+			Note:       "no points of interest known for the city",
+		}
+	}
+
+	assessment := InfrastructureAssessment{Categories: make(map[string]CategoryAssessment, len(poiScorings))}
+	score, walk, walkTotal := 0.0, 0.0, 0.0
+	for _, scoring := range poiScorings {
+		reach := math.Max(scoring.radius, walkNoCredit)
+		category := CategoryAssessment{Radius: int(scoring.radius)}
+
+		var nearby []utils.Neighbor[models.PointOfInterest]
+		if index := pois[scoring.category]; index != nil {
+			nearby = index.WithinRadius(property.Latitude, property.Longitude, reach/walkingDetour/1000)
+		}
+		for i, neighbor := range nearby {
+			distance := neighbor.Distance * 1000 * walkingDetour
+			if distance <= scoring.radius {
+				category.Count++
+			}
+			if i < len(scoring.walkWeights) {
+				walk += scoring.walkWeights[i] * walkCredit(distance, walkNoCredit)
+			}
+		}
+		for _, weight := range scoring.walkWeights {
+			walkTotal += weight
+		}
+
+		if len(nearby) > 0 {
+			nearest := nearby[0]
+			distance := nearest.Distance * 1000 * walkingDetour
+			category.Nearest = &NearbyPOI{
+				Name:            nearest.Value.Name,
+				Kind:            nearest.Value.Kind,
+				WalkingDistance: int(math.Round(distance)),
+				WalkingMinutes:  int(math.Ceil(distance / walkingSpeed)),
+			}
+			density := math.Min(float64(category.Count)/float64(scoring.enough), 1)
+			category.Score = math.Round((walkCredit(distance, scoring.radius)+density)*50*100) / 100
+		}
+
+		score += scoring.weight * category.Score
+		assessment.Categories[scoring.category] = category
+	}
+
+	assessment.Score = math.Round(score*100) / 100
+	assessment.Walkability = math.Round(walk/walkTotal*100*100) / 100
+	return assessment
+}
+
+// walkCredit is 1 for walks up to walkFullCredit, falling linearly to 0 at
+// noCredit
+func walkCredit(distance, noCredit float64) float64 {
+	if distance <= walkFullCredit {
+		return 1
+	}
+	if distance >= noCredit {
+		return 0
+	}
+	return 1 - (distance-walkFullCredit)/(noCredit-walkFullCredit)
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"testing"
+
+	"pricemap-go/models"
+)
+
+func TestAssessInfrastructure(t *testing.T) {
+	property := &models.Property{Latitude: 55.7558, Longitude: 37.6173}
+
+	// Without points of interest the city keeps the default score
+	got := AssessInfrastructure(property, NewPOIIndex(nil))
+	if got.Score != defaultInfrastructureScore || got.Walkability != 0 || got.Note == "" {
+		t.Errorf("AssessInfrastructure() without data = %+v", got)
+	}
+
+	pois := []models.PointOfInterest{
+		{Category: models.POICategoryGroceries, Kind: "supermarket", Name: "Near", Latitude: 55.7560, Longitude: 37.6173}, // ~30 m walk
+		{Category: models.POICategoryGroceries, Kind: "convenience", Latitude: 55.7580, Longitude: 37.6173},               // ~320 m
+		{Category: models.POICategoryGroceries, Kind: "bakery", Latitude: 55.7590, Longitude: 37.6173},                    // ~460 m
+		{Category: models.POICategoryParks, Kind: "park", Latitude: 55.7620, Longitude: 37.6173},                          // ~900 m
+		{Category: models.POICategoryHealthcare, Kind: "hospital", Latitude: 55.7800, Longitude: 37.6173},                 // out of reach
+	}
+	got = AssessInfrastructure(property, NewPOIIndex(pois))
+
+	groceries := got.Categories[models.POICategoryGroceries]
+	if groceries.Count != 3 || groceries.Radius != 1000 || groceries.Nearest == nil || groceries.Nearest.Name != "Near" {
+		t.Fatalf("groceries = %+v", groceries)
+	}
+	if groceries.Nearest.WalkingDistance < 25 || groceries.Nearest.WalkingDistance > 35 || groceries.Score != 100 {
+		t.Errorf("groceries nearest = %+v, score %v", groceries.Nearest, groceries.Score)
+	}
+
+	// Half the points for a park 900 m away, half for one of the two parks wanted
+	parks := got.Categories[models.POICategoryParks]
+	if parks.Count != 1 || parks.Score < 30 || parks.Score > 40 {
+		t.Errorf("parks = %+v", parks)
+	}
+
+	healthcare := got.Categories[models.POICategoryHealthcare]
+	if healthcare.Count != 0 || healthcare.Nearest != nil || healthcare.Score != 0 {
+		t.Errorf("healthcare = %+v", healthcare)
+	}
+	if _, ok := got.Categories[models.POICategoryRestaurants]; !ok {
+		t.Error("every category should be in the breakdown")
+	}
+
+	// 25 for groceries and 15 * parks score
+	if want := 25 + 0.15*parks.Score; got.Score < want-0.01 || got.Score > want+0.01 {
+		t.Errorf("Score = %v, want %v", got.Score, want)
+	}
+	// Walkability: groceries 3 in full and the park's point decayed, out of 13
+	if got.Walkability < 23 || got.Walkability > 30 {
+		t.Errorf("Walkability = %v", got.Walkability)
+	}
+}
+
+func TestWalkCredit(t *testing.T) {
+	tests := []struct {
+		distance, noCredit, want float64
+	}{
+		{100, 1600, 1},
+		{400, 1600, 1},
+		{1000, 1600, 0.5},
+		{1600, 1600, 0},
+		{700, 1000, 0.5},
+	}
+	for _, tt := range tests {
+		if got := walkCredit(tt.distance, tt.noCredit); got != tt.want {
+			t.Errorf("walkCredit(%v, %v) = %v, want %v", tt.distance, tt.noCredit, got, tt.want)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package services
 import (
 	"sync"
 	"time"
+
+	"pricemap-go/metrics"
 )
 
 // MetricsService tracks system metrics
@@ -60,6 +62,12 @@ func (ms *MetricsService) RecordParserRun(parserName string, propertiesParsed, p
 	ms.TotalPropertiesParsed += propertiesParsed
 	ms.TotalPropertiesSaved += propertiesSaved
 	ms.TotalErrors += errors
+
+	// Export the same counts for Prometheus
+	metrics.PropertiesParsed.WithLabelValues(parserName).Add(float64(propertiesParsed))
+	metrics.PropertiesSaved.WithLabelValues(parserName).Add(float64(propertiesSaved))
+	metrics.ParserErrors.WithLabelValues(parserName).Add(float64(errors))
+	metrics.ParserRunDuration.WithLabelValues(parserName).Observe(duration.Seconds())
 }
 
 // GetStats returns current metrics
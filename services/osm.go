@@ -0,0 +1,286 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+
+	"pricemap-go/database"
+	"pricemap-go/models"
+
+	"github.com/paulmach/osm"
+	"github.com/paulmach/osm/osmpbf"
+	"gorm.io/gorm"
+)
+
+// ImportPOIs reads the points of interest of an OpenStreetMap extract, a
+// .osm.pbf file or Overpass JSON, and replaces the stored points of interest
+// of city with them. It returns how many were stored.
+func ImportPOIs(ctx context.Context, city, path string) (int, error) {
+	var pois []models.PointOfInterest
+	if strings.HasSuffix(strings.ToLower(path), ".pbf") {
+		var err error
+		if pois, err = ReadOSMPBF(ctx, path, city); err != nil {
+			return 0, err
+		}
+	} else {
+		file, err := os.Open(path)
+		if err != nil {
+			return 0, err
+		}
+		defer file.Close()
+		if pois, err = ParseOverpassJSON(file, city); err != nil {
+			return 0, err
+		}
+	}
+
+	if len(pois) == 0 {
+		return 0, fmt.Errorf("OSM extract %s has no points of interest", path)
+	}
+	if err := SavePOIs(city, pois); err != nil {
+		return 0, fmt.Errorf("failed to save points of interest of %s: %w", city, err)
+	}
+	return len(pois), nil
+}
+
+// POICategory returns the category of an OpenStreetMap element from its
+// tags, and the tag value that decided it, or "" for elements of no category
+func POICategory(tag func(key string) string) (category, kind string) {
+	switch shop := tag("shop"); shop {
+	case "", "no", "vacant":
+	case "supermarket", "convenience", "greengrocer", "bakery", "butcher", "deli", "grocery", "general":
+		return models.POICategoryGroceries, shop
+	default:
+		return models.POICategoryShops, shop
+	}
+
+	switch amenity := tag("amenity"); amenity {
+	case "marketplace":
+		return models.POICategoryGroceries, amenity
+	case "restaurant", "cafe", "fast_food", "bar", "pub", "food_court", "ice_cream", "biergarten":
+		return models.POICategoryRestaurants, amenity
+	case "hospital", "clinic", "doctors", "dentist", "pharmacy":
+		return models.POICategoryHealthcare, amenity
+	case "kindergarten", "school", "college", "university":
+		return models.POICategorySchools, amenity
+	case "bank", "post_office", "library":
+		return models.POICategoryServices, amenity
+	}
+
+	switch leisure := tag("leisure"); leisure {
+	case "park", "garden", "playground":
+		return models.POICategoryParks, leisure
+	}
+	return "", ""
+}
+
+// osmWay is a way with a category, waiting for the coordinates of its nodes
+type osmWay struct {
+	poi   models.PointOfInterest
+	nodes []osm.NodeID
+}
+
+// ReadOSMPBF reads the points of interest of a .osm.pbf extract: tagged nodes
+// and ways, the latter at the centre of their nodes. The file is read twice,
+// first for the ways, then for the nodes they need, since ways refer to
+// nodes by ID. Relations, such as multipolygon parks, are left out.
+func ReadOSMPBF(ctx context.Context, path, city string) ([]models.PointOfInterest, error) {
+	var ways []osmWay
+	needed := make(map[osm.NodeID]bool)
+	err := scanOSMPBF(ctx, path, func(scanner *osmpbf.Scanner) {
+		scanner.SkipNodes = true
+		scanner.SkipRelations = true
+		scanner.FilterWay = func(way *osm.Way) bool { return len(way.Tags) > 0 }
+	}, func(object osm.Object) {
+		way, ok := object.(*osm.Way)
+		if !ok {
+			return
+		}
+		category, kind := POICategory(way.Tags.Find)
+		if category == "" {
+			return
+		}
+		nodes := make([]osm.NodeID, len(way.Nodes))
+		for i, node := range way.Nodes {
+			nodes[i] = node.ID
+			needed[node.ID] = true
+		}
+		ways = append(ways, osmWay{
+			poi:   models.PointOfInterest{City: city, OSMType: "way", OSMID: int64(way.ID), Category: category, Kind: kind, Name: way.Tags.Find("name")},
+			nodes: nodes,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var pois []models.PointOfInterest
+	coordinates := make(map[osm.NodeID][2]float64, len(needed))
+	err = scanOSMPBF(ctx, path, func(scanner *osmpbf.Scanner) {
+		scanner.SkipWays = true
+		scanner.SkipRelations = true
+		scanner.FilterNode = func(node *osm.Node) bool { return len(node.Tags) > 0 || needed[node.ID] }
+	}, func(object osm.Object) {
+		node, ok := object.(*osm.Node)
+		if !ok {
+			return
+		}
+		if needed[node.ID] {
+			coordinates[node.ID] = [2]float64{node.Lat, node.Lon}
+		}
+		if category, kind := POICategory(node.Tags.Find); category != "" {
+			pois = append(pois, models.PointOfInterest{
+				City: city, OSMType: "node", OSMID: int64(node.ID), Category: category, Kind: kind,
+				Name: node.Tags.Find("name"), Latitude: node.Lat, Longitude: node.Lon,
+			})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, way := range ways {
+		var points [][2]float64
+		for _, id := range way.nodes {
+			if point, ok := coordinates[id]; ok {
+				points = append(points, point)
+			}
+		}
+		if lat, lng, ok := centre(points); ok {
+			way.poi.Latitude, way.poi.Longitude = lat, lng
+			pois = append(pois, way.poi)
+		}
+	}
+	return pois, nil
+}
+
+func scanOSMPBF(ctx context.Context, path string, configure func(*osmpbf.Scanner), object func(osm.Object)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := osmpbf.New(ctx, file, runtime.GOMAXPROCS(0))
+	defer scanner.Close()
+	configure(scanner)
+
+	for scanner.Scan() {
+		object(scanner.Object())
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("invalid OSM extract %s: %w", path, err)
+	}
+	return nil
+}
+
+// overpassPoint is a coordinate of an Overpass element
+type overpassPoint struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// overpassResult is the JSON output of an Overpass API query ([out:json])
+type overpassResult struct {
+	Elements []struct {
+		Type     string            `json:"type"`
+		ID       int64             `json:"id"`
+		Lat      *float64          `json:"lat"`
+		Lon      *float64          `json:"lon"`
+		Center   *overpassPoint    `json:"center"`   // out center
+		Geometry []overpassPoint   `json:"geometry"` // out geom
+		Nodes    []int64           `json:"nodes"`    // out body, with the nodes in the result (>;)
+		Tags     map[string]string `json:"tags"`
+	} `json:"elements"`
+}
+
+// ParseOverpassJSON reads the points of interest of an Overpass API result.
+// Ways and relations are placed at their centre (out center), the centre of
+// their geometry (out geom), or the centre of their nodes when the result
+// holds them (out body; >; out skel qt), and left out otherwise.
+func ParseOverpassJSON(r io.Reader, city string) ([]models.PointOfInterest, error) {
+	var result overpassResult
+	if err := json.NewDecoder(r).Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid Overpass JSON: %w", err)
+	}
+
+	nodes := make(map[int64][2]float64)
+	for _, element := range result.Elements {
+		if element.Type == "node" && element.Lat != nil && element.Lon != nil {
+			nodes[element.ID] = [2]float64{*element.Lat, *element.Lon}
+		}
+	}
+
+	var pois []models.PointOfInterest
+	seen := make(map[string]bool)
+	for _, element := range result.Elements {
+		tag := func(key string) string { return element.Tags[key] }
+		category, kind := POICategory(tag)
+		key := fmt.Sprintf("%s/%d", element.Type, element.ID)
+		if category == "" || seen[key] {
+			continue
+		}
+
+		var lat, lng float64
+		ok := false
+		switch {
+		case element.Lat != nil && element.Lon != nil:
+			lat, lng, ok = *element.Lat, *element.Lon, true
+		case element.Center != nil:
+			lat, lng, ok = element.Center.Lat, element.Center.Lon, true
+		case len(element.Geometry) > 0:
+			points := make([][2]float64, len(element.Geometry))
+			for i, point := range element.Geometry {
+				points[i] = [2]float64{point.Lat, point.Lon}
+			}
+			lat, lng, ok = centre(points)
+		default:
+			var points [][2]float64
+			for _, id := range element.Nodes {
+				if point, found := nodes[id]; found {
+					points = append(points, point)
+				}
+			}
+			lat, lng, ok = centre(points)
+		}
+		if !ok {
+			continue
+		}
+
+		seen[key] = true
+		pois = append(pois, models.PointOfInterest{
+			City: city, OSMType: element.Type, OSMID: element.ID, Category: category, Kind: kind,
+			Name: tag("name"), Latitude: lat, Longitude: lng,
+		})
+	}
+	return pois, nil
+}
+
+// centre returns the mean of points, counting the closing point of a ring once
+func centre(points [][2]float64) (lat, lng float64, ok bool) {
+	if len(points) > 1 && points[0] == points[len(points)-1] {
+		points = points[:len(points)-1]
+	}
+	if len(points) == 0 {
+		return 0, 0, false
+	}
+	for _, point := range points {
+		lat += point[0]
+		lng += point[1]
+	}
+	return lat / float64(len(points)), lng / float64(len(points)), true
+}
+
+// SavePOIs replaces the stored points of interest of city
+func SavePOIs(city string, pois []models.PointOfInterest) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("city = ?", city).Delete(&models.PointOfInterest{}).Error; err != nil {
+			return err
+		}
+		return tx.CreateInBatches(&pois, 500).Error
+	})
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pricemap-go/models"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const overpassJSON = `{
+  "version": 0.6,
+  "elements": [
+    {"type": "node", "id": 1, "lat": 55.7558, "lon": 37.6173, "tags": {"shop": "supermarket", "name": "Azbuka Vkusa"}},
+    {"type": "node", "id": 2, "lat": 55.7560, "lon": 37.6180, "tags": {"amenity": "bench"}},
+    {"type": "node", "id": 3, "lat": 55.7600, "lon": 37.6100},
+    {"type": "node", "id": 4, "lat": 55.7610, "lon": 37.6100},
+    {"type": "node", "id": 5, "lat": 55.7610, "lon": 37.6120},
+    {"type": "way", "id": 10, "center": {"lat": 55.7520, "lon": 37.6200}, "tags": {"leisure": "park", "name": "Zaryadye"}},
+    {"type": "way", "id": 11, "geometry": [{"lat": 55.75, "lon": 37.60}, {"lat": 55.76, "lon": 37.60}, {"lat": 55.76, "lon": 37.62}, {"lat": 55.75, "lon": 37.60}], "tags": {"amenity": "school"}},
+    {"type": "way", "id": 12, "nodes": [3, 4, 5, 3], "tags": {"amenity": "hospital"}},
+    {"type": "relation", "id": 20, "tags": {"leisure": "park"}},
+    {"type": "node", "id": 1, "lat": 55.7558, "lon": 37.6173, "tags": {"shop": "supermarket"}}
+  ]
+}`
+
+func TestPOICategory(t *testing.T) {
+	tests := []struct {
+		tags     map[string]string
+		category string
+		kind     string
+	}{
+		{map[string]string{"shop": "convenience"}, models.POICategoryGroceries, "convenience"},
+		{map[string]string{"shop": "clothes", "amenity": "cafe"}, models.POICategoryShops, "clothes"},
+		{map[string]string{"shop": "vacant", "amenity": "cafe"}, models.POICategoryRestaurants, "cafe"},
+		{map[string]string{"amenity": "pharmacy"}, models.POICategoryHealthcare, "pharmacy"},
+		{map[string]string{"amenity": "kindergarten"}, models.POICategorySchools, "kindergarten"},
+		{map[string]string{"amenity": "post_office"}, models.POICategoryServices, "post_office"},
+		{map[string]string{"leisure": "playground"}, models.POICategoryParks, "playground"},
+		{map[string]string{"amenity": "parking"}, "", ""},
+		{map[string]string{"highway": "bus_stop"}, "", ""},
+	}
+	for _, tt := range tests {
+		category, kind := POICategory(func(key string) string { return tt.tags[key] })
+		if category != tt.category || kind != tt.kind {
+			t.Errorf("POICategory(%v) = %q, %q, want %q, %q", tt.tags, category, kind, tt.category, tt.kind)
+		}
+	}
+}
+
+func TestParseOverpassJSON(t *testing.T) {
+	pois, err := ParseOverpassJSON(strings.NewReader(overpassJSON), "Moscow")
+	if err != nil {
+		t.Fatalf("ParseOverpassJSON() error = %v", err)
+	}
+	if len(pois) != 4 {
+		t.Fatalf("ParseOverpassJSON() returned %d points, want 4: %+v", len(pois), pois)
+	}
+
+	want := []struct {
+		osmType  string
+		id       int64
+		category string
+		lat, lng float64
+	}{
+		{"node", 1, models.POICategoryGroceries, 55.7558, 37.6173},
+		{"way", 10, models.POICategoryParks, 55.7520, 37.6200},
+		{"way", 11, models.POICategorySchools, 55.7567, 37.6067}, // centre of the ring, closing point once
+		{"way", 12, models.POICategoryHealthcare, 55.7607, 37.6107},
+	}
+	for i, w := range want {
+		got := pois[i]
+		if got.OSMType != w.osmType || got.OSMID != w.id || got.Category != w.category || got.City != "Moscow" ||
+			!approx(got.Latitude, w.lat) || !approx(got.Longitude, w.lng) {
+			t.Errorf("pois[%d] = %+v, want %s/%d %s at %v, %v", i, got, w.osmType, w.id, w.category, w.lat, w.lng)
+		}
+	}
+	if pois[0].Name != "Azbuka Vkusa" || pois[0].Kind != "supermarket" {
+		t.Errorf("pois[0] name and kind = %q, %q", pois[0].Name, pois[0].Kind)
+	}
+
+	if _, err := ParseOverpassJSON(strings.NewReader("<osm/>"), "Moscow"); err == nil {
+		t.Error("ParseOverpassJSON() should reject XML")
+	}
+}
+
+func approx(a, b float64) bool {
+	d := a - b
+	return d > -0.0001 && d < 0.0001
+}
+
+// pbfBlock frames a file block of an .osm.pbf file, zlib compressed
+func pbfBlock(t *testing.T, blockType string, data []byte) []byte {
+	t.Helper()
+	var compressed bytes.Buffer
+	w := zlib.NewWriter(&compressed)
+	w.Write(data)
+	w.Close()
+
+	var blob []byte
+	blob = protowire.AppendTag(blob, 2, protowire.VarintType) // raw_size
+	blob = protowire.AppendVarint(blob, uint64(len(data)))
+	blob = protowire.AppendTag(blob, 3, protowire.BytesType) // zlib_data
+	blob = protowire.AppendBytes(blob, compressed.Bytes())
+
+	var header []byte
+	header = protowire.AppendTag(header, 1, protowire.BytesType) // type
+	header = protowire.AppendString(header, blockType)
+	header = protowire.AppendTag(header, 3, protowire.VarintType) // datasize
+	header = protowire.AppendVarint(header, uint64(len(blob)))
+
+	block := binary.BigEndian.AppendUint32(nil, uint32(len(header)))
+	block = append(block, header...)
+	return append(block, blob...)
+}
+
+func packed(values []int64, zigzag bool) []byte {
+	var b []byte
+	for _, v := range values {
+		if zigzag {
+			b = protowire.AppendVarint(b, protowire.EncodeZigZag(v))
+		} else {
+			b = protowire.AppendVarint(b, uint64(v))
+		}
+	}
+	return b
+}
+
+func deltas(values []int64) []int64 {
+	out := make([]int64, len(values))
+	prev := int64(0)
+	for i, v := range values {
+		out[i] = v - prev
+		prev = v
+	}
+	return out
+}
+
+// osmPBF encodes a tiny extract: a tagged café node, two untagged nodes and
+// a park way over them and the café, and an untagged way
+func osmPBF(t *testing.T) []byte {
+	t.Helper()
+	table := []string{"", "amenity", "cafe", "name", "Coffeemania", "leisure", "park", "highway", "service"}
+
+	var headerBlock []byte
+	headerBlock = protowire.AppendTag(headerBlock, 4, protowire.BytesType) // required_features
+	headerBlock = protowire.AppendString(headerBlock, "OsmSchema-V0.6")
+	headerBlock = protowire.AppendTag(headerBlock, 4, protowire.BytesType)
+	headerBlock = protowire.AppendString(headerBlock, "DenseNodes")
+
+	ids := []int64{1, 2, 3}
+	lats := []int64{557558000, 557568000, 557568000} // 1e-7 degrees, granularity 100
+	lons := []int64{376173000, 376173000, 376193000}
+	keyVals := []int64{1, 2, 3, 4, 0, 0, 0} // café tags, then no tags twice
+
+	var dense []byte
+	dense = protowire.AppendTag(dense, 1, protowire.BytesType)
+	dense = protowire.AppendBytes(dense, packed(deltas(ids), true))
+	dense = protowire.AppendTag(dense, 8, protowire.BytesType)
+	dense = protowire.AppendBytes(dense, packed(deltas(lats), true))
+	dense = protowire.AppendTag(dense, 9, protowire.BytesType)
+	dense = protowire.AppendBytes(dense, packed(deltas(lons), true))
+	dense = protowire.AppendTag(dense, 10, protowire.BytesType)
+	dense = protowire.AppendBytes(dense, packed(keyVals, false))
+
+	way := func(id int64, keys, vals, refs []int64) []byte {
+		var b []byte
+		b = protowire.AppendTag(b, 1, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(id))
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendBytes(b, packed(keys, false))
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendBytes(b, packed(vals, false))
+		b = protowire.AppendTag(b, 8, protowire.BytesType)
+		return protowire.AppendBytes(b, packed(deltas(refs), true))
+	}
+
+	var nodesGroup, waysGroup []byte
+	nodesGroup = protowire.AppendTag(nodesGroup, 2, protowire.BytesType) // dense
+	nodesGroup = protowire.AppendBytes(nodesGroup, dense)
+	waysGroup = protowire.AppendTag(waysGroup, 3, protowire.BytesType)
+	waysGroup = protowire.AppendBytes(waysGroup, way(10, []int64{5}, []int64{6}, []int64{1, 2, 3, 1}))
+	waysGroup = protowire.AppendTag(waysGroup, 3, protowire.BytesType)
+	waysGroup = protowire.AppendBytes(waysGroup, way(11, []int64{7}, []int64{8}, []int64{2, 3}))
+
+	var stringTable []byte
+	for _, s := range table {
+		stringTable = protowire.AppendTag(stringTable, 1, protowire.BytesType)
+		stringTable = protowire.AppendString(stringTable, s)
+	}
+	var primitiveBlock []byte
+	primitiveBlock = protowire.AppendTag(primitiveBlock, 1, protowire.BytesType)
+	primitiveBlock = protowire.AppendBytes(primitiveBlock, stringTable)
+	primitiveBlock = protowire.AppendTag(primitiveBlock, 2, protowire.BytesType)
+	primitiveBlock = protowire.AppendBytes(primitiveBlock, nodesGroup)
+	primitiveBlock = protowire.AppendTag(primitiveBlock, 2, protowire.BytesType)
+	primitiveBlock = protowire.AppendBytes(primitiveBlock, waysGroup)
+
+	return append(pbfBlock(t, "OSMHeader", headerBlock), pbfBlock(t, "OSMData", primitiveBlock)...)
+}
+
+func TestReadOSMPBF(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "moscow.osm.pbf")
+	if err := os.WriteFile(path, osmPBF(t), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	pois, err := ReadOSMPBF(context.Background(), path, "Moscow")
+	if err != nil {
+		t.Fatalf("ReadOSMPBF() error = %v", err)
+	}
+	if len(pois) != 2 {
+		t.Fatalf("ReadOSMPBF() returned %d points, want 2: %+v", len(pois), pois)
+	}
+
+	cafe := pois[0]
+	if cafe.OSMType != "node" || cafe.OSMID != 1 || cafe.Category != models.POICategoryRestaurants || cafe.Name != "Coffeemania" ||
+		!approx(cafe.Latitude, 55.7558) || !approx(cafe.Longitude, 37.6173) {
+		t.Errorf("cafe = %+v", cafe)
+	}
+
+	// At the centre of its three distinct nodes
+	park := pois[1]
+	if park.OSMType != "way" || park.OSMID != 10 || park.Category != models.POICategoryParks ||
+		!approx(park.Latitude, 55.7564667) || !approx(park.Longitude, 37.6179667) {
+		t.Errorf("park = %+v", park)
+	}
+
+	if _, err := ReadOSMPBF(context.Background(), filepath.Join(t.TempDir(), "missing.osm.pbf"), "Moscow"); err == nil {
+		t.Error("ReadOSMPBF() should fail on a missing file")
+	}
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"pricemap-go/database"
+	"pricemap-go/models"
+	"pricemap-go/parsers"
+)
+
+// startScrapeRun journals a run as running, so a crashed run stays visible
+func startScrapeRun(run *models.ScrapeRun) {
+	if err := database.DB.Create(run).Error; err != nil {
+		log.Printf("Error recording scrape run of %s: %v", run.Source, err)
+	}
+}
+
+// finishScrapeRun records the outcome of a run. Errors from the run itself come
+// before the HTTP errors its requests ran into.
+func finishScrapeRun(ctx context.Context, run *models.ScrapeRun, stats *parsers.FetchStats, runErr error) {
+	now := time.Now()
+	run.FinishedAt = &now
+	run.HTTPStatuses = stats.Statuses()
+	run.Status = scrapeRunStatus(ctx, runErr)
+	if runErr != nil {
+		run.Errors = append(models.StringArray{runErr.Error()}, run.Errors...)
+	}
+	run.Errors = append(run.Errors, stats.Errors()...)
+
+	// Save inserts the entry if journaling its start failed
+	if err := database.DB.Save(run).Error; err != nil {
+		log.Printf("Error recording scrape run of %s: %v", run.Source, err)
+	}
+}
+
+func scrapeRunStatus(ctx context.Context, runErr error) string {
+	switch {
+	case errors.Is(runErr, context.Canceled), errors.Is(runErr, context.DeadlineExceeded), ctx.Err() != nil:
+		return models.ScrapeRunCancelled
+	case runErr != nil:
+		return models.ScrapeRunFailed
+	default:
+		return models.ScrapeRunSucceeded
+	}
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"pricemap-go/models"
+)
+
+func TestScrapeRunStatus(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want string
+	}{
+		{"success", context.Background(), nil, models.ScrapeRunSucceeded},
+		{"parse error", context.Background(), errors.New("blocked"), models.ScrapeRunFailed},
+		{"wrapped cancellation", context.Background(), fmt.Errorf("failed to parse: %w", context.Canceled), models.ScrapeRunCancelled},
+		{"timeout", context.Background(), context.DeadlineExceeded, models.ScrapeRunCancelled},
+		{"cancelled during enrichment", cancelled, nil, models.ScrapeRunCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scrapeRunStatus(tt.ctx, tt.err); got != tt.want {
+				t.Errorf("scrapeRunStatus() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
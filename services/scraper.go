@@ -3,7 +3,10 @@ package services
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"pricemap-go/config"
 	"pricemap-go/database"
 	"pricemap-go/models"
 	"pricemap-go/parsers"
@@ -12,41 +15,126 @@ import (
 )
 
 type ScraperService struct {
-	parsers        []parsers.Parser
+	options        ScrapeOptions
+	dryRun         *DryRunWriter
 	factorsService *FactorsService
+	dedupService   *DedupService
 	metricsService *MetricsService
 	cacheService   *CacheService
 }
 
+// ScrapeOptions narrows what a scraper run covers
+type ScrapeOptions struct {
+	Sources []string // source names; empty means every enabled source
+	Cities  []string // limit sources to these cities; empty means all
+	DryRun  bool     // validate and write properties to Output instead of the database
+
+	// Output receives dry run properties as NDJSON; defaults to stdout
+	Output io.Writer
+}
+
 func NewScraperService() *ScraperService {
-	return &ScraperService{
-		parsers: []parsers.Parser{
-			// Open Data Sources (most reliable, no blocking)
-			parsers.NewNYCOpenDataParser(),    // NYC Open Data - Property Sales
-			parsers.NewLondonOpenDataParser(), // London Data Store - House Prices
-			parsers.NewBerlinOpenDataParser(), // Berlin Open Data - Real Estate
-			parsers.NewParisOpenDataParser(),  // Paris Open Data - Rent Control
-			parsers.NewTokyoOpenDataParser(),  // Tokyo Open Data - Property Prices
-			parsers.NewSydneyOpenDataParser(), // Sydney Open Data - Property Sales
-			parsers.NewMoscowOpenDataParser(), // Moscow Open Data - Real Estate
-
-			// Commercial sites (may have blocking)
-			parsers.NewCianParser(),      // Russia - 30+ cities, sale & rent
-			parsers.NewRightmoveParser(), // UK - 25+ cities, sale & rent
-			parsers.NewZillowParser(),    // USA - 30+ cities, sale & rent
-			parsers.NewIdealistaParser(), // Spain - 20+ cities, sale & rent
-		},
+	return NewScraperServiceWithOptions(ScrapeOptions{})
+}
+
+// NewScraperServiceWithOptions creates a scraper limited to the selected sources and cities
+func NewScraperServiceWithOptions(options ScrapeOptions) *ScraperService {
+	ss := &ScraperService{
+		options:        options,
 		factorsService: NewFactorsService(),
+		dedupService:   NewDedupService(),
 		metricsService: NewMetricsService(),
 		cacheService:   NewCacheService(1 * time.Hour), // 1 hour TTL
 	}
+
+	if options.DryRun {
+		output := options.Output
+		if output == nil {
+			output = os.Stdout
+		}
+		ss.dryRun = NewDryRunWriter(output)
+	}
+
+	return ss
+}
+
+// DryRunSummary returns the validation summary of a dry run so far
+func (ss *ScraperService) DryRunSummary() DryRunSummary {
+	if ss.dryRun == nil {
+		return DryRunSummary{}
+	}
+	return ss.dryRun.Summary()
+}
+
+// SelectedSources returns the registered parsers merged with the site definitions
+// currently on disk, narrowed to the run's options. A definition replaces the
+// registered parser with the same name, so selector fixes take effect on the
+// next run without a code release. Sources listed in DISABLED_SOURCES are
+// skipped unless selected explicitly.
+func (ss *ScraperService) SelectedSources() ([]parsers.SourceInfo, error) {
+	sources, err := parsers.Sources(config.AppConfig.ParserDefinitionsDir)
+	if err != nil {
+		log.Printf("Error loading parser definitions, using built-in parsers only: %v", err)
+	}
+
+	if len(ss.options.Sources) > 0 {
+		return parsers.Select(sources, ss.options.Sources)
+	}
+
+	enabled := make([]parsers.SourceInfo, 0, len(sources))
+	for _, info := range sources {
+		if !config.AppConfig.SourceEnabled(info.Name) {
+			log.Printf("Source %s is disabled", info.Name)
+			continue
+		}
+		enabled = append(enabled, info)
+	}
+	return enabled, nil
+}
+
+// activeParsers creates a parser for every selected source that covers one of
+// the selected cities
+func (ss *ScraperService) activeParsers() ([]parsers.Parser, error) {
+	sources, err := ss.SelectedSources()
+	if err != nil {
+		return nil, err
+	}
+
+	active := make([]parsers.Parser, 0, len(sources))
+	for _, info := range sources {
+		cities := info.MatchCities(ss.options.Cities)
+		if len(cities) == 0 {
+			continue
+		}
+
+		parser := info.New()
+		if len(ss.options.Cities) > 0 {
+			if cp, ok := parser.(parsers.CityParser); ok {
+				cp.SetCities(cities)
+			}
+		}
+		if info.Definition {
+			log.Printf("Using parser definition %s", info.Name)
+		}
+		active = append(active, parser)
+	}
+
+	if len(active) == 0 {
+		return nil, fmt.Errorf("no sources match the selection")
+	}
+	return active, nil
 }
 
 // ScrapeAll starts parsing all sources sequentially
 func (ss *ScraperService) ScrapeAll(ctx context.Context) error {
 	log.Println("Starting scraping process...")
 
-	for _, parser := range ss.parsers {
+	active, err := ss.activeParsers()
+	if err != nil {
+		return err
+	}
+
+	for _, parser := range active {
 		if err := ss.scrapeSource(ctx, parser); err != nil {
 			log.Printf("Error scraping %s: %v", parser.Name(), err)
 			continue
@@ -61,15 +149,20 @@ func (ss *ScraperService) ScrapeAll(ctx context.Context) error {
 func (ss *ScraperService) ScrapeAllConcurrent(ctx context.Context, workers int) error {
 	log.Printf("Starting concurrent scraping with %d workers...", workers)
 
+	active, err := ss.activeParsers()
+	if err != nil {
+		return err
+	}
+
 	// Channel for parser jobs
-	jobs := make(chan parsers.Parser, len(ss.parsers))
+	jobs := make(chan parsers.Parser, len(active))
 
 	// Channel for results
 	type result struct {
 		parser string
 		err    error
 	}
-	results := make(chan result, len(ss.parsers))
+	results := make(chan result, len(active))
 
 	// Start worker pool
 	var wg sync.WaitGroup
@@ -98,7 +191,7 @@ func (ss *ScraperService) ScrapeAllConcurrent(ctx context.Context, workers int)
 
 	// Send jobs to workers
 	go func() {
-		for _, parser := range ss.parsers {
+		for _, parser := range active {
 			jobs <- parser
 		}
 		close(jobs)
@@ -128,10 +221,26 @@ func (ss *ScraperService) ScrapeAllConcurrent(ctx context.Context, workers int)
 	return nil
 }
 
-func (ss *ScraperService) scrapeSource(ctx context.Context, parser parsers.Parser) error {
+func (ss *ScraperService) scrapeSource(ctx context.Context, parser parsers.Parser) (err error) {
 	startTime := time.Now()
 	log.Printf("Scraping %s...", parser.Name())
 
+	// Count the HTTP outcomes of this source's requests
+	stats := parsers.NewFetchStats()
+	ctx = parsers.WithFetchStats(ctx, stats)
+	// and which cities and deal types it listed to the last page
+	coverage := parsers.NewCoverage()
+	ctx = parsers.WithCoverage(ctx, coverage)
+
+	// Journal the run; dry runs stay off the database
+	run := &models.ScrapeRun{Source: parser.Name(), StartedAt: startTime, Status: models.ScrapeRunRunning}
+	if ss.dryRun == nil {
+		startScrapeRun(run)
+		defer func() {
+			finishScrapeRun(ctx, run, stats, err)
+		}()
+	}
+
 	var savedCount, errorCount int64
 
 	properties, err := parser.Parse(ctx)
@@ -140,19 +249,63 @@ func (ss *ScraperService) scrapeSource(ctx context.Context, parser parsers.Parse
 		ss.metricsService.RecordParserRun(parser.Name(), 0, 0, errorCount, time.Since(startTime))
 		return fmt.Errorf("failed to parse %s: %w", parser.Name(), err)
 	}
+	run.Parsed = len(properties)
 
 	log.Printf("Found %d properties from %s", len(properties), parser.Name())
 
+	// A dry run only reports what would be saved
+	if ss.dryRun != nil {
+		if err := ss.dryRun.Write(properties); err != nil {
+			return err
+		}
+		ss.metricsService.RecordParserRun(parser.Name(), int64(len(properties)), 0, 0, time.Since(startTime))
+		return nil
+	}
+
 	// Batch save properties (much faster than one-by-one)
 	if len(properties) > 0 {
 		saved, errors := ss.batchSaveProperties(properties)
 		savedCount = int64(saved)
 		errorCount = int64(errors)
+		run.Saved, run.Failed = saved, errors
+		if errors > 0 {
+			run.Errors = append(run.Errors, fmt.Sprintf("failed to save %d properties", errors))
+		}
+
+		// Link listings that describe the same property on other sources
+		if matched := ss.dedupService.Deduplicate(properties); matched > 0 {
+			log.Printf("Matched %d properties from %s to listings on other sources", matched, parser.Name())
+		}
 
 		// Calculate factors for saved properties (async to not block)
 		go ss.calculateFactorsAsync(properties)
 	}
 
+	// Listings that disappeared from a complete run have been removed from the site.
+	// A run limited to some cities does not see the others' listings.
+	if lp, ok := parser.(parsers.ListingParser); ok && lp.TracksListings() && len(ss.options.Cities) == 0 {
+		delisted, err := ss.markDelisted(parser.Name(), properties, coverage)
+		run.Delisted = delisted
+		if err != nil {
+			log.Printf("Error detecting delisted properties for %s: %v", parser.Name(), err)
+			run.Errors = append(run.Errors, fmt.Sprintf("delisting: %v", err))
+		} else if delisted > 0 {
+			log.Printf("Marked %d properties from %s as delisted", delisted, parser.Name())
+		}
+	}
+
+	// Visit the pages of new and changed listings for details the cards lack
+	if dp, ok := parser.(parsers.DetailParser); ok && dp.HasDetailPages() && config.AppConfig.DetailEnrichment {
+		enriched, err := ss.enrichDetails(ctx, dp)
+		run.Enriched = enriched
+		if err != nil {
+			log.Printf("Error enriching %s listings from detail pages: %v", parser.Name(), err)
+			run.Errors = append(run.Errors, fmt.Sprintf("detail pages: %v", err))
+		} else if enriched > 0 {
+			log.Printf("Enriched %d %s listings from detail pages", enriched, parser.Name())
+		}
+	}
+
 	// Record metrics
 	ss.metricsService.RecordParserRun(parser.Name(), int64(len(properties)), savedCount, errorCount, time.Since(startTime))
 
@@ -171,6 +324,9 @@ func (ss *ScraperService) batchSaveProperties(properties []models.Property) (sav
 
 		batch := properties[i:end]
 
+		// Remember which listings are new or changed so the price timeline can be extended
+		changed := make([]bool, len(batch))
+
 		// Check for duplicates and update existing
 		for j := range batch {
 			var existing models.Property
@@ -181,6 +337,17 @@ func (ss *ScraperService) batchSaveProperties(properties []models.Property) (sav
 				batch[j].ID = existing.ID
 				batch[j].CreatedAt = existing.CreatedAt
 				batch[j].UpdatedAt = time.Now()
+				batch[j].FirstSeenAt = existing.FirstSeenAt
+				batch[j].CanonicalID = existing.CanonicalID
+				batch[j].MatchConfidence = existing.MatchConfidence
+				if batch[j].FirstSeenAt.IsZero() {
+					batch[j].FirstSeenAt = existing.CreatedAt
+				}
+				changed[j] = priceChanged(&existing, &batch[j])
+				keepStoredDetails(&existing, &batch[j], changed[j])
+			} else {
+				batch[j].FirstSeenAt = batch[j].ScrapedAt
+				changed[j] = true
 			}
 		}
 
@@ -188,8 +355,19 @@ func (ss *ScraperService) batchSaveProperties(properties []models.Property) (sav
 		if err := database.DB.Save(&batch).Error; err != nil {
 			log.Printf("Error batch saving properties: %v", err)
 			errors += len(batch)
-		} else {
-			saved += len(batch)
+			continue
+		}
+		saved += len(batch)
+
+		// Record price observations for new and changed listings
+		var observations []models.PriceObservation
+		for j := range batch {
+			if changed[j] {
+				observations = append(observations, newPriceObservation(&batch[j]))
+			}
+		}
+		if err := savePriceObservations(observations); err != nil {
+			log.Printf("Error saving price history: %v", err)
 		}
 	}
 
@@ -198,9 +376,10 @@ func (ss *ScraperService) batchSaveProperties(properties []models.Property) (sav
 
 // calculateFactorsAsync calculates factors for properties asynchronously
 func (ss *ScraperService) calculateFactorsAsync(properties []models.Property) {
+	indexes := NewSpatialIndexes()
 	for i := range properties {
 		if properties[i].ID > 0 {
-			factors, err := ss.factorsService.CalculateFactors(&properties[i])
+			factors, err := ss.factorsService.CalculateFactorsWith(&properties[i], indexes)
 			if err != nil {
 				continue
 			}
@@ -222,9 +401,29 @@ func (ss *ScraperService) saveProperty(property *models.Property) error {
 		property.ID = existing.ID
 		property.CreatedAt = existing.CreatedAt
 		property.UpdatedAt = time.Now()
-		return database.DB.Save(property).Error
+		property.FirstSeenAt = existing.FirstSeenAt
+		property.CanonicalID = existing.CanonicalID
+		property.MatchConfidence = existing.MatchConfidence
+		if property.FirstSeenAt.IsZero() {
+			property.FirstSeenAt = existing.CreatedAt
+		}
+		changed := priceChanged(&existing, property)
+		keepStoredDetails(&existing, property, changed)
+		if err := database.DB.Save(property).Error; err != nil {
+			return err
+		}
+		if !changed {
+			return nil
+		}
+		return savePriceObservations([]models.PriceObservation{newPriceObservation(property)})
 	}
 
 	// Create new
-	return database.DB.Create(property).Error
+	if property.FirstSeenAt.IsZero() {
+		property.FirstSeenAt = property.ScrapedAt
+	}
+	if err := database.DB.Create(property).Error; err != nil {
+		return err
+	}
+	return savePriceObservations([]models.PriceObservation{newPriceObservation(property)})
 }
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"strings"
+	"sync"
+)
+
+// SpatialIndexes builds the spatial indexes factor scoring queries once per
+// city and keeps them for a scoring batch, so scoring every property of a
+// city loads and indexes the city's data once. It is safe for concurrent use.
+type SpatialIndexes struct {
+	mu      sync.Mutex
+	transit map[string]*TransitIndex
+	pois    map[string]POIIndex
+	crime   map[string]*CrimeIndex
+}
+
+// NewSpatialIndexes creates the indexes of a scoring batch
+func NewSpatialIndexes() *SpatialIndexes {
+	return &SpatialIndexes{
+		transit: make(map[string]*TransitIndex),
+		pois:    make(map[string]POIIndex),
+		crime:   make(map[string]*CrimeIndex),
+	}
+}
+
+// Transit returns the index of the transit stops of city, loading them on
+// first use
+func (si *SpatialIndexes) Transit(city string) (*TransitIndex, error) {
+	si.mu.Lock()
+	defer si.mu.Unlock()
+
+	key := strings.TrimSpace(city)
+	if index, ok := si.transit[key]; ok {
+		return index, nil
+	}
+	index, err := LoadTransitIndex(key)
+	if err != nil {
+		return nil, err
+	}
+	si.transit[key] = index
+	return index, nil
+}
+
+// POIs returns the index of the points of interest of city, loading them on
+// first use
+func (si *SpatialIndexes) POIs(city string) (POIIndex, error) {
+	si.mu.Lock()
+	defer si.mu.Unlock()
+
+	key := strings.TrimSpace(city)
+	if index, ok := si.pois[key]; ok {
+		return index, nil
+	}
+	index, err := LoadPOIIndex(key)
+	if err != nil {
+		return nil, err
+	}
+	si.pois[key] = index
+	return index, nil
+}
+
+// Crime returns the index of the recent crime incidents of city, loading them
+// on first use
+func (si *SpatialIndexes) Crime(city string) (*CrimeIndex, error) {
+	si.mu.Lock()
+	defer si.mu.Unlock()
+
+	key := strings.TrimSpace(city)
+	if index, ok := si.crime[key]; ok {
+		return index, nil
+	}
+	index, err := LoadCrimeIndex(key)
+	if err != nil {
+		return nil, err
+	}
+	si.crime[key] = index
+	return index, nil
+}
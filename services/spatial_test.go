@@ -0,0 +1,38 @@
+package services
+
+import "testing"
+
+func TestSpatialIndexes_Transit(t *testing.T) {
+	indexes := NewSpatialIndexes()
+
+	// Without a database every city has an empty index, built once
+	first, err := indexes.Transit("Moscow")
+	if err != nil {
+		t.Fatalf("Transit() error = %v", err)
+	}
+	if first.Len() != 0 {
+		t.Errorf("Transit() Len = %d, want 0", first.Len())
+	}
+	if again, _ := indexes.Transit(" Moscow "); again != first {
+		t.Error("Transit() should reuse the index of a city within a batch")
+	}
+	if other, _ := indexes.Transit("London"); other == first {
+		t.Error("Transit() should index each city separately")
+	}
+}
+
+func TestSpatialIndexes_POIs(t *testing.T) {
+	indexes := NewSpatialIndexes()
+
+	first, err := indexes.POIs("Moscow")
+	if err != nil {
+		t.Fatalf("POIs() error = %v", err)
+	}
+	if len(first) != 0 {
+		t.Errorf("POIs() = %d categories, want 0", len(first))
+	}
+	first["marker"] = nil
+	if again, _ := indexes.POIs("Moscow"); len(again) != 1 {
+		t.Error("POIs() should reuse the index of a city within a batch")
+	}
+}
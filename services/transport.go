@@ -1,85 +1,191 @@
 package services
 
 import (
-	"encoding/csv"
 	"fmt"
-	"io"
 	"math"
+	"pricemap-go/database"
 	"pricemap-go/models"
+	"pricemap-go/utils"
 )
 
+const (
+	walkingDetour  = 1.3    // Streets make walks about this much longer than the straight line
+	walkingSpeed   = 80.0   // Metres a minute, about 5 km/h
+	walkingRadius  = 1000.0 // Metres of walk within which stops count towards the score
+	maxScoredStops = 10     // Nearest stops counted, so one big interchange does not swamp the score
+	serviceHours   = 18.0   // Hours of service a day, to turn departures per day into per hour
+
+	// TransitSearchRadius is how far from a property the nearest stop is
+	// looked for when none is within walking radius, in kilometres, reaching
+	// past the farthest access band
+	TransitSearchRadius = 2.5
+
+	// unknownDeparturesPerDay stands in for stops without known departures:
+	// 4 an hour
+	unknownDeparturesPerDay = 72.0
+)
+
+// transitModeScores are the points the best mode within walking radius gives
+var transitModeScores = map[string]float64{
+	models.TransitModeMetro: 30,
+	models.TransitModeRail:  25,
+	models.TransitModeTram:  20,
+	models.TransitModeBus:   15,
+}
+
 type TransportService struct{}
 
 func NewTransportService() *TransportService {
 	return &TransportService{}
 }
 
+// TransitIndex is a spatial index of transit stops
+type TransitIndex = utils.SpatialIndex[TransitStop]
+
+// NewTransitIndex indexes transit stops
+func NewTransitIndex(stops []TransitStop) *TransitIndex {
+	index := utils.NewSpatialIndex[TransitStop](utils.DefaultSpatialCellKm)
+	for _, stop := range stops {
+		index.Insert(stop.Latitude, stop.Longitude, stop)
+	}
+	return index
+}
+
+// LoadTransitIndex indexes the stored transit stops of a city
+func LoadTransitIndex(city string) (*TransitIndex, error) {
+	if database.DB == nil {
+		return NewTransitIndex(nil), nil // No transit data without a database
+	}
+
+	var stored []models.TransitStop
+	if err := database.DB.Where("city = ?", city).Find(&stored).Error; err != nil {
+		return nil, fmt.Errorf("failed to load transit stops of %s: %w", city, err)
+	}
+
+	stops := make([]TransitStop, 0, len(stored))
+	for _, stop := range stored {
+		modes := stop.ModeList()
+		stopType := ""
+		if len(modes) > 0 {
+			stopType = modes[0]
+		}
+		stops = append(stops, TransitStop{
+			Latitude:         stop.Latitude,
+			Longitude:        stop.Longitude,
+			Type:             stopType,
+			Name:             stop.Name,
+			StopID:           stop.StopID,
+			Modes:            modes,
+			DeparturesPerDay: stop.DeparturesPerDay,
+		})
+	}
+	return NewTransitIndex(stops), nil
+}
+
+// TransportAssessment is a transport score with the stops it is based on,
+// stored as the TransportData of the property factors
+type TransportAssessment struct {
+	Score             float64    `json:"score"`
+	Stops             []UsedStop `json:"stops"`               // Nearest first
+	DeparturesPerHour float64    `json:"departures_per_hour"` // Over the stops within walking radius
+	Note              string     `json:"note,omitempty"`
+}
+
+// UsedStop is a stop a transport score counted
+type UsedStop struct {
+	StopID           string   `json:"stop_id,omitempty"`
+	Name             string   `json:"name"`
+	Modes            []string `json:"modes"`
+	WalkingDistance  int      `json:"walking_distance_m"`
+	WalkingMinutes   int      `json:"walking_minutes"`
+	DeparturesPerDay float64  `json:"departures_per_day"`
+}
+
 // CalculateTransportScore calculates transportation accessibility score
 func (ts *TransportService) CalculateTransportScore(property *models.Property, transitStops []TransitStop) float64 {
-	if len(transitStops) == 0 {
-		return 50.0 // Default score if no transit data
+	return ts.AssessTransport(property, NewTransitIndex(transitStops)).Score
+}
+
+// AssessTransport scores transportation accessibility (0-100) from the
+// transit stops around a property:
+// - Walking distance to the nearest stop (0-40 points)
+// - Best mode within walking radius, metro > rail > tram > bus (0-30 points)
+// - Departures per hour within walking radius (0-30 points, 60 an hour for all)
+// Walking distances are straight-line distances stretched by walkingDetour.
+func (ts *TransportService) AssessTransport(property *models.Property, stops *TransitIndex) TransportAssessment {
+	if stops.Len() == 0 {
+		// Default score if no transit data
+		return TransportAssessment{Score: 50.0, Stops: []UsedStop{}, Note: "no transit stops known for the city"}
 	}
-	
-	// Find nearest transit stops
-	minDistance := math.MaxFloat64
-	metroCount := 0
-	busCount := 0
-	
-	for _, stop := range transitStops {
-		distance := ts.haversineDistance(
-			property.Latitude, property.Longitude,
-			stop.Latitude, stop.Longitude,
-		)
-		
-		if distance < minDistance {
-			minDistance = distance
+
+	// Only the nearest one counts when none is within walking radius
+	nearby := stops.Nearest(property.Latitude, property.Longitude, maxScoredStops, walkingRadius/walkingDetour/1000)
+	withinWalk := len(nearby) > 0
+	if !withinWalk {
+		nearby = stops.Nearest(property.Latitude, property.Longitude, 1, TransitSearchRadius)
+	}
+	if len(nearby) == 0 {
+		return TransportAssessment{Score: 0, Stops: []UsedStop{}, Note: fmt.Sprintf("no transit stop within %g km", TransitSearchRadius)}
+	}
+
+	used := make([]UsedStop, 0, len(nearby))
+	for _, neighbor := range nearby {
+		stop := neighbor.Value
+		distance := neighbor.Distance * 1000 * walkingDetour
+
+		modes := stop.Modes
+		if len(modes) == 0 && stop.Type != "" {
+			modes = []string{stop.Type}
 		}
-		
-		// Count stops within 1km
-		if distance <= 1.0 {
-			if stop.Type == "metro" || stop.Type == "subway" {
-				metroCount++
-			} else {
-				busCount++
-			}
+		departures := stop.DeparturesPerDay
+		if departures == 0 {
+			departures = unknownDeparturesPerDay
 		}
+
+		used = append(used, UsedStop{
+			StopID:           stop.StopID,
+			Name:             stop.Name,
+			Modes:            modes,
+			WalkingDistance:  int(math.Round(distance)),
+			WalkingMinutes:   int(math.Ceil(distance / walkingSpeed)),
+			DeparturesPerDay: departures,
+		})
 	}
-	
-	// Calculate score based on:
-	// - Distance to nearest stop (closer = better)
-	// - Number of stops within 1km
-	// - Type of transit (metro > bus)
-	
+
 	score := 0.0
-	
+
 	// Distance score (0-40 points)
-	if minDistance <= 0.5 {
+	nearest := used[0].WalkingDistance
+	if nearest <= 400 {
 		score += 40 // Very close
-	} else if minDistance <= 1.0 {
+	} else if nearest <= 800 {
 		score += 30
-	} else if minDistance <= 2.0 {
+	} else if nearest <= 1500 {
 		score += 20
-	} else if minDistance <= 5.0 {
+	} else if nearest <= 3000 {
 		score += 10
 	}
-	
-	// Metro availability (0-40 points)
-	if metroCount > 0 {
-		score += math.Min(float64(metroCount)*10, 40)
-	} else if busCount > 0 {
-		// Bus availability (0-20 points)
-		score += math.Min(float64(busCount)*2, 20)
+
+	if !withinWalk {
+		return TransportAssessment{Score: score, Stops: used}
 	}
-	
-	// Additional points for multiple options (0-20 points)
-	totalStops := metroCount + busCount
-	if totalStops >= 5 {
-		score += 20
-	} else if totalStops >= 3 {
-		score += 10
+
+	// Mode score (0-30 points) and frequency score (0-30 points)
+	modeScore, departures := 0.0, 0.0
+	for _, stop := range used {
+		for _, mode := range stop.Modes {
+			modeScore = math.Max(modeScore, transitModeScores[mode])
+		}
+		departures += stop.DeparturesPerDay
+	}
+	perHour := departures / serviceHours
+	score += modeScore + math.Min(perHour/2, 30)
+
+	return TransportAssessment{
+		Score:             math.Round(math.Min(score, 100.0)*100) / 100,
+		Stops:             used,
+		DeparturesPerHour: math.Round(perHour*10) / 10,
 	}
-	
-	return math.Min(score, 100.0)
 }
 
 // TransitStop represents a public transit stop
@@ -88,94 +194,8 @@ type TransitStop struct {
 	Longitude float64
 	Type      string // "metro", "bus", "tram", etc.
 	Name      string
-}
 
-// ParseGTFSStops parses GTFS stops.txt file
-func (ts *TransportService) ParseGTFSStops(reader io.Reader) ([]TransitStop, error) {
-	var stops []TransitStop
-	
-	csvReader := csv.NewReader(reader)
-	records, err := csvReader.ReadAll()
-	if err != nil {
-		return nil, fmt.Errorf("failed to read CSV: %w", err)
-	}
-	
-	if len(records) == 0 {
-		return stops, nil
-	}
-	
-	// Find column indices
-	header := records[0]
-	latIdx := -1
-	lngIdx := -1
-	nameIdx := -1
-	typeIdx := -1
-	
-	for i, col := range header {
-		switch col {
-		case "stop_lat", "lat", "latitude":
-			latIdx = i
-		case "stop_lon", "lon", "lng", "longitude":
-			lngIdx = i
-		case "stop_name", "name":
-			nameIdx = i
-		case "location_type", "type":
-			typeIdx = i
-		}
-	}
-	
-	if latIdx == -1 || lngIdx == -1 {
-		return nil, fmt.Errorf("latitude or longitude column not found")
-	}
-	
-	// Parse rows
-	for i := 1; i < len(records); i++ {
-		record := records[i]
-		if len(record) <= latIdx || len(record) <= lngIdx {
-			continue
-		}
-		
-		var lat, lng float64
-		if _, err := fmt.Sscanf(record[latIdx], "%f", &lat); err != nil {
-			continue
-		}
-		if _, err := fmt.Sscanf(record[lngIdx], "%f", &lng); err != nil {
-			continue
-		}
-		
-		stop := TransitStop{
-			Latitude:  lat,
-			Longitude: lng,
-			Type:      "bus", // Default
-		}
-		
-		if nameIdx >= 0 && nameIdx < len(record) {
-			stop.Name = record[nameIdx]
-		}
-		
-		if typeIdx >= 0 && typeIdx < len(record) {
-			stop.Type = record[typeIdx]
-		}
-		
-		stops = append(stops, stop)
-	}
-	
-	return stops, nil
+	StopID           string   // GTFS stop_id, for imported stops
+	Modes            []string // All modes calling at the stop, best first; Type alone when empty
+	DeparturesPerDay float64  // 0 when unknown
 }
-
-// haversineDistance calculates distance between two points in kilometers
-func (ts *TransportService) haversineDistance(lat1, lon1, lat2, lon2 float64) float64 {
-	const R = 6371 // Earth radius in kilometers
-	
-	dLat := (lat2 - lat1) * math.Pi / 180
-	dLon := (lon2 - lon1) * math.Pi / 180
-	
-	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
-		math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*
-			math.Sin(dLon/2)*math.Sin(dLon/2)
-	
-	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
-	
-	return R * c
-}
-
@@ -70,58 +70,38 @@ func TestTransportService_CalculateTransportScore(t *testing.T) {
 	}
 }
 
-func TestTransportService_HaversineDistance(t *testing.T) {
+func TestTransportService_AssessTransport(t *testing.T) {
 	ts := NewTransportService()
+	property := &models.Property{Latitude: 55.7558, Longitude: 37.6173}
 
-	tests := []struct {
-		name     string
-		lat1     float64
-		lon1     float64
-		lat2     float64
-		lon2     float64
-		want     float64 // Approximate distance in km
-		tolerance float64
-	}{
-		{
-			name:      "same point",
-			lat1:      55.7558,
-			lon1:      37.6173,
-			lat2:      55.7558,
-			lon2:      37.6173,
-			want:      0.0,
-			tolerance: 0.1,
-		},
-		{
-			name:      "close points",
-			lat1:      55.7558,
-			lon1:      37.6173,
-			lat2:      55.7559,
-			lon2:      37.6174,
-			want:      0.1, // Very close
-			tolerance: 0.1,
-		},
-		{
-			name:      "Moscow to Saint Petersburg",
-			lat1:      55.7558,
-			lon1:      37.6173,
-			lat2:      59.9343,
-			lon2:      30.3351,
-			want:      635.0, // Approximate distance
-			tolerance: 50.0,
-		},
+	stops := []TransitStop{
+		{Latitude: 55.7600, Longitude: 37.6173, Name: "Far Bus", Modes: []string{"bus"}, DeparturesPerDay: 300}, // ~600 m walk
+		{Latitude: 55.7568, Longitude: 37.6173, Name: "Metro", StopID: "ST1", Modes: []string{"metro", "bus"}, DeparturesPerDay: 600},
+		{Latitude: 55.7700, Longitude: 37.6173, Name: "Outside", Modes: []string{"rail"}, DeparturesPerDay: 1000},
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := ts.haversineDistance(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
-			diff := got - tt.want
-			if diff < 0 {
-				diff = -diff
-			}
-			if diff > tt.tolerance {
-				t.Errorf("haversineDistance() = %v, want %v (tolerance %v)", got, tt.want, tt.tolerance)
-			}
-		})
+	got := ts.AssessTransport(property, NewTransitIndex(stops))
+	if len(got.Stops) != 2 {
+		t.Fatalf("AssessTransport() used %d stops, want 2: %+v", len(got.Stops), got.Stops)
+	}
+	if got.Stops[0].Name != "Metro" || got.Stops[0].StopID != "ST1" || got.Stops[1].Name != "Far Bus" {
+		t.Errorf("AssessTransport() stops = %+v, want nearest first", got.Stops)
+	}
+	// 111 m to the stop in a straight line, about 145 m with the 1.3 detour factor
+	if got.Stops[0].WalkingDistance < 140 || got.Stops[0].WalkingDistance > 150 || got.Stops[0].WalkingMinutes != 2 {
+		t.Errorf("AssessTransport() walk = %d m, %d min", got.Stops[0].WalkingDistance, got.Stops[0].WalkingMinutes)
+	}
+	if got.DeparturesPerHour != 50 {
+		t.Errorf("AssessTransport() DeparturesPerHour = %v, want 50", got.DeparturesPerHour)
+	}
+	// 40 for distance, 30 for metro, 25 for 50 departures an hour
+	if got.Score != 95 {
+		t.Errorf("AssessTransport() Score = %v, want 95", got.Score)
 	}
-}
 
+	// Nothing within walking radius: only the nearest stop, for distance
+	got = ts.AssessTransport(property, NewTransitIndex(stops[2:]))
+	if len(got.Stops) != 1 || got.Stops[0].Name != "Outside" || got.Score != 10 {
+		t.Errorf("AssessTransport() out of walking radius = %+v", got)
+	}
+}
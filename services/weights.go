@@ -0,0 +1,41 @@
+package services
+
+import (
+	"fmt"
+
+	"pricemap-go/database"
+	"pricemap-go/models"
+)
+
+// LoadWeightProfiles returns the stored weighting profiles by name. Without a
+// database the built-in ones are known.
+func LoadWeightProfiles() ([]models.WeightProfile, error) {
+	if database.DB == nil {
+		return append([]models.WeightProfile(nil), models.BuiltinWeightProfiles...), nil
+	}
+
+	var profiles []models.WeightProfile
+	if err := database.DB.Order("name").Find(&profiles).Error; err != nil {
+		return nil, fmt.Errorf("failed to load weight profiles: %w", err)
+	}
+	return profiles, nil
+}
+
+// FindWeightProfile returns the weighting profile of a name, or nil if there
+// is none. A stored profile whose weights do not sum to 1 is an error.
+func FindWeightProfile(name string) (*models.WeightProfile, error) {
+	profiles, err := LoadWeightProfiles()
+	if err != nil {
+		return nil, err
+	}
+	for i := range profiles {
+		if profiles[i].Name != name {
+			continue
+		}
+		if err := profiles[i].Validate(); err != nil {
+			return nil, fmt.Errorf("weight profile %s: %w", name, err)
+		}
+		return &profiles[i], nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,27 @@
+package services
+
+import (
+	"testing"
+
+	"pricemap-go/models"
+)
+
+func TestFindWeightProfile(t *testing.T) {
+	// Without a database the built-in profiles are known
+	profile, err := FindWeightProfile("retiree")
+	if err != nil {
+		t.Fatalf("FindWeightProfile() error = %v", err)
+	}
+	if profile == nil || profile.Education != 0 || profile.Infrastructure != 0.45 {
+		t.Errorf("FindWeightProfile(retiree) = %+v", profile)
+	}
+
+	profile, err = FindWeightProfile(models.DefaultWeightProfile)
+	if err != nil || profile == nil || profile.FactorWeights != models.DefaultFactorWeights {
+		t.Errorf("FindWeightProfile(default) = %+v, %v", profile, err)
+	}
+
+	if profile, err := FindWeightProfile("pensioner"); profile != nil || err != nil {
+		t.Errorf("FindWeightProfile(pensioner) = %+v, %v, want nil", profile, err)
+	}
+}
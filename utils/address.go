@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// addressStopWords are street-type tokens that vary between sources
+// ("ул." vs "улица", "St" vs "Street") and carry no identifying information
+var addressStopWords = map[string]bool{
+	// English
+	"street": true, "st": true, "road": true, "rd": true, "avenue": true, "ave": true,
+	"lane": true, "ln": true, "drive": true, "dr": true, "flat": true, "apt": true,
+	"apartment": true, "unit": true,
+	// Russian
+	"улица": true, "ул": true, "проспект": true, "пр": true, "просп": true, "дом": true,
+	"д": true, "кв": true, "корп": true, "к": true, "стр": true, "г": true,
+	// Spanish
+	"calle": true, "c": true, "avenida": true, "avda": true, "av": true, "piso": true,
+	// German / French
+	"straße": true, "strasse": true, "str": true, "rue": true,
+}
+
+// addressUnitMarkers introduce the number of a flat within its building
+var addressUnitMarkers = map[string]bool{
+	"flat": true, "apt": true, "apartment": true, "unit": true,
+	"кв": true, "квартира": true,
+	"wohnung": true, "whg": true, "appartement": true, "appt": true,
+}
+
+// NormalizeAddress lowercases an address, strips punctuation and street-type
+// words so the same location written by different sources compares equal
+func NormalizeAddress(address string) string {
+	return strings.Join(addressTokens(address), " ")
+}
+
+// AddressSimilarity returns the Jaccard similarity (0-1) of two addresses' tokens
+func AddressSimilarity(a, b string) float64 {
+	tokensA := addressTokens(a)
+	tokensB := addressTokens(b)
+	if len(tokensA) == 0 || len(tokensB) == 0 {
+		return 0
+	}
+
+	setA := make(map[string]bool, len(tokensA))
+	for _, t := range tokensA {
+		setA[t] = true
+	}
+
+	setB := make(map[string]bool, len(tokensB))
+	intersection := 0
+	for _, t := range tokensB {
+		if setB[t] {
+			continue
+		}
+		setB[t] = true
+		if setA[t] {
+			intersection++
+		}
+	}
+
+	union := len(setA) + len(setB) - intersection
+	return float64(intersection) / float64(union)
+}
+
+// AddressUnit returns the flat number of an address ("Flat 3", "кв. 12"), or
+// "" when the address only names the building. The marker and the number
+// must be in the same comma-separated part.
+func AddressUnit(address string) string {
+	for _, part := range strings.Split(address, ",") {
+		fields := addressFields(part)
+		for i := 0; i+1 < len(fields); i++ {
+			if addressUnitMarkers[fields[i]] && strings.ContainsFunc(fields[i+1], unicode.IsDigit) {
+				return fields[i+1]
+			}
+		}
+	}
+	return ""
+}
+
+func addressFields(address string) []string {
+	return strings.FieldsFunc(strings.ToLower(address), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func addressTokens(address string) []string {
+	fields := addressFields(address)
+
+	tokens := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if addressStopWords[f] {
+			continue
+		}
+		tokens = append(tokens, f)
+	}
+	return tokens
+}
@@ -0,0 +1,62 @@
+package utils
+
+import "testing"
+
+func TestNormalizeAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		want    string
+	}{
+		{"english street type", "221B Baker Street, London", "221b baker london"},
+		{"abbreviation", "221b Baker St., London", "221b baker london"},
+		{"russian", "Москва, ул. Тверская, д. 7", "москва тверская 7"},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeAddress(tt.address); got != tt.want {
+				t.Errorf("NormalizeAddress(%q) = %q, want %q", tt.address, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddressSimilarity(t *testing.T) {
+	if got := AddressSimilarity("ул. Тверская, д. 7", "Тверская улица 7"); got != 1 {
+		t.Errorf("AddressSimilarity() for same address = %v, want 1", got)
+	}
+
+	if got := AddressSimilarity("Baker Street 221b", "Oxford Street 10"); got != 0 {
+		t.Errorf("AddressSimilarity() for different addresses = %v, want 0", got)
+	}
+
+	got := AddressSimilarity("Calle Mayor 5, Madrid", "Calle Mayor 7, Madrid")
+	if got <= 0 || got >= 1 {
+		t.Errorf("AddressSimilarity() for partial match = %v, want between 0 and 1", got)
+	}
+
+	if got := AddressSimilarity("", "Calle Mayor 5"); got != 0 {
+		t.Errorf("AddressSimilarity() with empty address = %v, want 0", got)
+	}
+}
+
+func TestAddressUnit(t *testing.T) {
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{"Flat 3B, 221 Baker Street, London", "3b"},
+		{"Москва, ул. Тверская, д. 7, кв. 12", "12"},
+		{"350 W 42nd St Apt 12F, New York", "12f"},
+		{"Москва, ул. Тверская, д. 7", ""},
+		{"Garden flat, 12 Elm Road", ""},
+	}
+
+	for _, tt := range tests {
+		if got := AddressUnit(tt.address); got != tt.want {
+			t.Errorf("AddressUnit(%q) = %q, want %q", tt.address, got, tt.want)
+		}
+	}
+}
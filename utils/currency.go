@@ -2,32 +2,117 @@ package utils
 
 import (
 	"errors"
+	"sort"
+	"time"
 	"pricemap-go/models"
 )
 
+// BaseCurrency is the currency rates are quoted against
+const BaseCurrency = "USD"
+
+// defaultRatesDate is the day the default rates were taken
+var defaultRatesDate = time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
 // CurrencyConverter handles currency conversion
 type CurrencyConverter struct {
-	rates map[string]float64 // Base currency: USD
+	rates   map[string]float64      // Base currency: USD
+	date    time.Time               // when the rates were current
+	history map[string][]datedRate // published rates per currency, by date
+}
+
+// datedRate is the rate of a currency published for a day
+type datedRate struct {
+	date time.Time
+	rate float64
 }
 
 func NewCurrencyConverter() *CurrencyConverter {
 	return &CurrencyConverter{
-		rates: getDefaultRates(),
+		rates:   getDefaultRates(),
+		date:    defaultRatesDate,
+		history: make(map[string][]datedRate),
 	}
 }
 
-// Convert converts price from one currency to another
+// AddRate records how many units of currency one USD bought on a day. A
+// second rate for the same day replaces the first.
+func (cc *CurrencyConverter) AddRate(currency string, date time.Time, rate float64) {
+	rates := cc.history[currency]
+	i := sort.Search(len(rates), func(i int) bool { return !rates[i].date.Before(date) })
+	switch {
+	case i < len(rates) && rates[i].date.Equal(date):
+		rates[i].rate = rate
+	default:
+		rates = append(rates, datedRate{})
+		copy(rates[i+1:], rates[i:])
+		rates[i] = datedRate{date, rate}
+	}
+	cc.history[currency] = rates
+}
+
+// Rate returns how many units of currency one USD buys today
+func (cc *CurrencyConverter) Rate(currency string) (float64, bool) {
+	rate, _, ok := cc.RateAt(currency, time.Now())
+	return rate, ok
+}
+
+// RateAt returns how many units of currency one USD bought at t, and the day
+// of that rate: the latest rate published on or before t, else the earliest
+// one published, else the built-in approximate rate
+func (cc *CurrencyConverter) RateAt(currency string, t time.Time) (float64, time.Time, bool) {
+	if currency == BaseCurrency {
+		return 1, t.UTC().Truncate(24 * time.Hour), true
+	}
+	if rates := cc.history[currency]; len(rates) > 0 {
+		i := sort.Search(len(rates), func(i int) bool { return rates[i].date.After(t) })
+		if i > 0 {
+			i--
+		}
+		return rates[i].rate, rates[i].date, true
+	}
+	rate, ok := cc.rates[currency]
+	return rate, cc.date, ok
+}
+
+// Currencies lists the currencies with a rate, sorted
+func (cc *CurrencyConverter) Currencies() []string {
+	currencies := make([]string, 0, len(cc.rates))
+	for currency := range cc.rates {
+		currencies = append(currencies, currency)
+	}
+	for currency := range cc.history {
+		if _, ok := cc.rates[currency]; !ok {
+			currencies = append(currencies, currency)
+		}
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+// DefaultRate returns the built-in approximate rate of currency, used when
+// no rate of it has been published
+func (cc *CurrencyConverter) DefaultRate(currency string) (float64, bool) {
+	rate, ok := cc.rates[currency]
+	return rate, ok
+}
+
+// Convert converts price from one currency to another at today's rates
 func (cc *CurrencyConverter) Convert(price float64, from, to string) (float64, error) {
+	return cc.ConvertAt(price, from, to, time.Now())
+}
+
+// ConvertAt converts price from one currency to another at the rates of t
+func (cc *CurrencyConverter) ConvertAt(price float64, from, to string, t time.Time) (float64, error) {
 	if from == to {
 		return price, nil
 	}
 	
-	fromRate, ok := cc.rates[from]
+	fromRate, _, ok := cc.RateAt(from, t)
 	if !ok {
 		return 0, errors.New("unknown currency: " + from)
 	}
 	
-	toRate, ok := cc.rates[to]
+	toRate, _, ok := cc.RateAt(to, t)
 	if !ok {
 		return 0, errors.New("unknown currency: " + to)
 	}
@@ -37,13 +122,14 @@ func (cc *CurrencyConverter) Convert(price float64, from, to string) (float64, e
 	return usdPrice * toRate, nil
 }
 
-// NormalizeToUSD converts any currency to USD
+// NormalizeToUSD converts any currency to USD at the rates of the day the
+// property was scraped
 func (cc *CurrencyConverter) NormalizeToUSD(property *models.Property) error {
 	if property.Currency == "USD" {
 		return nil
 	}
 	
-	converted, err := cc.Convert(property.Price, property.Currency, "USD")
+	converted, err := cc.ConvertAt(property.Price, property.Currency, "USD", property.ScrapedAt)
 	if err != nil {
 		return err
 	}
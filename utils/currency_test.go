@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"sort"
 	"testing"
+	"time"
 	"pricemap-go/models"
 )
 
@@ -132,3 +134,70 @@ func TestCurrencyConverter_NormalizeToUSD(t *testing.T) {
 	}
 }
 
+func TestCurrencyConverter_Rates(t *testing.T) {
+	cc := NewCurrencyConverter()
+
+	if rate, ok := cc.Rate(BaseCurrency); !ok || rate != 1 {
+		t.Errorf("Rate(%s) = %v, %v, want 1, true", BaseCurrency, rate, ok)
+	}
+	if _, ok := cc.Rate("XXX"); ok {
+		t.Error("Rate(XXX) should not be known")
+	}
+
+	currencies := cc.Currencies()
+	if !sort.StringsAreSorted(currencies) {
+		t.Errorf("Currencies() = %v, want sorted", currencies)
+	}
+	for _, currency := range currencies {
+		if _, ok := cc.Rate(currency); !ok {
+			t.Errorf("Currencies() lists %s without a rate", currency)
+		}
+	}
+}
+
+func TestCurrencyConverter_RateAt(t *testing.T) {
+	cc := NewCurrencyConverter()
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+
+	// Without published rates the built-in rate applies
+	if rate, date, ok := cc.RateAt("EUR", day(4)); !ok || rate != 0.92 || !date.Equal(defaultRatesDate) {
+		t.Errorf("RateAt(EUR) = %v, %v, %v, want the built-in rate", rate, date, ok)
+	}
+
+	cc.AddRate("EUR", day(5), 0.925)
+	cc.AddRate("EUR", day(1), 0.921)
+	cc.AddRate("EUR", day(4), 0.923)
+	cc.AddRate("EUR", day(4), 0.924) // replaces the first rate of the day
+	cc.AddRate("ISK", day(1), 137.5)
+
+	tests := []struct {
+		at       time.Time
+		wantRate float64
+		wantDate time.Time
+	}{
+		{day(1), 0.921, day(1)},
+		{day(3).Add(15 * time.Hour), 0.921, day(1)}, // weekend: the last published rate
+		{day(4).Add(9 * time.Hour), 0.924, day(4)},
+		{day(20), 0.925, day(5)},
+		{day(1).AddDate(0, 0, -7), 0.921, day(1)}, // before the first rate: the earliest
+	}
+	for _, tt := range tests {
+		rate, date, ok := cc.RateAt("EUR", tt.at)
+		if !ok || rate != tt.wantRate || !date.Equal(tt.wantDate) {
+			t.Errorf("RateAt(EUR, %v) = %v, %v, %v, want %v, %v", tt.at, rate, date, ok, tt.wantRate, tt.wantDate)
+		}
+	}
+
+	// Currencies without a built-in rate become known through published ones
+	if _, ok := cc.Rate("ISK"); !ok {
+		t.Error("Rate(ISK) should be known after AddRate")
+	}
+	if _, ok := cc.DefaultRate("ISK"); ok {
+		t.Error("DefaultRate(ISK) should not exist")
+	}
+
+	got, err := cc.ConvertAt(92.1, "EUR", "USD", day(2))
+	if err != nil || got < 99.99 || got > 100.01 {
+		t.Errorf("ConvertAt() = %v, %v, want 100", got, err)
+	}
+}
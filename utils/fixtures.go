@@ -0,0 +1,218 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"pricemap-go/config"
+)
+
+// HTTP fixture modes
+const (
+	FixtureModeRecord = "record" // perform real requests and save responses
+	FixtureModeReplay = "replay" // serve saved responses, never touch the network
+)
+
+// ErrFixtureNotFound is returned in replay mode for requests that were never recorded
+var ErrFixtureNotFound = errors.New("no recorded fixture for request")
+
+// sensitiveParams are query parameters redacted from recorded URLs
+var sensitiveParams = []string{"key", "api_key", "apikey", "token", "access_token"}
+
+// Fixture is a recorded HTTP response
+type Fixture struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	Status     int         `json:"status"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body,omitempty"`
+	BodyBase64 string      `json:"body_base64,omitempty"`
+}
+
+// FixtureTransport records responses to a fixture directory or replays them
+type FixtureTransport struct {
+	mode string
+	dir  string
+	next http.RoundTripper
+}
+
+// NewFixtureTransport wraps a transport according to HTTP_FIXTURE_MODE.
+// When fixtures are disabled the transport is returned unchanged.
+func NewFixtureTransport(next http.RoundTripper) http.RoundTripper {
+	if config.AppConfig == nil || config.AppConfig.HTTPFixtureMode == "" {
+		return next
+	}
+	return NewFixtureTransportWithMode(config.AppConfig.HTTPFixtureMode, config.AppConfig.HTTPFixtureDir, next)
+}
+
+// NewFixtureTransportWithMode creates a fixture transport with an explicit mode and directory
+func NewFixtureTransportWithMode(mode, dir string, next http.RoundTripper) *FixtureTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &FixtureTransport{mode: mode, dir: dir, next: next}
+}
+
+// IsReplayMode reports whether HTTP responses are served from fixtures
+func IsReplayMode() bool {
+	return config.AppConfig != nil && config.AppConfig.HTTPFixtureMode == FixtureModeReplay
+}
+
+func (ft *FixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch ft.mode {
+	case FixtureModeReplay:
+		return ft.replay(req)
+	case FixtureModeRecord:
+		return ft.record(req)
+	default:
+		return ft.next.RoundTrip(req)
+	}
+}
+
+func (ft *FixtureTransport) replay(req *http.Request) (*http.Response, error) {
+	fixture, err := LoadFixture(ft.dir, req.Method, req.URL.String())
+	if err != nil {
+		return nil, err
+	}
+
+	body := []byte(fixture.Body)
+	if fixture.BodyBase64 != "" {
+		body, err = base64.StdEncoding.DecodeString(fixture.BodyBase64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fixture body for %s: %w", fixture.URL, err)
+		}
+	}
+
+	header := fixture.Header
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", fixture.Status, http.StatusText(fixture.Status)),
+		StatusCode:    fixture.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func (ft *FixtureTransport) record(req *http.Request) (*http.Response, error) {
+	resp, err := ft.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response for recording: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	header := resp.Header.Clone()
+	header.Del("Set-Cookie")
+
+	fixture := &Fixture{
+		Method: req.Method,
+		URL:    redactURL(req.URL.String()),
+		Status: resp.StatusCode,
+		Header: header,
+	}
+	if utf8.Valid(body) {
+		fixture.Body = string(body)
+	} else {
+		fixture.BodyBase64 = base64.StdEncoding.EncodeToString(body)
+	}
+
+	if err := SaveFixture(ft.dir, fixture); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// FixturePath returns where the response for a request is stored:
+// <dir>/<host>/<hash of method and redacted URL>.json
+func FixturePath(dir, method, rawURL string) string {
+	redacted := redactURL(rawURL)
+
+	host := "unknown"
+	if u, err := url.Parse(redacted); err == nil && u.Host != "" {
+		host = u.Host
+	}
+
+	sum := sha1.Sum([]byte(strings.ToUpper(method) + " " + redacted))
+	return filepath.Join(dir, host, hex.EncodeToString(sum[:])[:16]+".json")
+}
+
+// LoadFixture reads the recorded response for a request
+func LoadFixture(dir, method, rawURL string) (*Fixture, error) {
+	data, err := os.ReadFile(FixturePath(dir, method, rawURL))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s %s", ErrFixtureNotFound, method, redactURL(rawURL))
+		}
+		return nil, err
+	}
+
+	var fixture Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("invalid fixture for %s: %w", rawURL, err)
+	}
+	return &fixture, nil
+}
+
+// SaveFixture writes a recorded response to the fixture directory
+func SaveFixture(dir string, fixture *Fixture) error {
+	path := FixturePath(dir, fixture.Method, fixture.URL)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// redactURL hides credentials in query parameters so fixtures can be committed
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.RawQuery == "" {
+		return rawURL
+	}
+
+	query := u.Query()
+	redacted := false
+	for _, name := range sensitiveParams {
+		if query.Has(name) {
+			query.Set(name, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return rawURL
+	}
+
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFixtureTransport_RecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("<html>listing</html>"))
+	}))
+
+	dir := t.TempDir()
+	url := server.URL + "/search?city=London&key=secret-key"
+
+	recorder := &http.Client{Transport: NewFixtureTransportWithMode(FixtureModeRecord, dir, http.DefaultTransport)}
+	resp, err := recorder.Get(url)
+	if err != nil {
+		t.Fatalf("record request error = %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "<html>listing</html>" {
+		t.Errorf("recorded response body = %q, want original body", body)
+	}
+
+	// Replay must work without the server
+	server.Close()
+
+	replayer := &http.Client{Transport: NewFixtureTransportWithMode(FixtureModeReplay, dir, nil)}
+	resp, err = replayer.Get(url)
+	if err != nil {
+		t.Fatalf("replay request error = %v", err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("replayed status = %d, want 200", resp.StatusCode)
+	}
+	if string(body) != "<html>listing</html>" {
+		t.Errorf("replayed body = %q, want recorded body", body)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("replayed Content-Type = %q, want recorded header", got)
+	}
+	if resp.Header.Get("Set-Cookie") != "" {
+		t.Errorf("Set-Cookie should not be recorded")
+	}
+
+	data, err := os.ReadFile(FixturePath(dir, "GET", url))
+	if err != nil {
+		t.Fatalf("fixture file not found: %v", err)
+	}
+	if strings.Contains(string(data), "secret-key") {
+		t.Errorf("fixture should not contain the API key")
+	}
+}
+
+func TestFixtureTransport_ReplayMissing(t *testing.T) {
+	client := &http.Client{Transport: NewFixtureTransportWithMode(FixtureModeReplay, t.TempDir(), nil)}
+
+	_, err := client.Get("https://example.com/not-recorded")
+	if !errors.Is(err, ErrFixtureNotFound) {
+		t.Errorf("replay of unknown request error = %v, want ErrFixtureNotFound", err)
+	}
+}
+
+func TestFixturePath(t *testing.T) {
+	a := FixturePath("fixtures", "GET", "https://www.cian.ru/cat.php?region=1")
+	b := FixturePath("fixtures", "get", "https://www.cian.ru/cat.php?region=1")
+	c := FixturePath("fixtures", "GET", "https://www.cian.ru/cat.php?region=2")
+
+	if a != b {
+		t.Errorf("FixturePath() should not depend on method case: %s vs %s", a, b)
+	}
+	if a == c {
+		t.Errorf("FixturePath() should differ for different URLs")
+	}
+	if !strings.Contains(a, "www.cian.ru") {
+		t.Errorf("FixturePath() = %s, want host directory", a)
+	}
+}
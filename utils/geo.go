@@ -0,0 +1,32 @@
+package utils
+
+import "math"
+
+// EarthRadiusKm is the mean Earth radius used for distance calculations
+const EarthRadiusKm = 6371.0
+
+// HaversineDistance calculates the great-circle distance between two points in kilometers
+func HaversineDistance(lat1, lon1, lat2, lon2 float64) float64 {
+	dLat := (lat2 - lat1) * math.Pi / 180
+	dLon := (lon2 - lon1) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*
+			math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+
+	return EarthRadiusKm * c
+}
+
+// BoundingBox returns the latitude/longitude deltas that enclose a radius (km)
+// around a point, for use as a cheap pre-filter before exact distance checks
+func BoundingBox(lat, radiusKm float64) (dLat, dLng float64) {
+	dLat = radiusKm / EarthRadiusKm * 180 / math.Pi
+	cosLat := math.Cos(lat * math.Pi / 180)
+	if cosLat < 0.01 {
+		cosLat = 0.01
+	}
+	dLng = dLat / cosLat
+	return dLat, dLng
+}
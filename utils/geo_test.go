@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHaversineDistance(t *testing.T) {
+	// Moscow (Red Square) to Saint Petersburg (Palace Square) is ~634 km
+	got := HaversineDistance(55.7539, 37.6208, 59.9390, 30.3158)
+	if math.Abs(got-634) > 5 {
+		t.Errorf("HaversineDistance() = %v, want ~634", got)
+	}
+
+	if got := HaversineDistance(51.5, -0.12, 51.5, -0.12); got != 0 {
+		t.Errorf("HaversineDistance() for same point = %v, want 0", got)
+	}
+}
+
+func TestBoundingBox(t *testing.T) {
+	dLat, dLng := BoundingBox(60, 1)
+
+	// 1 km north must land on the box edge
+	if d := HaversineDistance(60, 30, 60+dLat, 30); math.Abs(d-1) > 0.001 {
+		t.Errorf("latitude delta covers %v km, want 1", d)
+	}
+
+	// Longitude degrees shrink towards the poles, so the delta must be wider
+	if dLng <= dLat {
+		t.Errorf("BoundingBox() dLng = %v, want > dLat %v at latitude 60", dLng, dLat)
+	}
+}
@@ -15,7 +15,7 @@ type GeocodingService struct {
 
 func NewGeocodingService() *GeocodingService {
 	return &GeocodingService{
-		client: &http.Client{},
+		client: &http.Client{Transport: NewFixtureTransport(http.DefaultTransport)},
 	}
 }
 
@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"math"
+	"sort"
+)
+
+// DefaultSpatialCellKm is the cell size of a SpatialIndex created without one,
+// about the radius of a typical query so that it visits few cells
+const DefaultSpatialCellKm = 0.5
+
+// SpatialIndex is an in-memory index of points for radius and k-nearest
+// queries. Points are bucketed into a grid of cells of equal latitude and
+// longitude degrees, so a query only measures the points of the cells its
+// bounding box covers. Build it once for a set of points, e.g. the transit
+// stops of a city, and query it for every property of a batch; queries may
+// run concurrently, but not alongside Insert. Longitudes do not wrap around
+// the antimeridian.
+type SpatialIndex[T any] struct {
+	cellKm  float64
+	cellDeg float64
+	cells   map[spatialCell][]indexedPoint[T]
+	size    int
+}
+
+type spatialCell struct {
+	lat, lng int
+}
+
+// indexedPoint keeps the insertion order, which breaks distance ties
+type indexedPoint[T any] struct {
+	SpatialPoint[T]
+	seq int
+}
+
+// SpatialPoint is a point of a SpatialIndex with its value
+type SpatialPoint[T any] struct {
+	Lat   float64
+	Lng   float64
+	Value T
+}
+
+// Neighbor is a point found by a query with its distance in kilometers
+type Neighbor[T any] struct {
+	SpatialPoint[T]
+	Distance float64
+	seq      int
+}
+
+// NewSpatialIndex creates an empty index with cells of about cellKm, or
+// DefaultSpatialCellKm when cellKm is not positive
+func NewSpatialIndex[T any](cellKm float64) *SpatialIndex[T] {
+	if cellKm <= 0 {
+		cellKm = DefaultSpatialCellKm
+	}
+	return &SpatialIndex[T]{
+		cellKm:  cellKm,
+		cellDeg: cellKm / EarthRadiusKm * 180 / math.Pi,
+		cells:   make(map[spatialCell][]indexedPoint[T]),
+	}
+}
+
+// Insert adds a point
+func (idx *SpatialIndex[T]) Insert(lat, lng float64, value T) {
+	cell := idx.cellOf(lat, lng)
+	idx.cells[cell] = append(idx.cells[cell], indexedPoint[T]{SpatialPoint[T]{lat, lng, value}, idx.size})
+	idx.size++
+}
+
+// Len returns the number of points
+func (idx *SpatialIndex[T]) Len() int {
+	return idx.size
+}
+
+// WithinRadius returns the points within radiusKm of a point, nearest first
+func (idx *SpatialIndex[T]) WithinRadius(lat, lng, radiusKm float64) []Neighbor[T] {
+	if idx.size == 0 || radiusKm < 0 {
+		return nil
+	}
+
+	dLat, dLng := BoundingBox(lat, radiusKm)
+	low := idx.cellOf(lat-dLat, lng-dLng)
+	high := idx.cellOf(lat+dLat, lng+dLng)
+
+	var found []Neighbor[T]
+	visit := func(points []indexedPoint[T]) {
+		for _, point := range points {
+			if distance := HaversineDistance(lat, lng, point.Lat, point.Lng); distance <= radiusKm {
+				found = append(found, Neighbor[T]{point.SpatialPoint, distance, point.seq})
+			}
+		}
+	}
+
+	// A box of more cells than are filled is cheaper to check cell by cell
+	boxCells := (float64(high.lat) - float64(low.lat) + 1) * (float64(high.lng) - float64(low.lng) + 1)
+	if boxCells > float64(len(idx.cells)) {
+		for cell, points := range idx.cells {
+			if cell.lat >= low.lat && cell.lat <= high.lat && cell.lng >= low.lng && cell.lng <= high.lng {
+				visit(points)
+			}
+		}
+	} else {
+		for cellLat := low.lat; cellLat <= high.lat; cellLat++ {
+			for cellLng := low.lng; cellLng <= high.lng; cellLng++ {
+				visit(idx.cells[spatialCell{cellLat, cellLng}])
+			}
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Distance != found[j].Distance {
+			return found[i].Distance < found[j].Distance
+		}
+		return found[i].seq < found[j].seq
+	})
+	return found
+}
+
+// Nearest returns the k points nearest to a point, nearest first, leaving
+// out points farther than maxKm unless maxKm is not positive
+func (idx *SpatialIndex[T]) Nearest(lat, lng float64, k int, maxKm float64) []Neighbor[T] {
+	if k <= 0 || idx.size == 0 {
+		return nil
+	}
+
+	// Widen the search until it holds k points; points inside the radius
+	// are always nearer than points outside it
+	limit := math.Pi * EarthRadiusKm
+	if maxKm > 0 && maxKm < limit {
+		limit = maxKm
+	}
+	radius := math.Min(idx.cellKm, limit)
+	for {
+		found := idx.WithinRadius(lat, lng, radius)
+		if len(found) >= k || len(found) == idx.size || radius >= limit {
+			if len(found) > k {
+				found = found[:k]
+			}
+			return found
+		}
+		radius = math.Min(radius*2, limit)
+	}
+}
+
+func (idx *SpatialIndex[T]) cellOf(lat, lng float64) spatialCell {
+	return spatialCell{int(math.Floor(lat / idx.cellDeg)), int(math.Floor(lng / idx.cellDeg))}
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// bruteForce returns the values of points within radiusKm, nearest first
+func bruteForce(points []SpatialPoint[int], lat, lng, radiusKm float64) []int {
+	type hit struct {
+		value    int
+		distance float64
+	}
+	var hits []hit
+	for _, p := range points {
+		if d := HaversineDistance(lat, lng, p.Lat, p.Lng); d <= radiusKm {
+			hits = append(hits, hit{p.Value, d})
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].distance < hits[j].distance })
+	values := make([]int, len(hits))
+	for i, h := range hits {
+		values[i] = h.value
+	}
+	return values
+}
+
+func neighborValues(neighbors []Neighbor[int]) []int {
+	values := make([]int, len(neighbors))
+	for i, n := range neighbors {
+		values[i] = n.Value
+	}
+	return values
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSpatialIndex_MatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	index := NewSpatialIndex[int](0.5)
+	var points []SpatialPoint[int]
+	// Around Moscow, about 30 x 30 km
+	for i := 0; i < 2000; i++ {
+		p := SpatialPoint[int]{Lat: 55.6 + rng.Float64()*0.3, Lng: 37.4 + rng.Float64()*0.45, Value: i}
+		points = append(points, p)
+		index.Insert(p.Lat, p.Lng, p.Value)
+	}
+	if index.Len() != 2000 {
+		t.Fatalf("Len() = %d, want 2000", index.Len())
+	}
+
+	for _, radius := range []float64{0.1, 0.8, 2.5, 50} {
+		for i := 0; i < 20; i++ {
+			lat, lng := 55.6+rng.Float64()*0.3, 37.4+rng.Float64()*0.45
+			got := neighborValues(index.WithinRadius(lat, lng, radius))
+			if want := bruteForce(points, lat, lng, radius); !equalInts(got, want) {
+				t.Fatalf("WithinRadius(%v, %v, %v) = %d points, want %d", lat, lng, radius, len(got), len(want))
+			}
+
+			nearest := neighborValues(index.Nearest(lat, lng, 5, 0))
+			if want := bruteForce(points, lat, lng, 1000)[:5]; !equalInts(nearest, want) {
+				t.Fatalf("Nearest(%v, %v, 5) = %v, want %v", lat, lng, nearest, want)
+			}
+		}
+	}
+}
+
+func TestSpatialIndex_Nearest(t *testing.T) {
+	index := NewSpatialIndex[string](0)
+	if got := index.Nearest(55.75, 37.61, 3, 0); got != nil {
+		t.Errorf("Nearest() on an empty index = %v, want nil", got)
+	}
+
+	index.Insert(55.7558, 37.6173, "red square")
+	index.Insert(55.7558, 37.6173, "red square again") // same place, inserted later
+	index.Insert(59.9390, 30.3158, "palace square")
+
+	got := index.Nearest(55.7558, 37.6173, 5, 0)
+	if len(got) != 3 || got[0].Value != "red square" || got[1].Value != "red square again" || got[2].Value != "palace square" {
+		t.Errorf("Nearest() = %+v, want all three, ties in insertion order", got)
+	}
+	if got[2].Distance < 600 || got[2].Distance > 700 {
+		t.Errorf("Nearest() distance to Saint Petersburg = %v km", got[2].Distance)
+	}
+
+	// maxKm leaves out the far point
+	if got := index.Nearest(55.7558, 37.6173, 5, 10); len(got) != 2 {
+		t.Errorf("Nearest() within 10 km = %d points, want 2", len(got))
+	}
+	if got := index.Nearest(55.7558, 37.6173, 1, 10); len(got) != 1 || got[0].Value != "red square" {
+		t.Errorf("Nearest(k=1) = %+v", got)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"pricemap-go/config"
+	"pricemap-go/metrics"
 )
 
 // TorController handles Tor circuit rotation
@@ -27,6 +28,16 @@ func NewTorController() *TorController {
 
 // RotateCircuit requests a new Tor circuit (changes IP)
 func (tc *TorController) RotateCircuit() error {
+	err := tc.rotateCircuit()
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	metrics.TorRotations.WithLabelValues(result).Inc()
+	return err
+}
+
+func (tc *TorController) rotateCircuit() error {
 	conn, err := net.DialTimeout("tcp", tc.controlAddr, 10*time.Second)
 	if err != nil {
 		return fmt.Errorf("failed to connect to Tor control port: %w", err)
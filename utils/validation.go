@@ -4,25 +4,36 @@ import "pricemap-go/models"
 
 // ValidateProperty validates a property before saving
 func ValidateProperty(property *models.Property) error {
+	if errs := PropertyValidationErrors(property); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// PropertyValidationErrors returns every rule a property breaks, in the order
+// ValidateProperty checks them
+func PropertyValidationErrors(property *models.Property) []*ValidationError {
+	var errs []*ValidationError
+
 	if property.Price <= 0 {
-		return ErrInvalidPrice
+		errs = append(errs, ErrInvalidPrice)
 	}
 	
 	if property.Latitude == 0 && property.Longitude == 0 {
 		if property.Address == "" {
-			return ErrMissingLocation
+			errs = append(errs, ErrMissingLocation)
 		}
 	}
 	
 	if property.Source == "" {
-		return ErrMissingSource
+		errs = append(errs, ErrMissingSource)
 	}
 	
 	if property.ExternalID == "" {
-		return ErrMissingExternalID
+		errs = append(errs, ErrMissingExternalID)
 	}
 	
-	return nil
+	return errs
 }
 
 // NormalizeProperty normalizes property data
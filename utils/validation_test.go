@@ -100,6 +100,25 @@ func TestValidateProperty(t *testing.T) {
 	}
 }
 
+func TestPropertyValidationErrors(t *testing.T) {
+	errs := PropertyValidationErrors(&models.Property{Source: "test"})
+
+	want := []*ValidationError{ErrInvalidPrice, ErrMissingLocation, ErrMissingExternalID}
+	if len(errs) != len(want) {
+		t.Fatalf("PropertyValidationErrors() = %v, want %v", errs, want)
+	}
+	for i := range want {
+		if errs[i] != want[i] {
+			t.Errorf("PropertyValidationErrors()[%d] = %v, want %v", i, errs[i], want[i])
+		}
+	}
+
+	valid := &models.Property{Price: 1, Address: "123 Main St", Source: "test", ExternalID: "1"}
+	if errs := PropertyValidationErrors(valid); len(errs) != 0 {
+		t.Errorf("PropertyValidationErrors() = %v, want none", errs)
+	}
+}
+
 func TestNormalizeProperty(t *testing.T) {
 	tests := []struct {
 		name     string
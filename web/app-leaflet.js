@@ -10,6 +10,18 @@ let factorsChart = null;
 let autoUpdateInterval = null;
 let debounceTimer = null;
 let currentSelectedPoint = null;
+let listCursor = null; // next_cursor of the last loaded page, null at the end
+let listLoading = false;
+let listRequest = 0; // discards pages of a list that was reloaded meanwhile
+
+// API sort of each sortBy option
+const LIST_SORTS = {
+    price_asc: 'price',
+    price_desc: '-price',
+    score_asc: 'overall_score',
+    score_desc: '-overall_score',
+    newest: '-scraped_at'
+};
 
 // Initialize on page load
 document.addEventListener('DOMContentLoaded', () => {
@@ -98,6 +110,14 @@ function initEventListeners() {
     // Search and sort
     document.getElementById('searchInput').addEventListener('input', filterPropertiesList);
     document.getElementById('sortBy').addEventListener('change', sortPropertiesList);
+
+    // Infinite scroll: load the next page near the end of the list
+    document.getElementById('propertiesContainer').addEventListener('scroll', (e) => {
+        const container = e.target;
+        if (container.scrollTop + container.clientHeight >= container.scrollHeight - 200) {
+            loadMoreProperties();
+        }
+    });
 }
 
 // Load initial data
@@ -124,7 +144,7 @@ async function loadHeatmapData() {
     const sw = bounds.getSouthWest();
     
     const filters = getFilters();
-    let url = `${API_BASE_URL}/heatmap?lat_min=${sw.lat}&lat_max=${ne.lat}&lng_min=${sw.lng}&lng_max=${ne.lng}`;
+    let url = `${API_BASE_URL}/heatmap?lat_min=${sw.lat}&lat_max=${ne.lat}&lng_min=${sw.lng}&lng_max=${ne.lng}&zoom=${Math.floor(map.getZoom())}`;
     
     if (filters.city) url += `&city=${encodeURIComponent(filters.city)}`;
     if (filters.type) url += `&type=${encodeURIComponent(filters.type)}`;
@@ -171,7 +191,7 @@ async function loadHeatmapData() {
 // Load all data (for initial load or when no bounds data)
 async function loadAllData() {
     try {
-        const response = await fetch(`${API_BASE_URL}/heatmap`);
+        const response = await fetch(`${API_BASE_URL}/heatmap?zoom=${Math.floor(map.getZoom())}`);
         
         if (!response.ok) throw new Error(`HTTP ${response.status}`);
         
@@ -333,17 +353,32 @@ function createInfoWindowContent(point) {
         <div style="padding: 10px; min-width: 200px;">
             <h4 style="margin: 0 0 10px 0; color: #2c3e50;">Area Information</h4>
             <p style="margin: 5px 0;"><strong>Average price:</strong> ${formatPrice(point.price)}</p>
+            ${point.price_per_sqm ? `<p style="margin: 5px 0;"><strong>Price per m²:</strong> ${formatPrice(point.price_per_sqm)}</p>` : ''}
             <p style="margin: 5px 0;"><strong>Properties:</strong> ${point.count || 1}</p>
             <p style="margin: 5px 0;"><strong>Overall rating:</strong> ${formatScore(point.score || 0)}</p>
         </div>
     `;
 }
 
-// Load properties list
+// Load properties list from the first page
 async function loadPropertiesList() {
+    listCursor = null;
+    await loadPropertiesPage(false);
+}
+
+// Append the next page of the list, if there is one
+async function loadMoreProperties() {
+    if (!listCursor || listLoading) return;
+    await loadPropertiesPage(true);
+}
+
+// Load one page of properties; cursors keep the order stable between pages
+async function loadPropertiesPage(append) {
     const filters = getFilters();
-    let url = `${API_BASE_URL}/properties?limit=100`;
+    const sort = LIST_SORTS[document.getElementById('sortBy').value] || 'price';
+    let url = `${API_BASE_URL}/properties?limit=50&sort=${sort}`;
     
+    if (append) url += `&cursor=${encodeURIComponent(listCursor)}`;
     if (filters.city) url += `&city=${encodeURIComponent(filters.city)}`;
     if (filters.type) url += `&type=${encodeURIComponent(filters.type)}`;
     if (filters.priceMin) url += `&price_min=${filters.priceMin}`;
@@ -361,15 +396,23 @@ async function loadPropertiesList() {
     if (filters.transportScoreMin) url += `&transport_score_min=${filters.transportScoreMin}`;
     if (filters.educationScoreMin) url += `&education_score_min=${filters.educationScoreMin}`;
     
+    const request = ++listRequest;
+    listLoading = true;
     try {
         const response = await fetch(url);
         const data = await response.json();
+        if (request !== listRequest) return;
         
         if (data.data) {
-            displayPropertiesList(data.data);
+            displayPropertiesList(data.data, append);
+        } else if (!append) {
+            displayPropertiesList([], false);
         }
+        listCursor = data.next_cursor || null;
     } catch (error) {
         console.error('Error loading properties:', error);
+    } finally {
+        if (request === listRequest) listLoading = false;
     }
 }
 
@@ -543,19 +586,19 @@ function displayFactorsDetails(factors) {
     `;
 }
 
-// Display properties list
-function displayPropertiesList(properties) {
+// Display properties list, or add a page to it
+function displayPropertiesList(properties, append) {
     const container = document.getElementById('propertiesContainer');
     
-    if (properties.length === 0) {
+    if (!append && properties.length === 0) {
         container.innerHTML = '<p class="placeholder">No properties found</p>';
         return;
     }
     
-    container.innerHTML = properties.map(prop => `
+    const cards = properties.map(prop => `
         <div class="property-card" onclick="focusOnProperty(${prop.latitude}, ${prop.longitude})">
             <h4>${prop.address || 'Address not specified'}</h4>
-            <div class="price">${formatPrice(prop.price)}</div>
+            <div class="price">${formatPrice(prop.price)}${prop.price_per_sqm ? ` <small>(${formatPrice(prop.price_per_sqm)}/m²)</small>` : ''}</div>
             <div class="details">
                 ${prop.area ? `Area: ${prop.area} m²` : ''} | 
                 ${prop.rooms ? `Rooms: ${prop.rooms}` : ''} | 
@@ -564,6 +607,14 @@ function displayPropertiesList(properties) {
             <div>Rating: ${formatScore(prop.factors?.overall_score || 0)}</div>
         </div>
     `).join('');
+    
+    if (append) {
+        container.insertAdjacentHTML('beforeend', cards);
+        filterPropertiesList();
+    } else {
+        container.innerHTML = cards;
+        container.scrollTop = 0;
+    }
 }
 
 // Focus on property
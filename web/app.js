@@ -115,7 +115,7 @@ async function loadHeatmapData() {
     const sw = bounds.getSouthWest();
     
     const filters = getFilters();
-    let url = `${API_BASE_URL}/heatmap?lat_min=${sw.lat()}&lat_max=${ne.lat()}&lng_min=${sw.lng()}&lng_max=${ne.lng()}`;
+    let url = `${API_BASE_URL}/heatmap?lat_min=${sw.lat()}&lat_max=${ne.lat()}&lng_min=${sw.lng()}&lng_max=${ne.lng()}&zoom=${Math.floor(map.getZoom())}`;
     
     if (filters.city) url += `&city=${encodeURIComponent(filters.city)}`;
     if (filters.type) url += `&type=${encodeURIComponent(filters.type)}`;